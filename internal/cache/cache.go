@@ -3,6 +3,8 @@ package cache
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -10,8 +12,19 @@ import (
 type Cache struct {
 	filename  string
 	ttl       time.Duration
-	skipReads bool // When true, ignore cached data but still write fresh results
-	data      CacheData
+	skipReads bool          // When true, ignore cached data but still write fresh results
+	maxAge    time.Duration // Entries older than this are pruned on Save (0 = never)
+
+	mu   sync.RWMutex // Guards data, the used keys, and the previous versions
+	data CacheData
+
+	// Keys looked up or stored during this run, never pruned
+	usedImages map[string]bool
+	usedCharts map[string]bool
+
+	// Latest versions as loaded from disk, before this run's lookups
+	previousImages map[string]string
+	previousCharts map[string]string
 }
 
 // CacheData represents the cache file structure
@@ -25,6 +38,12 @@ type CacheEntry struct {
 	Latest    string    `json:"latest"`
 	CheckedAt time.Time `json:"checked_at"`
 	AllTags   []string  `json:"all_tags,omitempty"`
+
+	// LatestAny is the latest version including pre-releases, for charts only
+	LatestAny string `json:"latest_any,omitempty"`
+
+	// Digests maps tags to their manifest digest, for images only
+	Digests map[string]string `json:"digests,omitempty"`
 }
 
 // New creates a new cache instance
@@ -38,7 +57,29 @@ func New(filename string, ttl time.Duration, skipReads bool) *Cache {
 			Images: make(map[string]CacheEntry),
 			Charts: make(map[string]CacheEntry),
 		},
+		usedImages: make(map[string]bool),
+		usedCharts: make(map[string]bool),
+	}
+}
+
+// SetMaxAge sets the age after which entries are pruned on Save
+// Entries used during the current run are always kept
+func (c *Cache) SetMaxAge(maxAge time.Duration) {
+	c.maxAge = maxAge
+}
+
+// Clear removes the cache file and drops all cached data
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.Images = make(map[string]CacheEntry)
+	c.data.Charts = make(map[string]CacheEntry)
+
+	if err := os.Remove(c.filename); err != nil && !os.IsNotExist(err) {
+		return err
 	}
+	return nil
 }
 
 // Load reads the cache from disk
@@ -51,40 +92,113 @@ func (c *Cache) Load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, &c.data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := json.Unmarshal(data, &c.data); err != nil {
+		return err
+	}
+
+	c.previousImages = latestVersions(c.data.Images)
+	c.previousCharts = latestVersions(c.data.Charts)
+	return nil
+}
+
+func latestVersions(entries map[string]CacheEntry) map[string]string {
+	latest := make(map[string]string, len(entries))
+	for key, entry := range entries {
+		latest[key] = entry.Latest
+	}
+	return latest
+}
+
+// PreviousImage returns the latest version an image had in the cache file
+// when it was loaded, regardless of its age or --refresh
+func (c *Cache) PreviousImage(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	latest, ok := c.previousImages[key]
+	return latest, ok
+}
+
+// PreviousChart returns the latest version a chart had in the cache file
+// when it was loaded, regardless of its age or --refresh
+func (c *Cache) PreviousChart(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	latest, ok := c.previousCharts[key]
+	return latest, ok
 }
 
-// Save writes the cache to disk
+// Save prunes old entries and writes the cache to disk, creating its
+// directory if needed
 func (c *Cache) Save() error {
+	c.mu.Lock()
+	c.prune()
 	data, err := json.MarshalIndent(c.data, "", "  ")
+	c.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
+	if err := os.MkdirAll(filepath.Dir(c.filename), 0755); err != nil {
+		return err
+	}
+
 	return os.WriteFile(c.filename, data, 0644)
 }
 
-// GetImage retrieves a cached image lookup
+// prune drops entries older than maxAge that were not used in this run.
+// The caller holds c.mu.
+func (c *Cache) prune() {
+	if c.maxAge <= 0 {
+		return
+	}
+
+	for key, entry := range c.data.Images {
+		if !c.usedImages[key] && time.Since(entry.CheckedAt) > c.maxAge {
+			delete(c.data.Images, key)
+		}
+	}
+	for key, entry := range c.data.Charts {
+		if !c.usedCharts[key] && time.Since(entry.CheckedAt) > c.maxAge {
+			delete(c.data.Charts, key)
+		}
+	}
+}
+
+// GetImage retrieves a cached image lookup and when it was made
 // Returns false if skipReads is enabled (forces fresh lookup)
-func (c *Cache) GetImage(key string) (string, []string, bool) {
+func (c *Cache) GetImage(key string) (string, []string, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usedImages[key] = true
+
 	if c.skipReads {
-		return "", nil, false
+		return "", nil, time.Time{}, false
 	}
 
 	entry, ok := c.data.Images[key]
 	if !ok {
-		return "", nil, false
+		return "", nil, time.Time{}, false
 	}
 
 	if time.Since(entry.CheckedAt) > c.ttl {
-		return "", nil, false // Cache expired
+		return "", nil, time.Time{}, false // Cache expired
 	}
 
-	return entry.Latest, entry.AllTags, true
+	return entry.Latest, entry.AllTags, entry.CheckedAt, true
 }
 
 // SetImage stores an image lookup in the cache
 func (c *Cache) SetImage(key, latest string, allTags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usedImages[key] = true
 	c.data.Images[key] = CacheEntry{
 		Latest:    latest,
 		CheckedAt: time.Now(),
@@ -92,29 +206,81 @@ func (c *Cache) SetImage(key, latest string, allTags []string) {
 	}
 }
 
-// GetChart retrieves a cached chart lookup
-// Returns false if skipReads is enabled (forces fresh lookup)
-func (c *Cache) GetChart(key string) (string, bool) {
+// GetImageDigest retrieves the cached manifest digest of an image tag
+// Digests expire together with the image entry they belong to
+func (c *Cache) GetImageDigest(key, tag string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if c.skipReads {
 		return "", false
 	}
 
+	entry, ok := c.data.Images[key]
+	if !ok || time.Since(entry.CheckedAt) > c.ttl {
+		return "", false
+	}
+
+	digest, ok := entry.Digests[tag]
+	return digest, ok
+}
+
+// SetImageDigest stores the manifest digest of an image tag
+// It is a no-op if the image itself is not cached
+func (c *Cache) SetImageDigest(key, tag, digest string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.data.Images[key]
+	if !ok {
+		return
+	}
+	if entry.Digests == nil {
+		entry.Digests = make(map[string]string)
+	}
+	entry.Digests[tag] = digest
+	c.data.Images[key] = entry
+}
+
+// GetChart retrieves a cached chart lookup, its latest version including
+// pre-releases and when it was made. Entries written without one return
+// the latest version for both.
+// Returns false if skipReads is enabled (forces fresh lookup)
+func (c *Cache) GetChart(key string) (string, string, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usedCharts[key] = true
+
+	if c.skipReads {
+		return "", "", time.Time{}, false
+	}
+
 	entry, ok := c.data.Charts[key]
 	if !ok {
-		return "", false
+		return "", "", time.Time{}, false
 	}
 
 	if time.Since(entry.CheckedAt) > c.ttl {
-		return "", false // Cache expired
+		return "", "", time.Time{}, false // Cache expired
 	}
 
-	return entry.Latest, true
+	latestAny := entry.LatestAny
+	if latestAny == "" {
+		latestAny = entry.Latest
+	}
+	return entry.Latest, latestAny, entry.CheckedAt, true
 }
 
 // SetChart stores a chart lookup in the cache
-func (c *Cache) SetChart(key, latest string) {
+func (c *Cache) SetChart(key, latest, latestAny string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usedCharts[key] = true
 	c.data.Charts[key] = CacheEntry{
 		Latest:    latest,
 		CheckedAt: time.Now(),
+		LatestAny: latestAny,
 	}
 }
@@ -1,8 +1,10 @@
 package cache
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -20,10 +22,14 @@ func TestCache_ImageOperations(t *testing.T) {
 	// Test SetImage and GetImage
 	c.SetImage("docker.io/nginx", "1.21.0", []string{"1.20.0", "1.21.0", "latest"})
 
-	latest, tags, ok := c.GetImage("docker.io/nginx")
+	before := time.Now()
+	latest, tags, checkedAt, ok := c.GetImage("docker.io/nginx")
 	if !ok {
 		t.Error("expected to find cached image")
 	}
+	if checkedAt.IsZero() || checkedAt.After(before) {
+		t.Errorf("CheckedAt = %v, want the time of SetImage", checkedAt)
+	}
 	if latest != "1.21.0" {
 		t.Errorf("Latest = %q, want %q", latest, "1.21.0")
 	}
@@ -32,7 +38,7 @@ func TestCache_ImageOperations(t *testing.T) {
 	}
 
 	// Test non-existent key
-	_, _, ok = c.GetImage("docker.io/nonexistent")
+	_, _, _, ok = c.GetImage("docker.io/nonexistent")
 	if ok {
 		t.Error("expected not to find non-existent image")
 	}
@@ -49,18 +55,27 @@ func TestCache_ChartOperations(t *testing.T) {
 	c := New(cacheFile, 1*time.Hour, false)
 
 	// Test SetChart and GetChart
-	c.SetChart("bitnami/postgresql", "14.0.0")
+	c.SetChart("bitnami/postgresql", "14.0.0", "15.0.0-rc.1")
 
-	latest, ok := c.GetChart("bitnami/postgresql")
+	latest, latestAny, _, ok := c.GetChart("bitnami/postgresql")
 	if !ok {
 		t.Error("expected to find cached chart")
 	}
 	if latest != "14.0.0" {
 		t.Errorf("Latest = %q, want %q", latest, "14.0.0")
 	}
+	if latestAny != "15.0.0-rc.1" {
+		t.Errorf("LatestAny = %q, want %q", latestAny, "15.0.0-rc.1")
+	}
+
+	// Entries without a pre-release fall back to the latest version
+	c.SetChart("bitnami/redis", "20.0.0", "")
+	if _, latestAny, _, _ := c.GetChart("bitnami/redis"); latestAny != "20.0.0" {
+		t.Errorf("LatestAny = %q, want %q", latestAny, "20.0.0")
+	}
 
 	// Test non-existent key
-	_, ok = c.GetChart("bitnami/nonexistent")
+	_, _, _, ok = c.GetChart("bitnami/nonexistent")
 	if ok {
 		t.Error("expected not to find non-existent chart")
 	}
@@ -78,7 +93,7 @@ func TestCache_Persistence(t *testing.T) {
 	// Create and save cache
 	c1 := New(cacheFile, 1*time.Hour, false)
 	c1.SetImage("docker.io/nginx", "1.21.0", nil)
-	c1.SetChart("bitnami/postgresql", "14.0.0")
+	c1.SetChart("bitnami/postgresql", "14.0.0", "15.0.0-rc.1")
 	if err := c1.Save(); err != nil {
 		t.Fatalf("Save() error = %v", err)
 	}
@@ -90,7 +105,7 @@ func TestCache_Persistence(t *testing.T) {
 	}
 
 	// Verify data persisted
-	latest, _, ok := c2.GetImage("docker.io/nginx")
+	latest, _, _, ok := c2.GetImage("docker.io/nginx")
 	if !ok {
 		t.Error("expected to find persisted image")
 	}
@@ -98,12 +113,12 @@ func TestCache_Persistence(t *testing.T) {
 		t.Errorf("Image Latest = %q, want %q", latest, "1.21.0")
 	}
 
-	chartLatest, ok := c2.GetChart("bitnami/postgresql")
+	chartLatest, chartLatestAny, _, ok := c2.GetChart("bitnami/postgresql")
 	if !ok {
 		t.Error("expected to find persisted chart")
 	}
-	if chartLatest != "14.0.0" {
-		t.Errorf("Chart Latest = %q, want %q", chartLatest, "14.0.0")
+	if chartLatest != "14.0.0" || chartLatestAny != "15.0.0-rc.1" {
+		t.Errorf("Chart Latest = %q (any %q), want %q (any %q)", chartLatest, chartLatestAny, "14.0.0", "15.0.0-rc.1")
 	}
 }
 
@@ -124,12 +139,40 @@ func TestCache_TTLExpiry(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 
 	// Should not find expired entry
-	_, _, ok := c.GetImage("docker.io/nginx")
+	_, _, _, ok := c.GetImage("docker.io/nginx")
 	if ok {
 		t.Error("expected expired entry to not be found")
 	}
 }
 
+func TestCache_TTLOnLoad(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "test-cache.json")
+
+	c := New(cacheFile, time.Hour, false)
+	c.data.Images["docker.io/nginx"] = CacheEntry{Latest: "1.21.0", CheckedAt: time.Now().Add(-2 * time.Hour)}
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		ttl    time.Duration
+		wantOK bool
+	}{
+		{24 * time.Hour, true},
+		{time.Hour, false},
+		{0, false},
+	}
+	for _, tt := range tests {
+		c := New(cacheFile, tt.ttl, false)
+		if err := c.Load(); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if _, _, _, ok := c.GetImage("docker.io/nginx"); ok != tt.wantOK {
+			t.Errorf("ttl %s: GetImage() ok = %v, want %v for a 2h old entry", tt.ttl, ok, tt.wantOK)
+		}
+	}
+}
+
 func TestCache_SkipReads(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "chartup-cache-test-*")
 	if err != nil {
@@ -144,7 +187,7 @@ func TestCache_SkipReads(t *testing.T) {
 	c.SetImage("docker.io/nginx", "1.21.0", nil)
 
 	// Should not find anything when skipReads is true (forces fresh lookup)
-	_, _, ok := c.GetImage("docker.io/nginx")
+	_, _, _, ok := c.GetImage("docker.io/nginx")
 	if ok {
 		t.Error("expected skipReads cache to not return values on read")
 	}
@@ -165,7 +208,7 @@ func TestCache_SkipReads(t *testing.T) {
 		t.Fatalf("Load() error = %v", err)
 	}
 
-	latest, _, ok := c2.GetImage("docker.io/nginx")
+	latest, _, _, ok := c2.GetImage("docker.io/nginx")
 	if !ok {
 		t.Error("expected to find cached image after skipReads save")
 	}
@@ -174,6 +217,37 @@ func TestCache_SkipReads(t *testing.T) {
 	}
 }
 
+func TestCache_Concurrent(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "test-cache.json")
+	c := New(cacheFile, time.Hour, false)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("docker.io/org/app-%d", i%10)
+			c.SetImage(key, "1.0.0", []string{"1.0.0"})
+			c.GetImage(key)
+			c.SetImageDigest(key, "1.0.0", "sha256:abc")
+			c.GetImageDigest(key, "1.0.0")
+			c.SetChart(key, "1.0.0", "")
+			c.GetChart(key)
+			c.PreviousImage(key)
+			if i%10 == 0 {
+				if err := c.Save(); err != nil {
+					t.Errorf("Save() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, _, _, ok := c.GetImage("docker.io/org/app-9"); !ok {
+		t.Error("expected image stored concurrently to be cached")
+	}
+}
+
 func TestCache_LoadNonExistent(t *testing.T) {
 	c := New("/nonexistent/path/cache.json", 1*time.Hour, false)
 
@@ -182,3 +256,82 @@ func TestCache_LoadNonExistent(t *testing.T) {
 		t.Errorf("Load() on non-existent file error = %v", err)
 	}
 }
+
+func TestCache_PruneOnSave(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-cache-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cacheFile := filepath.Join(tmpDir, "test-cache.json")
+
+	// Seed a cache file with old and fresh entries
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	c1 := New(cacheFile, 1*time.Hour, false)
+	c1.data.Images["docker.io/old"] = CacheEntry{Latest: "1.0.0", CheckedAt: old}
+	c1.data.Images["docker.io/used"] = CacheEntry{Latest: "2.0.0", CheckedAt: old}
+	c1.data.Charts["bitnami/old"] = CacheEntry{Latest: "1.0.0", CheckedAt: old}
+	c1.SetImage("docker.io/fresh", "3.0.0", nil)
+	c1.SetChart("bitnami/fresh", "3.0.0", "")
+	if err := c1.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Next run: prune entries older than 30 days, but one old entry is looked up
+	c2 := New(cacheFile, 1*time.Hour, false)
+	c2.SetMaxAge(30 * 24 * time.Hour)
+	if err := c2.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	c2.GetImage("docker.io/used")
+	if err := c2.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, ok := c2.data.Images["docker.io/old"]; ok {
+		t.Error("expected old unused image entry to be pruned")
+	}
+	if _, ok := c2.data.Charts["bitnami/old"]; ok {
+		t.Error("expected old unused chart entry to be pruned")
+	}
+	if _, ok := c2.data.Images["docker.io/used"]; !ok {
+		t.Error("expected old entry used in this run to remain")
+	}
+	if _, ok := c2.data.Images["docker.io/fresh"]; !ok {
+		t.Error("expected fresh image entry to remain")
+	}
+	if _, ok := c2.data.Charts["bitnami/fresh"]; !ok {
+		t.Error("expected fresh chart entry to remain")
+	}
+}
+
+func TestCache_Clear(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-cache-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cacheFile := filepath.Join(tmpDir, "test-cache.json")
+	c := New(cacheFile, 1*time.Hour, false)
+	c.SetImage("docker.io/nginx", "1.21.0", nil)
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Error("expected cache file to be removed")
+	}
+	if _, _, _, ok := c.GetImage("docker.io/nginx"); ok {
+		t.Error("expected cleared cache to be empty")
+	}
+
+	// Clearing a missing file is not an error
+	if err := c.Clear(); err != nil {
+		t.Errorf("Clear() on missing file error = %v", err)
+	}
+}
@@ -1,8 +1,14 @@
 package checker
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nogo/chartup/internal/cache"
 	"github.com/nogo/chartup/internal/registry"
@@ -12,32 +18,93 @@ import (
 // Checker performs version checks for images and charts
 type Checker struct {
 	cache    *cache.Cache
-	registry *registry.Client
+	registry Registry
+	rewrites map[string]string // Repository to look up instead of the one in the file
+	digests  bool              // Whether to fetch the digest of each latest tag
+	apps     bool              // Whether to check GitHub releases of chart sources
+	offline  bool              // Whether to answer from the cache only
+	strict   bool              // Whether a cache miss while offline is an error
+	workers  int               // Number of lookups CheckAll runs at once
+	semver   bool              // Whether only clean release versions count, as in registry.Options.OnlySemver
+
+	loginMu  sync.Mutex
+	loginErr error // Why switching to authenticated Docker Hub requests failed
+	loggedIn bool  // Whether a Docker Hub login was attempted
+
+	mu          sync.Mutex
+	unsupported map[string]bool // Unsupported registries seen during CheckAll
+	missing     map[string]bool // Cache keys missed while offline during CheckAll
+	resetAt     time.Time       // Latest reset of a rate limit hit during CheckAll
+}
+
+// DefaultConcurrency is the number of lookups CheckAll runs at once by
+// default. Per-registry limits, such as Docker Hub's, still apply.
+const DefaultConcurrency = 8
+
+// ErrCacheMiss is returned when a lookup is missing from the cache in an
+// offline run that requires a complete cache
+var ErrCacheMiss = errors.New("missing from cache")
+
+// Registry looks up the latest versions of images and charts
+type Registry interface {
+	GetLatestTag(ctx context.Context, registry, repository, currentTag string) (*registry.TagInfo, error)
+	GetDigest(ctx context.Context, registry, repository, tag string) (string, error)
+	GetChartVersion(ctx context.Context, chartName, upstream, repository string) (*registry.ChartVersionInfo, error)
+	GetLatestRelease(ctx context.Context, owner, repo string) (string, error)
+}
+
+// statsRegistry is implemented by registries that count their requests
+type statsRegistry interface {
+	Stats() []registry.RegistryStats
+}
+
+// dockerHubLogin is implemented by registries that can switch to
+// authenticated Docker Hub requests after a rate limit
+type dockerHubLogin interface {
+	DockerHubAuthenticated() bool
+	DockerHubCredentials() (*registry.Credentials, error)
+	LoginDockerHub(ctx context.Context, creds *registry.Credentials) error
 }
 
 // ImageResult holds the result of an image version check
 type ImageResult struct {
-	Repository string
-	Registry   string
-	Current    string
-	Latest     string
-	Status     Status
-	Skipped    bool
-	Error      string
-	Path       string // File where this image was found
-	Line       int    // Line number in file (0 if unknown)
+	Repository   string
+	Registry     string
+	Current      string
+	Digest       string // Digest the current image is pinned to, if any
+	Latest       string
+	Bump         registry.Bump // Size of the update, for available updates
+	LatestStable string        // Latest stable release
+	LatestAny    string        // Latest release including pre-releases
+	LatestDigest string        // Manifest digest of Latest, if fetched
+	Previous     string        // Cached latest from the previous run, if any
+	CachedAt     time.Time     // When Latest was looked up, if served from the cache
+	Container    string        // Container name from a containers/initContainers list, if any
+	Status       Status
+	Skipped      bool
+	Error        string
+	Path         string // File where this image was found
+	Line         int    // Line number in file (0 if unknown)
 }
 
 // ChartResult holds the result of a chart version check
 type ChartResult struct {
-	Name     string
-	Current  string
-	Latest   string
-	Upstream string
-	Status   Status
-	Error    string
-	Path     string // File where this chart was found
-	Line     int    // Line number in file (0 if unknown)
+	Name             string
+	Current          string
+	Latest           string
+	Bump             registry.Bump // Size of the update, for available updates
+	LatestStable     string        // Latest stable release
+	LatestAny        string        // Latest release including pre-releases
+	Upstream         string
+	DependencyOf     string    // Parent chart if this is a dependency
+	AppVersion       string    // appVersion from Chart.yaml, set when app releases are checked
+	LatestAppVersion string    // Latest GitHub release of the chart's source
+	Previous         string    // Cached latest from the previous run, if any
+	CachedAt         time.Time // When Latest was looked up, if served from the cache
+	Status           Status
+	Error            string
+	Path             string // File where this chart was found
+	Line             int    // Line number in file (0 if unknown)
 }
 
 // Status represents the update status
@@ -49,6 +116,7 @@ const (
 	StatusUpdateAvailable
 	StatusSkipped
 	StatusError
+	StatusMajorUpdateAvailable // The update raises the major version
 )
 
 func (s Status) String() string {
@@ -61,97 +129,377 @@ func (s Status) String() string {
 		return "SKIPPED"
 	case StatusError:
 		return "ERROR"
+	case StatusMajorUpdateAvailable:
+		return "MAJOR"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// IsUpdate reports whether the status is an available update, major or not
+func (s Status) IsUpdate() bool {
+	return s == StatusUpdateAvailable || s == StatusMajorUpdateAvailable
+}
+
 // Results holds all check results
 type Results struct {
-	Images []ImageResult
-	Charts []ChartResult
+	Images   []ImageResult
+	Charts   []ChartResult
+	Warnings []string // Problems that make the results incomplete
+
+	// Registries counts the requests made to each registry host
+	Registries []registry.RegistryStats
+
+	// Unsupported lists the distinct image hosts no registry type handles
+	Unsupported []string
+}
+
+// Summary holds the number of results per status
+type Summary struct {
+	Updates  int // Including major updates
+	Major    int // Major updates, also counted in Updates
+	UpToDate int
+	Skipped  int
+	Errors   int
+	Unknown  int
+}
+
+// Total returns the number of results counted in the summary
+func (s Summary) Total() int {
+	return s.Updates + s.UpToDate + s.Skipped + s.Errors + s.Unknown
+}
+
+// Summary counts image and chart results by status
+func (r *Results) Summary() Summary {
+	var s Summary
+
+	count := func(status Status) {
+		switch status {
+		case StatusMajorUpdateAvailable:
+			s.Updates++
+			s.Major++
+		case StatusUpdateAvailable:
+			s.Updates++
+		case StatusUpToDate:
+			s.UpToDate++
+		case StatusSkipped:
+			s.Skipped++
+		case StatusError:
+			s.Errors++
+		default:
+			s.Unknown++
+		}
+	}
+
+	for _, img := range r.Images {
+		count(img.Status)
+	}
+	for _, chart := range r.Charts {
+		count(chart.Status)
+	}
+
+	return s
 }
 
 // New creates a new Checker
 func New(c *cache.Cache) *Checker {
+	return NewWithRegistry(c, registry.New(registry.DefaultOptions()))
+}
+
+// NewWithRegistry creates a Checker that uses the given registry for lookups
+func NewWithRegistry(c *cache.Cache, reg Registry) *Checker {
 	return &Checker{
 		cache:    c,
-		registry: registry.New(),
+		registry: reg,
+		workers:  DefaultConcurrency,
 	}
 }
 
+// SetConcurrency sets the number of image and chart lookups CheckAll runs
+// at once. Values below 1 mean one at a time.
+func (c *Checker) SetConcurrency(n int) {
+	c.workers = max(n, 1)
+}
+
+// SetRepoRewrites sets repositories to look up in place of renamed ones,
+// keyed by "repository" or "registry/repository" as written in the files.
+// Results still show the original repository.
+func (c *Checker) SetRepoRewrites(rewrites map[string]string) {
+	c.rewrites = rewrites
+}
+
+// SetFetchDigests sets whether the manifest digest of each latest image tag
+// is looked up. This costs one extra registry request per image.
+func (c *Checker) SetFetchDigests(fetch bool) {
+	c.digests = fetch
+}
+
+// SetCheckAppReleases sets whether a chart's appVersion is compared with the
+// latest GitHub release of the chart's sources
+func (c *Checker) SetCheckAppReleases(check bool) {
+	c.apps = check
+}
+
+// SetOnlySemver makes cached tags be selected with only clean release
+// versions counting, like a registry created with Options.OnlySemver
+func (c *Checker) SetOnlySemver(enabled bool) {
+	c.semver = enabled
+}
+
+// SetOffline makes the checker answer from the cache only, without network
+// requests. Lookups missing from the cache are skipped, or reported as errors
+// if requireCache is set.
+func (c *Checker) SetOffline(offline, requireCache bool) {
+	c.offline = offline
+	c.strict = requireCache
+}
+
+// lookupRepository returns the repository to query for an image
+func (c *Checker) lookupRepository(img scanner.ImageInfo) string {
+	if repo, ok := c.rewrites[img.Registry+"/"+img.Repository]; ok {
+		return repo
+	}
+	if repo, ok := c.rewrites[img.Repository]; ok {
+		return repo
+	}
+	return img.Repository
+}
+
 // IsRateLimitError checks if an error is a rate limit error
 func IsRateLimitError(err error) bool {
 	return errors.Is(err, registry.ErrRateLimit)
 }
 
-// CheckAll checks all images and charts for updates
-func (c *Checker) CheckAll(scan *scanner.ScanResults) (*Results, error) {
+// NeedsLogin reports whether a rate limit could not be recovered from
+// because no Docker Hub credentials are configured
+func NeedsLogin(err error) bool {
+	return errors.Is(err, registry.ErrNoCredentials)
+}
+
+// CheckAll checks all images and charts for updates. Lookups run
+// concurrently; results keep the order of the scan. Once a lookup hits a
+// rate limit or ctx is done, lookups that have not started yet are not sent
+// and reported as errors, and the partial results are returned with the
+// error.
+func (c *Checker) CheckAll(ctx context.Context, scan *scanner.ScanResults) (*Results, error) {
 	results := &Results{
-		Images: make([]ImageResult, 0, len(scan.Images)),
-		Charts: make([]ChartResult, 0, len(scan.Charts)),
+		Images: make([]ImageResult, len(scan.Images)),
+		Charts: make([]ChartResult, len(scan.Charts)),
 	}
 
-	var rateLimitHit bool
+	var rateLimitHit atomic.Bool
+	c.unsupported = make(map[string]bool)
+	c.missing = make(map[string]bool)
+	c.resetAt = time.Time{}
+
+	// skipped returns why lookups that have not started are not sent, if so
+	skipped := func() string {
+		switch {
+		case ctx.Err() != nil:
+			return "cancelled"
+		case rateLimitHit.Load():
+			return "rate limit hit"
+		default:
+			return ""
+		}
+	}
 
 	// Check images
-	for _, img := range scan.Images {
-		if rateLimitHit {
-			results.Images = append(results.Images, ImageResult{
+	c.forEach(len(scan.Images), func(i int) {
+		img := scan.Images[i]
+		if reason := skipped(); reason != "" {
+			results.Images[i] = ImageResult{
 				Repository: img.Repository,
 				Registry:   img.Registry,
 				Current:    img.Tag,
+				Container:  img.ContainerName,
 				Status:     StatusError,
-				Error:      "rate limit hit",
+				Error:      reason,
 				Path:       img.Path,
 				Line:       img.Line,
-			})
-			continue
+			}
+			return
 		}
 
-		result := c.checkImage(img)
-		results.Images = append(results.Images, result)
+		result := c.checkImage(ctx, img)
+		if result.Error == "rate limit exceeded" && c.loginAfterRateLimit(ctx, img.Registry) {
+			result = c.checkImage(ctx, img)
+		}
+		result.Bump = bump(result.Status, result.Current, result.Latest)
+		results.Images[i] = result
 
 		if result.Error == "rate limit exceeded" {
-			rateLimitHit = true
+			rateLimitHit.Store(true)
 		}
-	}
+	})
 
 	// Check charts
-	for _, chart := range scan.Charts {
-		if rateLimitHit {
-			results.Charts = append(results.Charts, ChartResult{
-				Name:     chart.Name,
-				Current:  chart.Version,
-				Upstream: chart.Upstream,
-				Status:   StatusError,
-				Error:    "rate limit hit",
-				Path:     chart.Path,
-				Line:     chart.Line,
-			})
-			continue
+	appErrs := make([]error, len(scan.Charts))
+	c.forEach(len(scan.Charts), func(i int) {
+		chart := scan.Charts[i]
+		if reason := skipped(); reason != "" {
+			results.Charts[i] = ChartResult{
+				Name:         chart.Name,
+				Current:      chart.Version,
+				Upstream:     chart.Upstream,
+				DependencyOf: chart.DependencyOf,
+				Status:       StatusError,
+				Error:        reason,
+				Path:         chart.Path,
+				Line:         chart.Line,
+			}
+			return
 		}
 
-		result := c.checkChart(chart)
-		results.Charts = append(results.Charts, result)
+		result := c.checkChart(ctx, chart)
+		result.Bump = bump(result.Status, result.Current, result.Latest)
+		appErrs[i] = c.checkAppRelease(ctx, chart, &result)
+		results.Charts[i] = result
 
 		if result.Error == "rate limit exceeded" {
-			rateLimitHit = true
+			rateLimitHit.Store(true)
 		}
+	})
+	for i, err := range appErrs {
+		if err != nil {
+			results.Warnings = append(results.Warnings, fmt.Sprintf("app release of chart %s: %v", scan.Charts[i].Name, err))
+		}
+	}
+
+	if stats, ok := c.registry.(statsRegistry); ok {
+		results.Registries = stats.Stats()
+	}
+	for host := range c.unsupported {
+		results.Unsupported = append(results.Unsupported, host)
 	}
+	sort.Strings(results.Unsupported)
 
-	if rateLimitHit {
+	if c.strict && len(c.missing) > 0 {
+		missing := make([]string, 0, len(c.missing))
+		for key := range c.missing {
+			missing = append(missing, key)
+		}
+		sort.Strings(missing)
+		return results, fmt.Errorf("%w: %s", ErrCacheMiss, strings.Join(missing, ", "))
+	}
+
+	if err := ctx.Err(); err != nil {
+		results.Warnings = append(results.Warnings, "cancelled; remaining lookups were skipped")
+		return results, err
+	}
+
+	if rateLimitHit.Load() {
+		results.Warnings = append(results.Warnings, "rate limit hit; remaining lookups were skipped")
+		if c.loginErr != nil || !c.resetAt.IsZero() {
+			return results, &registry.RateLimitError{ResetAt: c.resetAt, Err: c.loginErr}
+		}
 		return results, registry.ErrRateLimit
 	}
 
 	return results, nil
 }
 
-func (c *Checker) checkImage(img scanner.ImageInfo) ImageResult {
+// bump returns the size of an available update. Updates that can't be told
+// apart by version numbers, e.g. a changed tag suffix, are BumpUnknown.
+func bump(status Status, current, latest string) registry.Bump {
+	if !status.IsUpdate() {
+		return registry.BumpNone
+	}
+	if b := registry.BumpLevel(current, latest); b != registry.BumpNone {
+		return b
+	}
+	return registry.BumpUnknown
+}
+
+// ApplyLevel reports available updates larger than level, e.g. major
+// updates for BumpMinor, as up to date, so they are neither counted nor
+// printed outside verbose output. Latest and Bump are kept, so verbose
+// output still shows the newer version. Updates of unknown size are kept,
+// and BumpNone keeps everything. Record results that should keep their
+// real status, e.g. in a lock file, before calling it.
+func (r *Results) ApplyLevel(level registry.Bump) {
+	if level == registry.BumpNone {
+		return
+	}
+
+	aboveLevel := func(status Status, bump registry.Bump) bool {
+		return status.IsUpdate() && bump > level && bump != registry.BumpUnknown
+	}
+	for i := range r.Images {
+		if aboveLevel(r.Images[i].Status, r.Images[i].Bump) {
+			r.Images[i].Status = StatusUpToDate
+		}
+	}
+	for i := range r.Charts {
+		if aboveLevel(r.Charts[i].Status, r.Charts[i].Bump) {
+			r.Charts[i].Status = StatusUpToDate
+		}
+	}
+}
+
+// forEach calls fn for 0..n-1 on a pool of up to c.workers goroutines and
+// waits for all calls to return. Indexes are handed out in order.
+func (c *Checker) forEach(n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(c.workers, 1), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// loginAfterRateLimit switches to authenticated Docker Hub requests after an
+// anonymous rate limit. It returns true if the failed lookup should be retried.
+func (c *Checker) loginAfterRateLimit(ctx context.Context, imageRegistry string) bool {
+	if imageRegistry != "docker.io" && imageRegistry != "" {
+		return false
+	}
+	hub, ok := c.registry.(dockerHubLogin)
+	if !ok {
+		return false
+	}
+
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	if c.loggedIn {
+		// Another lookup already logged in while this one was in flight
+		return c.loginErr == nil
+	}
+	if hub.DockerHubAuthenticated() {
+		return false
+	}
+	c.loggedIn = true
+
+	creds, err := hub.DockerHubCredentials()
+	if err != nil {
+		c.loginErr = err
+		return false
+	}
+	if err := hub.LoginDockerHub(ctx, creds); err != nil {
+		c.loginErr = err
+		return false
+	}
+	return true
+}
+
+func (c *Checker) checkImage(ctx context.Context, img scanner.ImageInfo) ImageResult {
 	result := ImageResult{
 		Repository: img.Repository,
 		Registry:   img.Registry,
 		Current:    img.Tag,
+		Digest:     img.Digest,
+		Container:  img.ContainerName,
 		Path:       img.Path,
 		Line:       img.Line,
 	}
@@ -159,27 +507,64 @@ func (c *Checker) checkImage(img scanner.ImageInfo) ImageResult {
 	if img.Skipped {
 		result.Status = StatusSkipped
 		result.Skipped = true
+		if img.ByDigest {
+			result.Current = img.Digest
+			result.Error = "pinned by digest"
+		}
 		return result
 	}
 
+	// Renamed images are looked up at their new location
+	repository := c.lookupRepository(img)
+
 	// Check cache first
-	cacheKey := fmt.Sprintf("%s/%s", img.Registry, img.Repository)
-	if latest, _, ok := c.cache.GetImage(cacheKey); ok {
+	cacheKey := imageCacheKey(img.Registry, repository)
+	result.Previous, _ = c.cache.PreviousImage(cacheKey)
+	if latest, tags, checkedAt, ok := c.cache.GetImage(cacheKey); ok {
+		// Re-select from the cached tags so the current tag and tag
+		// matching mode are honored; older entries may have no tags
+		latestAny := latest
+		if len(tags) > 0 {
+			latest, latestAny = registry.SelectLatest(tags, img.Tag, c.semver)
+		}
 		result.Latest = latest
+		result.LatestStable = latest
+		result.LatestAny = latestAny
+		result.LatestDigest = c.latestDigest(ctx, img.Registry, repository, latest)
+		result.CachedAt = checkedAt
 		result.Status = determineStatus(img.Tag, latest)
 		return result
 	}
 
+	if c.offline {
+		result.Status, result.Error = c.cacheMiss(cacheKey)
+		return result
+	}
+
 	// Fetch from registry
-	tagInfo, err := c.registry.GetLatestTag(img.Registry, img.Repository, img.Tag)
+	tagInfo, err := c.registry.GetLatestTag(ctx, img.Registry, repository, img.Tag)
 	if err != nil {
 		if errors.Is(err, registry.ErrRateLimit) {
 			result.Status = StatusError
 			result.Error = "rate limit exceeded"
+			c.noteRateLimit(err)
+		} else if ctx.Err() != nil {
+			result.Status = StatusError
+			result.Error = "cancelled"
+		} else if errors.Is(err, registry.ErrNoAWSCredentials) {
+			// Private ECR images can't be checked without credentials,
+			// which is not a failure of the run
+			result.Status = StatusSkipped
+			result.Error = "no AWS credentials for ECR"
 		} else {
 			result.Status = StatusError
 			result.Error = err.Error()
 		}
+		if errors.Is(err, registry.ErrUnsupportedRegistry) {
+			c.mu.Lock()
+			c.unsupported[img.Registry] = true
+			c.mu.Unlock()
+		}
 		return result
 	}
 
@@ -187,39 +572,124 @@ func (c *Checker) checkImage(img scanner.ImageInfo) ImageResult {
 	c.cache.SetImage(cacheKey, tagInfo.Latest, tagInfo.AllTags)
 
 	result.Latest = tagInfo.Latest
+	result.LatestStable = tagInfo.Latest
+	result.LatestAny = tagInfo.LatestAny
+	result.LatestDigest = c.latestDigest(ctx, img.Registry, repository, tagInfo.Latest)
 	result.Status = determineStatus(img.Tag, tagInfo.Latest)
 	return result
 }
 
-func (c *Checker) checkChart(chart scanner.ChartInfo) ChartResult {
+// noteRateLimit records when the rate limit of err resets, if it says
+func (c *Checker) noteRateLimit(err error) {
+	var rateErr *registry.RateLimitError
+	if !errors.As(err, &rateErr) {
+		return
+	}
+	c.mu.Lock()
+	if rateErr.ResetAt.After(c.resetAt) {
+		c.resetAt = rateErr.ResetAt
+	}
+	c.mu.Unlock()
+}
+
+// cacheMiss records a lookup missing from the cache in an offline run and
+// returns the status and error to report for it
+func (c *Checker) cacheMiss(cacheKey string) (Status, string) {
+	c.mu.Lock()
+	c.missing[cacheKey] = true
+	c.mu.Unlock()
+	if c.strict {
+		return StatusError, "not in cache"
+	}
+	return StatusSkipped, "not in cache (offline)"
+}
+
+// imageCacheKey identifies an image in the cache. Docker Hub's official images
+// get the same key whether written as "nginx" or "library/nginx".
+func imageCacheKey(imageRegistry, repository string) string {
+	if imageRegistry == "docker.io" || imageRegistry == "" {
+		namespace, name, _ := registry.NormalizeDockerRepo(repository)
+		return "docker.io/" + namespace + "/" + name
+	}
+	return imageRegistry + "/" + repository
+}
+
+// latestDigest returns the manifest digest of tag, if digests are enabled.
+// Digests are informational, so lookup errors leave it empty.
+func (c *Checker) latestDigest(ctx context.Context, imageRegistry, repository, tag string) string {
+	if !c.digests || tag == "" {
+		return ""
+	}
+
+	cacheKey := imageCacheKey(imageRegistry, repository)
+	if digest, ok := c.cache.GetImageDigest(cacheKey, tag); ok {
+		return digest
+	}
+
+	if c.offline {
+		return ""
+	}
+
+	digest, err := c.registry.GetDigest(ctx, imageRegistry, repository, tag)
+	if err != nil {
+		return ""
+	}
+	c.cache.SetImageDigest(cacheKey, tag, digest)
+	return digest
+}
+
+func (c *Checker) checkChart(ctx context.Context, chart scanner.ChartInfo) ChartResult {
 	result := ChartResult{
-		Name:     chart.Name,
-		Current:  chart.Version,
-		Upstream: chart.Upstream,
-		Path:     chart.Path,
-		Line:     chart.Line,
+		Name:         chart.Name,
+		Current:      chart.Version,
+		Upstream:     chart.Upstream,
+		DependencyOf: chart.DependencyOf,
+		Path:         chart.Path,
+		Line:         chart.Line,
 	}
 
-	// Skip charts without known upstreams
-	if chart.Upstream == "" {
+	// Skip charts without a known upstream or Helm repository
+	if chart.Upstream == "" && !registry.IsHelmRepoURL(chart.Repository) && !registry.IsOCIRepoURL(chart.Repository) {
 		result.Status = StatusSkipped
 		return result
 	}
 
 	// Check cache first
-	cacheKey := fmt.Sprintf("%s/%s", chart.Upstream, chart.Name)
-	if latest, ok := c.cache.GetChart(cacheKey); ok {
+	source := chart.Upstream
+	if source == "" {
+		source = strings.TrimSuffix(chart.Repository, "/")
+	}
+	cacheKey := fmt.Sprintf("%s/%s", source, chart.Name)
+	result.Previous, _ = c.cache.PreviousChart(cacheKey)
+	if latest, latestAny, checkedAt, ok := c.cache.GetChart(cacheKey); ok {
 		result.Latest = latest
+		result.LatestStable = latest
+		result.LatestAny = latestAny
+		result.CachedAt = checkedAt
 		result.Status = determineStatus(chart.Version, latest)
 		return result
 	}
 
-	// Fetch from ArtifactHub
-	versionInfo, err := c.registry.GetChartVersion(chart.Name, chart.Upstream)
+	if c.offline {
+		result.Status, result.Error = c.cacheMiss(cacheKey)
+		return result
+	}
+
+	// Fetch from ArtifactHub, falling back to the chart's Helm repository
+	versionInfo, err := c.registry.GetChartVersion(ctx, chart.Name, chart.Upstream, chart.Repository)
 	if err != nil {
 		if errors.Is(err, registry.ErrRateLimit) {
 			result.Status = StatusError
 			result.Error = "rate limit exceeded"
+			c.noteRateLimit(err)
+		} else if ctx.Err() != nil {
+			result.Status = StatusError
+			result.Error = "cancelled"
+		} else if errors.Is(err, registry.ErrNoAWSCredentials) {
+			// Charts in a private ECR repository (oci://) can't be checked
+			// without credentials either
+			result.Status = StatusSkipped
+			result.Error = "no AWS credentials for ECR"
 		} else {
 			result.Status = StatusError
 			result.Error = err.Error()
@@ -227,14 +697,63 @@ func (c *Checker) checkChart(chart scanner.ChartInfo) ChartResult {
 		return result
 	}
 
-	// Update cache
-	c.cache.SetChart(cacheKey, versionInfo.LatestVersion)
-
 	result.Latest = versionInfo.LatestVersion
+	result.LatestStable = versionInfo.LatestVersion
+	result.LatestAny = versionInfo.LatestAny
+	if result.LatestAny == "" {
+		result.LatestAny = versionInfo.LatestVersion
+	}
+
+	// Update cache
+	c.cache.SetChart(cacheKey, result.Latest, result.LatestAny)
 	result.Status = determineStatus(chart.Version, versionInfo.LatestVersion)
 	return result
 }
 
+// checkAppRelease looks up the latest GitHub release of the first GitHub
+// source of a chart with an appVersion. It applies to local charts too,
+// whose own version is not checked.
+func (c *Checker) checkAppRelease(ctx context.Context, chart scanner.ChartInfo, result *ChartResult) error {
+	if !c.apps || chart.AppVersion == "" {
+		return nil
+	}
+
+	for _, source := range chart.Sources {
+		owner, repo, ok := registry.ParseGitHubRepo(source)
+		if !ok {
+			continue
+		}
+
+		cacheKey := fmt.Sprintf("github/%s/%s", owner, repo)
+		latest, _, _, ok := c.cache.GetChart(cacheKey)
+		if !ok && c.offline {
+			c.cacheMiss(cacheKey)
+			return nil
+		}
+		if !ok {
+			var err error
+			latest, err = c.registry.GetLatestRelease(ctx, owner, repo)
+			if err != nil {
+				return err
+			}
+			c.cache.SetChart(cacheKey, latest, "")
+		}
+
+		result.AppVersion = chart.AppVersion
+		result.LatestAppVersion = latest
+		return nil
+	}
+	return nil
+}
+
+// AppBehind reports whether the chart's appVersion is older than the latest
+// release of its source
+func (r ChartResult) AppBehind() bool {
+	return r.LatestAppVersion != "" && registry.IsNewerVersion(r.LatestAppVersion, r.AppVersion)
+}
+
+// determineStatus compares the current and latest versions. Updates that
+// raise the major version are told apart.
 func determineStatus(current, latest string) Status {
 	if current == latest {
 		return StatusUpToDate
@@ -242,5 +761,8 @@ func determineStatus(current, latest string) Status {
 	if latest == "" {
 		return StatusUnknown
 	}
+	if registry.BumpLevel(current, latest) == registry.BumpMajor {
+		return StatusMajorUpdateAvailable
+	}
 	return StatusUpdateAvailable
 }
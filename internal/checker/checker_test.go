@@ -0,0 +1,767 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nogo/chartup/internal/cache"
+	"github.com/nogo/chartup/internal/registry"
+	"github.com/nogo/chartup/internal/scanner"
+)
+
+// fakeRegistry serves fixed tags and records the repositories queried
+type fakeRegistry struct {
+	mu       sync.Mutex
+	tags     map[string][]string // repository -> tags
+	digests  map[string]string   // repository:tag -> digest
+	queried  []string
+	digested []string          // repository:tag of each digest lookup
+	releases map[string]string // owner/repo -> latest release tag
+	errs     map[string]error  // repository -> tag lookup error
+	delay    time.Duration     // How long each successful tag lookup takes
+
+	inFlight, maxInFlight int // Concurrent tag lookups
+}
+
+func (f *fakeRegistry) GetLatestTag(ctx context.Context, reg, repository, currentTag string) (*registry.TagInfo, error) {
+	f.mu.Lock()
+	f.queried = append(f.queried, repository)
+	err := f.errs[repository]
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	tags := f.tags[repository]
+	latest, latestAny := registry.SelectLatest(tags, currentTag, false)
+	return &registry.TagInfo{Name: repository, Latest: latest, LatestAny: latestAny, AllTags: tags}, nil
+}
+
+func (f *fakeRegistry) GetDigest(ctx context.Context, reg, repository, tag string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.digested = append(f.digested, repository+":"+tag)
+	digest, ok := f.digests[repository+":"+tag]
+	if !ok {
+		return "", fmt.Errorf("manifest %s:%s not found", repository, tag)
+	}
+	return digest, nil
+}
+
+func (f *fakeRegistry) GetLatestRelease(ctx context.Context, owner, repo string) (string, error) {
+	release, ok := f.releases[owner+"/"+repo]
+	if !ok {
+		return "", fmt.Errorf("no GitHub releases found for %s/%s", owner, repo)
+	}
+	return release, nil
+}
+
+func (f *fakeRegistry) GetChartVersion(ctx context.Context, chartName, upstream, repository string) (*registry.ChartVersionInfo, error) {
+	f.mu.Lock()
+	err := f.errs[chartName]
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &registry.ChartVersionInfo{Name: chartName}, nil
+}
+
+func newTestCache(t *testing.T) *cache.Cache {
+	t.Helper()
+	tmpDir, err := os.MkdirTemp("", "chartup-checker-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	return cache.New(filepath.Join(tmpDir, "cache.json"), 1*time.Hour, false)
+}
+
+func TestCheckAll_RepoRewrites(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{
+		"bitnami/postgresql":       {"15.0.0"},
+		"bitnamilegacy/postgresql": {"15.0.0", "16.1.0"},
+		"library/redis":            {"7.0.0", "7.2.0"},
+	}}
+
+	chk := NewWithRegistry(newTestCache(t), reg)
+	chk.SetRepoRewrites(map[string]string{
+		"bitnami/postgresql":      "bitnamilegacy/postgresql",
+		"docker.io/library/redis": "library/redis",
+	})
+
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "bitnami/postgresql", Tag: "15.0.0"},
+		{Registry: "docker.io", Repository: "library/redis", Tag: "7.0.0"},
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+	}}
+
+	results, err := chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	wantQueried := []string{"bitnamilegacy/postgresql", "library/redis", "nginx"}
+	sort.Strings(reg.queried)
+	if len(reg.queried) != len(wantQueried) {
+		t.Fatalf("queried %v, want %v", reg.queried, wantQueried)
+	}
+	for i := range wantQueried {
+		if reg.queried[i] != wantQueried[i] {
+			t.Errorf("queried[%d] = %q, want %q", i, reg.queried[i], wantQueried[i])
+		}
+	}
+
+	pg := results.Images[0]
+	if pg.Repository != "bitnami/postgresql" {
+		t.Errorf("Repository = %q, want original %q", pg.Repository, "bitnami/postgresql")
+	}
+	if pg.Latest != "16.1.0" || pg.Status != StatusMajorUpdateAvailable {
+		t.Errorf("got latest %q status %v, want 16.1.0 MAJOR", pg.Latest, pg.Status)
+	}
+}
+
+func TestCheckAll_LatestDigest(t *testing.T) {
+	reg := &fakeRegistry{
+		tags:    map[string][]string{"nginx": {"1.25.0", "1.26.0"}},
+		digests: map[string]string{"nginx:1.26.0": "sha256:abc"},
+	}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+	}}
+
+	c := newTestCache(t)
+	chk := NewWithRegistry(c, reg)
+	results, err := chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if got := results.Images[0].LatestDigest; got != "" {
+		t.Errorf("LatestDigest = %q without SetFetchDigests, want empty", got)
+	}
+
+	chk.SetFetchDigests(true)
+	results, err = chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if got := results.Images[0].LatestDigest; got != "sha256:abc" {
+		t.Errorf("LatestDigest = %q, want %q", got, "sha256:abc")
+	}
+	if len(reg.digested) != 1 || reg.digested[0] != "nginx:1.26.0" {
+		t.Errorf("digest lookups = %v, want only the latest tag nginx:1.26.0", reg.digested)
+	}
+
+	// Served from the cache on the next run
+	reg.digests = nil
+	chk = NewWithRegistry(c, reg)
+	chk.SetFetchDigests(true)
+	results, _ = chk.CheckAll(context.Background(), scan)
+	if got := results.Images[0].LatestDigest; got != "sha256:abc" {
+		t.Errorf("cached LatestDigest = %q, want %q", got, "sha256:abc")
+	}
+}
+
+func TestCheckAll_TagAndDigest(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{"org/app": {"v1.2.3", "v1.3.0"}}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "ghcr.io", Repository: "org/app", Tag: "v1.2.3", Digest: "sha256:abc"},
+	}}
+
+	results, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	got := results.Images[0]
+	if got.Current != "v1.2.3" || got.Digest != "sha256:abc" {
+		t.Errorf("current %q digest %q, want v1.2.3 pinned to sha256:abc", got.Current, got.Digest)
+	}
+	if got.Latest != "v1.3.0" || got.Status != StatusUpdateAvailable {
+		t.Errorf("latest %q status %v, want v1.3.0 UPDATE", got.Latest, got.Status)
+	}
+}
+
+func TestCheckAll_PinnedByDigest(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{"nginx": {"1.26.0"}}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "nginx", Digest: "sha256:abc", ByDigest: true, Skipped: true},
+	}}
+
+	results, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	got := results.Images[0]
+	if got.Status != StatusSkipped || got.Error != "pinned by digest" {
+		t.Errorf("status %v error %q, want SKIP pinned by digest", got.Status, got.Error)
+	}
+	if got.Current != "sha256:abc" {
+		t.Errorf("Current = %q, want the pinned digest", got.Current)
+	}
+	if len(reg.queried) != 0 {
+		t.Errorf("queried %v, want no lookups for a digest-pinned image", reg.queried)
+	}
+}
+
+func TestCheckAll_CachedAt(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{"nginx": {"1.25.0", "1.26.0"}}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+	}}
+
+	c := newTestCache(t)
+	results, err := NewWithRegistry(c, reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if got := results.Images[0].CachedAt; !got.IsZero() {
+		t.Errorf("CachedAt = %v for a fresh lookup, want zero", got)
+	}
+
+	results, _ = NewWithRegistry(c, reg).CheckAll(context.Background(), scan)
+	if got := results.Images[0].CachedAt; got.IsZero() || time.Since(got) > time.Minute {
+		t.Errorf("CachedAt = %v for a cached lookup, want the time of the first run", got)
+	}
+}
+
+func TestCheckAll_Bump(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{
+		"org/patch":  {"1.2.3", "1.2.9"},
+		"org/minor":  {"1.2.3", "1.5.0"},
+		"org/major":  {"1.2.3", "2.0.0"},
+		"org/latest": {"1.2.3"},
+	}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "ghcr.io", Repository: "org/patch", Tag: "1.2.3"},
+		{Registry: "ghcr.io", Repository: "org/minor", Tag: "1.2.3"},
+		{Registry: "ghcr.io", Repository: "org/major", Tag: "1.2.3"},
+		{Registry: "ghcr.io", Repository: "org/latest", Tag: "1.2.3"},
+	}}
+
+	results, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	want := []registry.Bump{registry.BumpPatch, registry.BumpMinor, registry.BumpMajor, registry.BumpNone}
+	for i, img := range results.Images {
+		if img.Bump != want[i] {
+			t.Errorf("%s %s -> %s: Bump = %v, want %v", img.Repository, img.Current, img.Latest, img.Bump, want[i])
+		}
+	}
+
+}
+
+func TestCheckAll_Level(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{
+		"org/patch": {"1.2.3", "1.2.9"},
+		"org/minor": {"1.2.3", "1.5.0"},
+		"org/major": {"1.2.3", "2.0.0"},
+	}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "ghcr.io", Repository: "org/patch", Tag: "1.2.3"},
+		{Registry: "ghcr.io", Repository: "org/minor", Tag: "1.2.3"},
+		{Registry: "ghcr.io", Repository: "org/major", Tag: "1.2.3"},
+	}}
+
+	tests := []struct {
+		level registry.Bump
+		want  []Status // patch, minor, major
+	}{
+		{registry.BumpNone, []Status{StatusUpdateAvailable, StatusUpdateAvailable, StatusMajorUpdateAvailable}},
+		{registry.BumpMajor, []Status{StatusUpdateAvailable, StatusUpdateAvailable, StatusMajorUpdateAvailable}},
+		{registry.BumpMinor, []Status{StatusUpdateAvailable, StatusUpdateAvailable, StatusUpToDate}},
+		{registry.BumpPatch, []Status{StatusUpdateAvailable, StatusUpToDate, StatusUpToDate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			results, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+			if err != nil {
+				t.Fatalf("CheckAll() error = %v", err)
+			}
+			// The checker reports what it found; the level applies afterwards
+			if got := results.Images[2].Status; got != StatusMajorUpdateAvailable {
+				t.Errorf("major update Status before ApplyLevel = %v, want major", got)
+			}
+			results.ApplyLevel(tt.level)
+
+			if len(results.Images) != 3 {
+				t.Fatalf("ApplyLevel(%v) left %d images, want all 3", tt.level, len(results.Images))
+			}
+			for i, img := range results.Images {
+				if img.Status != tt.want[i] {
+					t.Errorf("%s %s -> %s: Status = %v, want %v", img.Repository, img.Current, img.Latest, img.Status, tt.want[i])
+				}
+			}
+			// Updates above the level keep their latest version and size
+			if img := results.Images[2]; img.Latest != "2.0.0" || img.Bump != registry.BumpMajor {
+				t.Errorf("major update = %s (%v), want 2.0.0 (major)", img.Latest, img.Bump)
+			}
+			if got := results.Summary().Updates; got != countUpdates(tt.want) {
+				t.Errorf("Summary().Updates = %d, want %d", got, countUpdates(tt.want))
+			}
+		})
+	}
+
+	// Updates of unknown size are always reported
+	results := &Results{Images: []ImageResult{
+		{Repository: "org/nightly", Current: "nightly-a", Latest: "nightly-b", Status: StatusUpdateAvailable, Bump: registry.BumpUnknown},
+	}}
+	results.ApplyLevel(registry.BumpPatch)
+	if results.Images[0].Status != StatusUpdateAvailable {
+		t.Errorf("ApplyLevel(patch) hid an update of unknown size")
+	}
+}
+
+// countUpdates counts the available updates in statuses
+func countUpdates(statuses []Status) int {
+	n := 0
+	for _, status := range statuses {
+		if status.IsUpdate() {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDetermineStatus(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            Status
+	}{
+		{"1.2.3", "1.2.3", StatusUpToDate},
+		{"1.2.3", "", StatusUnknown},
+		{"1.2.3", "2.0.0", StatusMajorUpdateAvailable},
+		{"v1.9.9", "v2.0.0", StatusMajorUpdateAvailable},
+		{"2.0.0", "2.0.1", StatusUpdateAvailable},
+		{"1.2.3", "1.3.0", StatusUpdateAvailable},
+		{"stable", "edge", StatusUpdateAvailable}, // Size unknown
+	}
+	for _, tt := range tests {
+		if got := determineStatus(tt.current, tt.latest); got != tt.want {
+			t.Errorf("determineStatus(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestResults_SummaryMajor(t *testing.T) {
+	results := &Results{
+		Images: []ImageResult{
+			{Status: StatusMajorUpdateAvailable},
+			{Status: StatusUpdateAvailable},
+			{Status: StatusUpToDate},
+		},
+		Charts: []ChartResult{{Status: StatusMajorUpdateAvailable}},
+	}
+
+	s := results.Summary()
+	if s.Updates != 3 || s.Major != 2 || s.UpToDate != 1 || s.Total() != 4 {
+		t.Errorf("Summary() = %+v (total %d), want 3 updates of which 2 major, 1 up to date, 4 total", s, s.Total())
+	}
+	if !StatusMajorUpdateAvailable.IsUpdate() || !StatusUpdateAvailable.IsUpdate() || StatusUpToDate.IsUpdate() {
+		t.Error("IsUpdate() should hold for UPDATE and MAJOR only")
+	}
+}
+
+func TestCheckAll_AppReleases(t *testing.T) {
+	reg := &fakeRegistry{releases: map[string]string{"org/app": "v1.4.0"}}
+	scan := &scanner.ScanResults{Charts: []scanner.ChartInfo{
+		{Name: "app", Version: "0.1.0", AppVersion: "1.2.0", Sources: []string{"https://example.com", "https://github.com/org/app"}},
+		{Name: "other", Version: "0.1.0", AppVersion: "2.0.0", Sources: []string{"https://github.com/org/other"}},
+		{Name: "nosources", Version: "0.1.0", AppVersion: "1.0.0"},
+	}}
+
+	chk := NewWithRegistry(newTestCache(t), reg)
+	results, err := chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if results.Charts[0].LatestAppVersion != "" {
+		t.Errorf("LatestAppVersion = %q without SetCheckAppReleases, want empty", results.Charts[0].LatestAppVersion)
+	}
+
+	chk.SetCheckAppReleases(true)
+	results, err = chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	app := results.Charts[0]
+	if app.AppVersion != "1.2.0" || app.LatestAppVersion != "v1.4.0" || !app.AppBehind() {
+		t.Errorf("got app %q latest %q behind %v, want 1.2.0 v1.4.0 true", app.AppVersion, app.LatestAppVersion, app.AppBehind())
+	}
+	if results.Charts[2].AppBehind() {
+		t.Error("chart without sources reported as behind")
+	}
+	if len(results.Warnings) != 1 || !strings.Contains(results.Warnings[0], "other") {
+		t.Errorf("Warnings = %v, want one for chart other", results.Warnings)
+	}
+}
+
+func TestCheckAll_PreviousLatest(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-checker-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cacheFile := filepath.Join(tmpDir, "cache.json")
+
+	// Previous run cached 1.26.0 as the latest nginx
+	old := cache.New(cacheFile, 1*time.Hour, false)
+	old.SetImage("docker.io/library/nginx", "1.26.0", []string{"1.25.0", "1.26.0"})
+	old.SetImage("docker.io/library/redis", "7.2.0", []string{"7.0.0", "7.2.0"})
+	if err := old.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The registry now has a newer nginx; refresh skips the cached values
+	c := cache.New(cacheFile, 1*time.Hour, true)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	reg := &fakeRegistry{tags: map[string][]string{
+		"nginx": {"1.25.0", "1.26.0", "1.27.0"},
+		"redis": {"7.0.0", "7.2.0"},
+	}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+		{Registry: "docker.io", Repository: "redis", Tag: "7.0.0"},
+		{Registry: "docker.io", Repository: "busybox", Tag: "1.36.0"},
+	}}
+
+	results, err := NewWithRegistry(c, reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	nginx := results.Images[0]
+	if nginx.Previous != "1.26.0" || nginx.Latest != "1.27.0" {
+		t.Errorf("nginx previous %q latest %q, want 1.26.0 1.27.0", nginx.Previous, nginx.Latest)
+	}
+	if redis := results.Images[1]; redis.Previous != redis.Latest {
+		t.Errorf("redis previous %q latest %q, want unchanged", redis.Previous, redis.Latest)
+	}
+	if busybox := results.Images[2]; busybox.Previous != "" {
+		t.Errorf("busybox previous = %q, want empty for uncached image", busybox.Previous)
+	}
+}
+
+func TestCheckAll_UnsupportedRegistries(t *testing.T) {
+	// A web server that answers, but not the registry API
+	web := httptest.NewTLSServer(http.NotFoundHandler())
+	defer web.Close()
+	webHost := strings.TrimPrefix(web.URL, "https://")
+
+	// A registry host that is down
+	down := httptest.NewTLSServer(http.NotFoundHandler())
+	downHost := strings.TrimPrefix(down.URL, "https://")
+	down.Close()
+
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: webHost, Repository: "org/app", Tag: "1.0.0"},
+		{Registry: webHost, Repository: "org/worker", Tag: "1.0.0"},
+		{Registry: downHost, Repository: "team/api", Tag: "2.0.0"},
+	}}
+
+	reg := registry.New(registry.Options{InsecureRegistries: []string{webHost, downHost}})
+	results, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	if strings.Join(results.Unsupported, ",") != webHost {
+		t.Errorf("Unsupported = %v, want [%s]", results.Unsupported, webHost)
+	}
+	if got := results.Images[0].Error; !strings.Contains(got, webHost) {
+		t.Errorf("Error = %q, want it to name the host", got)
+	}
+	if api := results.Images[2]; api.Status != StatusError {
+		t.Errorf("image on a host that is down = %v, want an error", api.Status)
+	}
+}
+
+func TestCheckAll_ECRWithoutCredentials(t *testing.T) {
+	reg := &fakeRegistry{
+		tags: map[string][]string{"nginx": {"1.25.0", "1.26.0"}},
+		errs: map[string]error{"team/service": fmt.Errorf("%w: no providers", registry.ErrNoAWSCredentials)},
+	}
+	reg.errs["service-chart"] = fmt.Errorf("%w: no providers", registry.ErrNoAWSCredentials)
+	scan := &scanner.ScanResults{
+		Images: []scanner.ImageInfo{
+			{Registry: "123456789012.dkr.ecr.eu-central-1.amazonaws.com", Repository: "team/service", Tag: "1.0.0"},
+			{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+		},
+		Charts: []scanner.ChartInfo{
+			{Name: "service-chart", Version: "1.0.0", Repository: "oci://123456789012.dkr.ecr.eu-central-1.amazonaws.com/charts"},
+		},
+	}
+
+	results, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	ecr := results.Images[0]
+	if ecr.Status != StatusSkipped {
+		t.Errorf("ECR status = %v, want skipped", ecr.Status)
+	}
+	if !strings.Contains(ecr.Error, "AWS credentials") {
+		t.Errorf("ECR reason = %q, want it to mention AWS credentials", ecr.Error)
+	}
+	if results.Images[1].Status != StatusUpdateAvailable {
+		t.Errorf("nginx status = %v, want update", results.Images[1].Status)
+	}
+	if chart := results.Charts[0]; chart.Status != StatusSkipped || !strings.Contains(chart.Error, "AWS credentials") {
+		t.Errorf("ECR chart = %v (%s), want skipped for AWS credentials", chart.Status, chart.Error)
+	}
+	if len(results.Unsupported) != 0 {
+		t.Errorf("Unsupported = %v, want none", results.Unsupported)
+	}
+}
+
+func TestCheckAll_ChartCacheLatestAny(t *testing.T) {
+	c := newTestCache(t)
+	c.SetChart("bitnami/postgresql", "15.0.0", "16.0.0-rc.1")
+
+	scan := &scanner.ScanResults{Charts: []scanner.ChartInfo{
+		{Name: "postgresql", Version: "14.0.0", Upstream: "bitnami"},
+	}}
+	results, err := NewWithRegistry(c, &fakeRegistry{}).CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	chart := results.Charts[0]
+	if chart.Latest != "15.0.0" || chart.LatestAny != "16.0.0-rc.1" {
+		t.Errorf("cached chart = %q (any %q), want 15.0.0 (any 16.0.0-rc.1)", chart.Latest, chart.LatestAny)
+	}
+}
+
+func TestCheckAll_OfficialImageCacheKey(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{
+		"nginx":         {"1.25.0", "1.26.0"},
+		"library/nginx": {"1.25.0", "1.26.0"},
+	}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+		{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25.0"},
+	}}
+
+	// One at a time, so the second image finds the first one's cache entry
+	chk := NewWithRegistry(newTestCache(t), reg)
+	chk.SetConcurrency(1)
+	results, err := chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	// Both spellings share one cache entry, so only the first is looked up
+	if len(reg.queried) != 1 {
+		t.Errorf("queried %v, want a single lookup", reg.queried)
+	}
+	for _, img := range results.Images {
+		if img.Latest != "1.26.0" {
+			t.Errorf("%s latest = %q, want 1.26.0", img.Repository, img.Latest)
+		}
+	}
+}
+
+func TestCheckAll_Offline(t *testing.T) {
+	c := newTestCache(t)
+	c.SetImage("docker.io/library/nginx", "1.26.0", []string{"1.25.0", "1.26.0"})
+
+	reg := &fakeRegistry{tags: map[string][]string{"redis": {"7.2.0"}}}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25.0"},
+		{Registry: "docker.io", Repository: "redis", Tag: "7.0.0"},
+	}}
+
+	chk := NewWithRegistry(c, reg)
+	chk.SetOffline(true, false)
+	results, err := chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(reg.queried) != 0 {
+		t.Errorf("queried %v while offline, want no lookups", reg.queried)
+	}
+	if got := results.Images[0]; got.Latest != "1.26.0" || got.Status != StatusUpdateAvailable {
+		t.Errorf("nginx latest %q status %v, want cached 1.26.0 UPDATE", got.Latest, got.Status)
+	}
+	if got := results.Images[1]; got.Status != StatusSkipped {
+		t.Errorf("redis status = %v, want SKIP for a cache miss", got.Status)
+	}
+
+	chk.SetOffline(true, true)
+	results, err = chk.CheckAll(context.Background(), scan)
+	if !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("CheckAll() error = %v, want ErrCacheMiss", err)
+	}
+	if !strings.Contains(err.Error(), "docker.io/library/redis") {
+		t.Errorf("error %q does not name the missing entry", err)
+	}
+	if got := results.Images[1]; got.Status != StatusError {
+		t.Errorf("redis status = %v, want ERROR under require-cache", got.Status)
+	}
+}
+
+func TestCheckAll_RateLimitReset(t *testing.T) {
+	resetAt := time.Now().Add(10 * time.Minute)
+	reg := &fakeRegistry{
+		tags: map[string][]string{},
+		errs: map[string]error{"org/limited": &registry.RateLimitError{ResetAt: resetAt}},
+	}
+	scan := &scanner.ScanResults{Images: []scanner.ImageInfo{
+		{Registry: "ghcr.io", Repository: "org/limited", Tag: "1.0.0"},
+	}}
+
+	_, err := NewWithRegistry(newTestCache(t), reg).CheckAll(context.Background(), scan)
+	if !IsRateLimitError(err) {
+		t.Fatalf("CheckAll() error = %v, want a rate limit", err)
+	}
+	var rateErr *registry.RateLimitError
+	if !errors.As(err, &rateErr) || !rateErr.ResetAt.Equal(resetAt) {
+		t.Errorf("CheckAll() error = %#v, want the registry's reset time", err)
+	}
+}
+
+func TestCheckAll_Concurrent(t *testing.T) {
+	reg := &fakeRegistry{tags: map[string][]string{}, delay: 20 * time.Millisecond}
+	scan := &scanner.ScanResults{}
+	for i := range 12 {
+		repo := fmt.Sprintf("org/app%d", i)
+		reg.tags[repo] = []string{"1.0.0", fmt.Sprintf("1.%d.0", i+1)}
+		scan.Images = append(scan.Images, scanner.ImageInfo{Registry: "ghcr.io", Repository: repo, Tag: "1.0.0", Line: i + 1})
+	}
+
+	chk := NewWithRegistry(newTestCache(t), reg)
+	chk.SetConcurrency(4)
+	results, err := chk.CheckAll(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	if reg.maxInFlight < 2 || reg.maxInFlight > 4 {
+		t.Errorf("max concurrent lookups = %d, want 2..4", reg.maxInFlight)
+	}
+	if len(results.Images) != len(scan.Images) {
+		t.Fatalf("got %d results, want %d", len(results.Images), len(scan.Images))
+	}
+	for i, img := range results.Images {
+		if img.Repository != scan.Images[i].Repository || img.Line != i+1 {
+			t.Errorf("result %d = %s line %d, want scan order", i, img.Repository, img.Line)
+		}
+		if want := fmt.Sprintf("1.%d.0", i+1); img.Latest != want {
+			t.Errorf("%s latest = %q, want %q", img.Repository, img.Latest, want)
+		}
+	}
+}
+
+func TestCheckAll_Cancel(t *testing.T) {
+	// Lookups only finish when cancelled
+	reg := &fakeRegistry{tags: map[string][]string{}, delay: time.Hour}
+	c := newTestCache(t)
+	c.SetImage("ghcr.io/org/cached", "1.1.0", []string{"1.0.0", "1.1.0"})
+	scan := &scanner.ScanResults{
+		Images: []scanner.ImageInfo{{Registry: "ghcr.io", Repository: "org/cached", Tag: "1.0.0"}},
+		Charts: []scanner.ChartInfo{{Name: "redis", Version: "1.0.0", Upstream: "bitnami"}},
+	}
+	for i := range 6 {
+		scan.Images = append(scan.Images, scanner.ImageInfo{Registry: "ghcr.io", Repository: fmt.Sprintf("org/app%d", i), Tag: "1.0.0"})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	chk := NewWithRegistry(c, reg)
+	chk.SetConcurrency(2)
+	start := time.Now()
+	results, err := chk.CheckAll(ctx, scan)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CheckAll() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("CheckAll() took %v after cancelling", elapsed)
+	}
+
+	if got := results.Images[0]; got.Status != StatusUpdateAvailable {
+		t.Errorf("cached image status = %v, want UPDATE", got.Status)
+	}
+	for _, img := range results.Images[1:] {
+		if img.Status != StatusError || img.Error != "cancelled" {
+			t.Errorf("%s = %v %q, want a cancelled error", img.Repository, img.Status, img.Error)
+		}
+	}
+	if got := results.Charts[0]; got.Status != StatusError || got.Error != "cancelled" {
+		t.Errorf("chart = %v %q, want a cancelled error", got.Status, got.Error)
+	}
+	if len(reg.queried) > 2 {
+		t.Errorf("queried %v after cancelling, want only the lookups in flight", reg.queried)
+	}
+}
+
+func TestCheckAll_RateLimitShortCircuit(t *testing.T) {
+	const workers = 2
+	reg := &fakeRegistry{
+		tags:  map[string][]string{},
+		errs:  map[string]error{"org/limited": registry.ErrRateLimit},
+		delay: 50 * time.Millisecond,
+	}
+	scan := &scanner.ScanResults{
+		Images: []scanner.ImageInfo{{Registry: "ghcr.io", Repository: "org/limited", Tag: "1.0.0"}},
+		Charts: []scanner.ChartInfo{{Name: "redis", Version: "1.0.0"}},
+	}
+	for i := range 6 {
+		repo := fmt.Sprintf("org/app%d", i)
+		reg.tags[repo] = []string{"1.0.0"}
+		scan.Images = append(scan.Images, scanner.ImageInfo{Registry: "ghcr.io", Repository: repo, Tag: "1.0.0"})
+	}
+
+	chk := NewWithRegistry(newTestCache(t), reg)
+	chk.SetConcurrency(workers)
+	results, err := chk.CheckAll(context.Background(), scan)
+	if !errors.Is(err, registry.ErrRateLimit) {
+		t.Fatalf("CheckAll() error = %v, want ErrRateLimit", err)
+	}
+
+	// Only lookups already started when the limit was hit reach the registry
+	if len(reg.queried) > workers {
+		t.Errorf("queried %v, want at most %d lookups", reg.queried, workers)
+	}
+	if got := results.Images[0].Error; got != "rate limit exceeded" {
+		t.Errorf("first image error = %q, want rate limit exceeded", got)
+	}
+	hit := 0
+	for _, img := range results.Images[1:] {
+		if img.Error == "rate limit hit" {
+			hit++
+		}
+	}
+	if want := len(scan.Images) - len(reg.queried); hit != want {
+		t.Errorf("%d images marked rate limit hit, want %d", hit, want)
+	}
+	if got := results.Charts[0].Error; got != "rate limit hit" {
+		t.Errorf("chart error = %q, want rate limit hit", got)
+	}
+}
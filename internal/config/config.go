@@ -0,0 +1,214 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nogo/chartup/internal/checker"
+	"github.com/nogo/chartup/internal/registry"
+	"github.com/nogo/chartup/internal/scanner"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFilename is the config file auto-loaded from the scanned directory
+const DefaultFilename = ".chartup.yaml"
+
+// Config holds the effective chartup settings
+type Config struct {
+	CacheFile   string        `yaml:"cacheFile"`
+	CacheTTL    time.Duration `yaml:"cacheTTL"`
+	CacheMaxAge time.Duration `yaml:"cacheMaxAge"` // Prune entries older than this on save (0 = never)
+	Editor      string        `yaml:"editor"`
+	Verbose     bool          `yaml:"verbose"`
+	Refresh     bool          `yaml:"refresh"`
+
+	// Level is the largest update reported: "patch", "minor", or "major"
+	Level string `yaml:"level"`
+
+	// Registries limits checks to images on these registries (empty = all)
+	Registries []string `yaml:"registries"`
+
+	// Offline answers lookups from the cache only, ignoring its TTL
+	Offline bool `yaml:"offline"`
+
+	// RequireCache makes a cache miss in an offline run an error
+	RequireCache bool `yaml:"requireCache"`
+
+	// ScanSchemas enables image extraction from values.schema.json defaults
+	ScanSchemas bool `yaml:"scanSchemas"`
+
+	// ScanManifests enables container image extraction from Kubernetes
+	// manifests, i.e. YAML files other than Chart.yaml and values.yaml.
+	// On by default
+	ScanManifests bool `yaml:"scanManifests"`
+
+	// CheckMainChart checks each chart's own version against its detected
+	// upstream even when it looks locally developed
+	CheckMainChart bool `yaml:"checkMainChart"`
+
+	// CheckAppReleases compares each chart's appVersion with the latest
+	// GitHub release of the chart's sources
+	CheckAppReleases bool `yaml:"checkAppReleases"`
+
+	// MaxFileSize skips scanned files larger than this many bytes (0 = no limit)
+	MaxFileSize int64 `yaml:"maxFileSize"`
+
+	// Exclude skips scanned paths matching these globs, relative to the
+	// scanned directory
+	Exclude []string `yaml:"exclude"`
+
+	// Only restricts scanning to paths matching these globs, relative to
+	// the scanned directory
+	Only []string `yaml:"only"`
+
+	// Gitignore skips paths ignored by the scanned directory's .gitignore
+	Gitignore bool `yaml:"gitignore"`
+
+	// Upstreams maps chart names to the ArtifactHub repository their
+	// versions are looked up in, ahead of the built-in detection
+	// (e.g. "keycloak": "codecentric"; "" turns detection off for a chart)
+	Upstreams map[string]string `yaml:"upstreams"`
+
+	// SkipImages skips images whose reference contains one of these
+	// strings, or matches it if it is a glob pattern
+	SkipImages []string `yaml:"skipImages"`
+
+	// RepoRewrites maps renamed repositories to where updates are looked up,
+	// e.g. "bitnami/postgresql": "bitnamilegacy/postgresql"
+	RepoRewrites map[string]string `yaml:"repoRewrites"`
+
+	// PreferDigest looks up the digest of each latest tag for pinning,
+	// at the cost of one extra registry request per image
+	PreferDigest bool `yaml:"preferDigest"`
+
+	// Concurrency is the number of image and chart lookups run at once
+	Concurrency int `yaml:"concurrency"`
+
+	// MaxConcurrencyDockerHub limits concurrent docker.io lookups to stay
+	// clear of Docker Hub's anonymous rate limit (0 = no limit)
+	MaxConcurrencyDockerHub int `yaml:"maxConcurrencyDockerHub"`
+
+	// DockerHubMaxPages is how many pages of 100 tags are read per Docker
+	// Hub repository
+	DockerHubMaxPages int `yaml:"dockerHubMaxPages"`
+
+	// Timeout bounds each registry HTTP request
+	Timeout time.Duration `yaml:"timeout"`
+
+	// RegistryTimeouts overrides Timeout for a registry host and its
+	// subdomains, e.g. "ghcr.io": 30s
+	RegistryTimeouts map[string]time.Duration `yaml:"registryTimeouts"`
+
+	// Retries is how many times a registry request that failed with a 5xx
+	// status or a connection error is retried, with exponential backoff
+	Retries int `yaml:"retries"`
+
+	// LookupBudget bounds the total time of one image or chart lookup,
+	// across all of its requests (0 = no limit)
+	LookupBudget time.Duration `yaml:"lookupBudget"`
+
+	// DockerConfig is a Docker CLI config.json whose "auths" entries are used
+	// to authenticate registry lookups (empty = anonymous)
+	DockerConfig string `yaml:"dockerConfig"`
+
+	// RegistryEndpointOverride is the OCI registry API host for docker.io
+	// tag lookups, e.g. "registry-1.docker.io" (empty = hub.docker.com API)
+	RegistryEndpointOverride string `yaml:"registryEndpointOverride"`
+
+	// RegistryMirrors maps registries to a mirror host their images are
+	// looked up on instead, e.g. "docker.io": "mirror.corp.net"
+	RegistryMirrors map[string]string `yaml:"registryMirrors"`
+
+	// InsecureRegistries lists registry hosts reached without TLS
+	// certificate verification, or over plain HTTP
+	InsecureRegistries []string `yaml:"insecureRegistries"`
+
+	// OnlySemver ignores non-version tags for all images, not just those
+	// whose current tag is a clean X.Y.Z release
+	OnlySemver bool `yaml:"onlySemver"`
+}
+
+// CacheFilename is the name of the cache file in a cache directory
+const CacheFilename = "cache.json"
+
+// DefaultCacheFile returns the user-wide cache file,
+// $XDG_CACHE_HOME/chartup/cache.json or ~/.cache/chartup/cache.json on Linux.
+// Without a user cache directory, it is .chartup-cache.json in the current
+// directory.
+func DefaultCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".chartup-cache.json"
+	}
+	return filepath.Join(dir, "chartup", CacheFilename)
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		CacheFile:               DefaultCacheFile(),
+		Level:                   "major",
+		ScanManifests:           true,
+		CacheTTL:                1 * time.Hour,
+		CacheMaxAge:             30 * 24 * time.Hour,
+		MaxFileSize:             scanner.DefaultMaxFileSize,
+		Concurrency:             checker.DefaultConcurrency,
+		MaxConcurrencyDockerHub: registry.DefaultDockerHubConcurrency,
+		DockerHubMaxPages:       registry.DefaultDockerHubMaxPages,
+		Timeout:                 registry.DefaultTimeout,
+		Retries:                 registry.DefaultRetries,
+	}
+}
+
+// Find returns the path of the config file in dir, or "" if there is none
+func Find(dir string) string {
+	path := filepath.Join(dir, DefaultFilename)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// Load reads a config file on top of the defaults
+// An empty path returns the defaults
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ApplyEnv overrides settings from CHARTUP_* environment variables
+func (c *Config) ApplyEnv() error {
+	if v := os.Getenv("CHARTUP_CACHE_FILE"); v != "" {
+		c.CacheFile = v
+	}
+	if v := os.Getenv("CHARTUP_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		c.CacheTTL = ttl
+	}
+	if v := os.Getenv("CHARTUP_EDITOR"); v != "" {
+		c.Editor = v
+	}
+	return nil
+}
+
+// YAML returns the configuration serialized as YAML
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-config-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configYAML := `cacheFile: /tmp/chartup.json
+cacheTTL: 24h
+scanManifests: false
+`
+	path := filepath.Join(tmpDir, DefaultFilename)
+	if err := os.WriteFile(path, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Find(tmpDir); got != path {
+		t.Errorf("Find() = %q, want %q", got, path)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CacheFile != "/tmp/chartup.json" {
+		t.Errorf("CacheFile = %q, want %q", cfg.CacheFile, "/tmp/chartup.json")
+	}
+	if cfg.CacheTTL != 24*time.Hour {
+		t.Errorf("CacheTTL = %v, want %v", cfg.CacheTTL, 24*time.Hour)
+	}
+	if cfg.ScanManifests {
+		t.Error("ScanManifests = true, want the file to turn it off")
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+
+	if got := Find("/nonexistent"); got != "" {
+		t.Errorf("Find() = %q, want empty", got)
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	wantCache := filepath.Join(cacheHome, "chartup", "cache.json")
+	if runtime.GOOS != "linux" {
+		wantCache = DefaultCacheFile()
+	}
+	if cfg.CacheFile != wantCache || cfg.CacheTTL != time.Hour || !cfg.ScanManifests {
+		t.Errorf("Load(\"\") = %+v, want defaults", cfg)
+	}
+}
+
+func TestLoad_UpstreamsAndSkipImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFilename)
+	configYAML := `upstreams:
+  keycloak: codecentric
+  trino: ""
+skipImages:
+  - internal/
+`
+	if err := os.WriteFile(path, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Default().SkipImages; len(got) != 0 {
+		t.Errorf("default SkipImages = %q, want none", got)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if upstream, ok := cfg.Upstreams["keycloak"]; !ok || upstream != "codecentric" {
+		t.Errorf("Upstreams[keycloak] = %q, want %q", upstream, "codecentric")
+	}
+	if upstream, ok := cfg.Upstreams["trino"]; !ok || upstream != "" {
+		t.Errorf("Upstreams[trino] = %q (set %v), want explicitly empty", upstream, ok)
+	}
+	if len(cfg.SkipImages) != 1 || cfg.SkipImages[0] != "internal/" {
+		t.Errorf("SkipImages = %q, want [internal/]", cfg.SkipImages)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("CHARTUP_CACHE_TTL", "30m")
+	t.Setenv("CHARTUP_EDITOR", "zed")
+
+	cfg := Default()
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+	if cfg.CacheTTL != 30*time.Minute {
+		t.Errorf("CacheTTL = %v, want %v", cfg.CacheTTL, 30*time.Minute)
+	}
+	if cfg.Editor != "zed" {
+		t.Errorf("Editor = %q, want %q", cfg.Editor, "zed")
+	}
+
+	t.Setenv("CHARTUP_CACHE_TTL", "soon")
+	if err := cfg.ApplyEnv(); err == nil {
+		t.Error("expected error for invalid CHARTUP_CACHE_TTL")
+	}
+}
+
+func TestYAML(t *testing.T) {
+	data, err := Default().YAML()
+	if err != nil {
+		t.Fatalf("YAML() error = %v", err)
+	}
+	if !strings.Contains(string(data), "cacheTTL: 1h0m0s") {
+		t.Errorf("YAML() = %q, want cacheTTL as duration string", data)
+	}
+}
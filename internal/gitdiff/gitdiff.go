@@ -0,0 +1,87 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Runner executes a command in dir and returns its standard output
+type Runner func(dir, name string, args ...string) ([]byte, error)
+
+// ExecRunner runs commands with os/exec
+func ExecRunner(dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return out, fmt.Errorf("%s: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+	}
+	return out, err
+}
+
+// ChangedFiles returns absolute paths of files changed between base and the
+// working tree of the git repository containing dir.
+// An empty base uses the merge-base of HEAD with the default branch.
+func ChangedFiles(dir, base string, run Runner) ([]string, error) {
+	out, err := run(dir, "git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not inside a git repository", dir)
+	}
+	top := strings.TrimSpace(string(out))
+	// Match the paths the scanner resolves, e.g. when dir is reached
+	// through a symlink
+	if resolved, err := filepath.EvalSymlinks(top); err == nil {
+		top = resolved
+	}
+
+	if base == "" {
+		base, err = defaultBase(top, run)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out, err = run(top, "git", "diff", "--name-only", base)
+	if err != nil {
+		return nil, fmt.Errorf("git diff against %s failed: %w", base, err)
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		files = append(files, filepath.Join(top, filepath.FromSlash(line)))
+	}
+
+	return files, nil
+}
+
+// defaultBase returns the merge-base of HEAD with the default branch
+func defaultBase(top string, run Runner) (string, error) {
+	branch := ""
+	if out, err := run(top, "git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		branch = strings.TrimSpace(string(out))
+	}
+
+	candidates := []string{"main", "master"}
+	if branch != "" {
+		candidates = append([]string{branch}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		out, err := run(top, "git", "merge-base", "HEAD", candidate)
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+
+	return "", errors.New("could not determine the default branch, pass a base ref explicitly")
+}
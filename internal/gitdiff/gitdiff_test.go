@@ -0,0 +1,103 @@
+package gitdiff
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeGit returns canned output keyed by the joined command line
+func fakeGit(responses map[string]string, calls *[]string) Runner {
+	return func(dir, name string, args ...string) ([]byte, error) {
+		cmd := name + " " + strings.Join(args, " ")
+		*calls = append(*calls, cmd)
+		out, ok := responses[cmd]
+		if !ok {
+			return nil, errors.New("exit status 128")
+		}
+		return []byte(out), nil
+	}
+}
+
+func TestChangedFiles_DefaultBase(t *testing.T) {
+	var calls []string
+	run := fakeGit(map[string]string{
+		"git rev-parse --show-toplevel":                             "/repo\n",
+		"git symbolic-ref --quiet --short refs/remotes/origin/HEAD": "origin/main\n",
+		"git merge-base HEAD origin/main":                           "abc123\n",
+		"git diff --name-only abc123":                               "charts/app/values.yaml\nREADME.md\n\n",
+	}, &calls)
+
+	files, err := ChangedFiles("/repo/charts", "", run)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join("/repo", "charts", "app", "values.yaml"),
+		filepath.Join("/repo", "README.md"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestChangedFiles_ExplicitBase(t *testing.T) {
+	var calls []string
+	run := fakeGit(map[string]string{
+		"git rev-parse --show-toplevel": "/repo\n",
+		"git diff --name-only v1.0.0":   "Chart.yaml\n",
+	}, &calls)
+
+	files, err := ChangedFiles("/repo", "v1.0.0", run)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join("/repo", "Chart.yaml") {
+		t.Errorf("ChangedFiles() = %v", files)
+	}
+	for _, call := range calls {
+		if strings.Contains(call, "merge-base") {
+			t.Errorf("unexpected merge-base lookup with explicit base: %s", call)
+		}
+	}
+}
+
+func TestChangedFiles_NotGit(t *testing.T) {
+	var calls []string
+	run := fakeGit(map[string]string{}, &calls)
+
+	_, err := ChangedFiles("/tmp/plain", "", run)
+	if err == nil || !strings.Contains(err.Error(), "not inside a git repository") {
+		t.Errorf("ChangedFiles() error = %v, want not-a-git-repository error", err)
+	}
+}
+
+func TestChangedFiles_SymlinkedToplevel(t *testing.T) {
+	real := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	var calls []string
+	run := fakeGit(map[string]string{
+		"git rev-parse --show-toplevel": link + "\n",
+		"git diff --name-only v1.0.0":   "Chart.yaml\n",
+	}, &calls)
+
+	files, err := ChangedFiles(link, "v1.0.0", run)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	resolved, err := filepath.EvalSymlinks(real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join(resolved, "Chart.yaml") {
+		t.Errorf("ChangedFiles() = %v, want paths under %s", files, resolved)
+	}
+}
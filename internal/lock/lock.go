@@ -0,0 +1,295 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nogo/chartup/internal/checker"
+	"gopkg.in/yaml.v3"
+)
+
+// Lock records the resolved latest versions of images and charts at a point in time
+type Lock struct {
+	GeneratedAt time.Time `yaml:"generatedAt" json:"generatedAt"`
+	Images      []Entry   `yaml:"images" json:"images"`
+	Charts      []Entry   `yaml:"charts" json:"charts"`
+}
+
+// Entry is a single locked image or chart
+type Entry struct {
+	Name    string `yaml:"name" json:"name"` // registry/repository for images, chart name for charts
+	Path    string `yaml:"path" json:"path"` // File relative to the scan root
+	Current string `yaml:"current" json:"current"`
+	Latest  string `yaml:"latest" json:"latest"`
+}
+
+// key identifies an entry across runs. A file can use the same image or
+// chart more than once, so entries are matched by fullKey first.
+func (e Entry) key() string {
+	return e.Name + "@" + e.Path
+}
+
+// fullKey identifies an entry together with its current version
+func (e Entry) fullKey() string {
+	return e.key() + "@" + e.Current
+}
+
+// less orders entries by name, path, current and latest version
+func (e Entry) less(other Entry) bool {
+	if e.Name != other.Name {
+		return e.Name < other.Name
+	}
+	if e.Path != other.Path {
+		return e.Path < other.Path
+	}
+	if e.Current != other.Current {
+		return e.Current < other.Current
+	}
+	return e.Latest < other.Latest
+}
+
+// FromResults builds a lock from check results
+// Paths are stored relative to root so the lock is portable
+func FromResults(results *checker.Results, root string) *Lock {
+	l := &Lock{
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Images:      make([]Entry, 0, len(results.Images)),
+		Charts:      make([]Entry, 0, len(results.Charts)),
+	}
+
+	for _, img := range results.Images {
+		l.Images = append(l.Images, imageEntry(img, root))
+	}
+	for _, chart := range results.Charts {
+		l.Charts = append(l.Charts, chartEntry(chart, root))
+	}
+
+	sortEntries(l.Images)
+	sortEntries(l.Charts)
+	return l
+}
+
+// Read loads a lock file written as YAML or JSON
+func Read(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, so one parser handles both
+	var l Lock
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Write saves the lock as JSON if path ends in .json, otherwise as YAML
+func (l *Lock) Write(path string) error {
+	var data []byte
+	var err error
+	if strings.HasSuffix(path, ".json") {
+		data, err = json.MarshalIndent(l, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(l)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Change is a difference between a lock and the current findings
+type Change struct {
+	Kind  string // "image" or "chart"
+	Entry Entry  // Current entry, or the locked entry if removed
+	Old   *Entry // Locked entry, nil if added
+	New   *Entry // Current entry, nil if removed
+}
+
+// String describes the change on one line
+func (c Change) String() string {
+	where := fmt.Sprintf("%s %s (%s)", c.Kind, c.Entry.Name, c.Entry.Path)
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s: %s, latest %s", where, c.New.Current, c.New.Latest)
+	case c.New == nil:
+		return fmt.Sprintf("- %s: no longer found", where)
+	}
+
+	var parts []string
+	if c.Old.Current != c.New.Current {
+		parts = append(parts, fmt.Sprintf("current %s -> %s", c.Old.Current, c.New.Current))
+	}
+	if c.Old.Latest != c.New.Latest {
+		parts = append(parts, fmt.Sprintf("latest %s -> %s", c.Old.Latest, c.New.Latest))
+	}
+	return fmt.Sprintf("~ %s: %s", where, strings.Join(parts, ", "))
+}
+
+// Diff compares a lock against current findings and returns the entries that
+// were added, removed, or whose current or latest version changed
+func Diff(locked, current *Lock) []Change {
+	var changes []Change
+	changes = append(changes, diffEntries("image", locked.Images, current.Images)...)
+	changes = append(changes, diffEntries("chart", locked.Charts, current.Charts)...)
+	return changes
+}
+
+func diffEntries(kind string, locked, current []Entry) []Change {
+	old, matched := matchEntries(locked, current)
+
+	var changes []Change
+	for i := range current {
+		cur := &current[i]
+		prev := old[i]
+		if prev == nil {
+			changes = append(changes, Change{Kind: kind, Entry: *cur, New: cur})
+			continue
+		}
+		if prev.Current != cur.Current || prev.Latest != cur.Latest {
+			changes = append(changes, Change{Kind: kind, Entry: *cur, Old: prev, New: cur})
+		}
+	}
+	for i := range locked {
+		if !matched[i] {
+			changes = append(changes, Change{Kind: kind, Entry: locked[i], Old: &locked[i]})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Entry.less(changes[j].Entry)
+	})
+	return changes
+}
+
+// matchEntries pairs each current entry with a locked one: first one with
+// the same name, path and current version, then any left with the same name
+// and path. old[i] is the match of current[i], or nil; matched[j] reports
+// whether locked[j] was paired.
+func matchEntries(locked, current []Entry) (old []*Entry, matched []bool) {
+	old = make([]*Entry, len(current))
+	matched = make([]bool, len(locked))
+
+	for _, key := range []func(Entry) string{Entry.fullKey, Entry.key} {
+		unmatched := make(map[string][]int)
+		for j := range locked {
+			if !matched[j] {
+				k := key(locked[j])
+				unmatched[k] = append(unmatched[k], j)
+			}
+		}
+		for i := range current {
+			if old[i] != nil {
+				continue
+			}
+			k := key(current[i])
+			if candidates := unmatched[k]; len(candidates) > 0 {
+				j := candidates[0]
+				unmatched[k] = candidates[1:]
+				old[i], matched[j] = &locked[j], true
+			}
+		}
+	}
+	return old, matched
+}
+
+// FilterChanged returns only the results that changed since the baseline:
+// items whose latest version differs from the one recorded, and items that
+// have newly fallen behind (were up to date, or not present, in the baseline)
+func FilterChanged(results *checker.Results, baseline *Lock, root string) *checker.Results {
+	imageEntries := make([]Entry, len(results.Images))
+	for i, img := range results.Images {
+		imageEntries[i] = imageEntry(img, root)
+	}
+	chartEntries := make([]Entry, len(results.Charts))
+	for i, chart := range results.Charts {
+		chartEntries[i] = chartEntry(chart, root)
+	}
+	images, _ := matchEntries(baseline.Images, imageEntries)
+	charts, _ := matchEntries(baseline.Charts, chartEntries)
+
+	filtered := &checker.Results{
+		Images:      []checker.ImageResult{},
+		Charts:      []checker.ChartResult{},
+		Warnings:    results.Warnings,
+		Registries:  results.Registries,
+		Unsupported: results.Unsupported,
+	}
+
+	for i, img := range results.Images {
+		if changed(imageEntries[i], img.Status, images[i]) {
+			filtered.Images = append(filtered.Images, img)
+		}
+	}
+	for i, chart := range results.Charts {
+		if changed(chartEntries[i], chart.Status, charts[i]) {
+			filtered.Charts = append(filtered.Charts, chart)
+		}
+	}
+
+	return filtered
+}
+
+// changed reports whether current differs from its baseline entry old, nil
+// if the baseline doesn't have it. An entry whose lookup failed when the
+// baseline was written counts as missing.
+func changed(current Entry, status checker.Status, old *Entry) bool {
+	if old == nil || old.Latest == "" {
+		return status.IsUpdate()
+	}
+
+	if current.Latest != "" && current.Latest != old.Latest {
+		return true
+	}
+
+	// Newly behind: the baseline had no update pending
+	wasBehind := old.Latest != "" && old.Current != old.Latest
+	return status.IsUpdate() && !wasBehind
+}
+
+func imageEntry(img checker.ImageResult, root string) Entry {
+	return Entry{
+		Name:    img.Registry + "/" + img.Repository,
+		Path:    relativePath(img.Path, root),
+		Current: img.Current,
+		Latest:  img.Latest,
+	}
+}
+
+func chartEntry(chart checker.ChartResult, root string) Entry {
+	return Entry{
+		Name:    chart.Name,
+		Path:    relativePath(chart.Path, root),
+		Current: chart.Current,
+		Latest:  chart.Latest,
+	}
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].less(entries[j])
+	})
+}
+
+func relativePath(path, root string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,222 @@
+package lock
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nogo/chartup/internal/checker"
+)
+
+func TestLock_WriteRead(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-lock-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "redis", Current: "7.0", Latest: "7.2", Path: filepath.Join(tmpDir, "b/values.yaml")},
+			{Registry: "docker.io", Repository: "nginx", Current: "1.21", Latest: "1.27", Path: filepath.Join(tmpDir, "a/values.yaml")},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Latest: "15.5.2", Path: filepath.Join(tmpDir, "a/Chart.yaml")},
+		},
+	}
+
+	lockPath := filepath.Join(tmpDir, "chartup.lock")
+	if err := FromResults(results, tmpDir).Write(lockPath); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	l, err := Read(lockPath)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if len(l.Images) != 2 || len(l.Charts) != 1 {
+		t.Fatalf("got %d images and %d charts, want 2 and 1", len(l.Images), len(l.Charts))
+	}
+	// Sorted by name, paths relative to the root
+	if l.Images[0].Name != "docker.io/nginx" || l.Images[0].Path != "a/values.yaml" {
+		t.Errorf("Images[0] = %+v, want docker.io/nginx at a/values.yaml", l.Images[0])
+	}
+	if l.Charts[0].Latest != "15.5.2" {
+		t.Errorf("Charts[0].Latest = %q, want %q", l.Charts[0].Latest, "15.5.2")
+	}
+}
+
+func TestFilterChanged(t *testing.T) {
+	root := "/repo"
+	baseline := &Lock{
+		Images: []Entry{
+			{Name: "docker.io/nginx", Path: "values.yaml", Current: "1.21", Latest: "1.25"},
+			{Name: "docker.io/redis", Path: "values.yaml", Current: "7.0", Latest: "7.2"},
+			{Name: "docker.io/busybox", Path: "values.yaml", Current: "1.36", Latest: "1.36"},
+			// Lookups that failed when the baseline was written
+			{Name: "docker.io/memcached", Path: "values.yaml", Current: "1.6", Latest: ""},
+			{Name: "docker.io/postgres", Path: "values.yaml", Current: "15", Latest: ""},
+		},
+		Charts: []Entry{
+			{Name: "postgresql", Path: "Chart.yaml", Current: "15.0.0", Latest: "15.5.2"},
+		},
+	}
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			// New version appeared since baseline
+			{Registry: "docker.io", Repository: "nginx", Current: "1.21", Latest: "1.27", Status: checker.StatusUpdateAvailable, Path: "/repo/values.yaml"},
+			// Unchanged pending update
+			{Registry: "docker.io", Repository: "redis", Current: "7.0", Latest: "7.2", Status: checker.StatusUpdateAvailable, Path: "/repo/values.yaml"},
+			// Newly fell behind
+			{Registry: "docker.io", Repository: "busybox", Current: "1.36", Latest: "1.37", Status: checker.StatusUpdateAvailable, Path: "/repo/values.yaml"},
+			// Failed in the baseline, up to date now
+			{Registry: "docker.io", Repository: "memcached", Current: "1.6", Latest: "1.6", Status: checker.StatusUpToDate, Path: "/repo/values.yaml"},
+			// Failed in the baseline, behind now
+			{Registry: "docker.io", Repository: "postgres", Current: "15", Latest: "16", Status: checker.StatusMajorUpdateAvailable, Path: "/repo/values.yaml"},
+			// Not in baseline, up to date
+			{Registry: "docker.io", Repository: "alpine", Current: "3.20", Latest: "3.20", Status: checker.StatusUpToDate, Path: "/repo/values.yaml"},
+			// Not in baseline, behind
+			{Registry: "quay.io", Repository: "minio/minio", Current: "1", Latest: "2", Status: checker.StatusUpdateAvailable, Path: "/repo/values.yaml"},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "15.0.0", Latest: "15.5.2", Status: checker.StatusUpdateAvailable, Path: "/repo/Chart.yaml"},
+		},
+	}
+
+	filtered := FilterChanged(results, baseline, root)
+
+	got := map[string]bool{}
+	for _, img := range filtered.Images {
+		got[img.Repository] = true
+	}
+	want := map[string]bool{"nginx": true, "busybox": true, "postgres": true, "minio/minio": true}
+	if len(got) != len(want) {
+		t.Errorf("got images %v, want %v", got, want)
+	}
+	for repo := range want {
+		if !got[repo] {
+			t.Errorf("expected %s in filtered results", repo)
+		}
+	}
+	if len(filtered.Charts) != 0 {
+		t.Errorf("got %d charts, want 0 (unchanged)", len(filtered.Charts))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	locked := &Lock{
+		Images: []Entry{
+			{Name: "docker.io/nginx", Path: "a/values.yaml", Current: "1.21", Latest: "1.25"},
+			{Name: "docker.io/redis", Path: "b/values.yaml", Current: "7.0", Latest: "7.2"},
+			{Name: "docker.io/memcached", Path: "b/values.yaml", Current: "1.6", Latest: "1.6"},
+		},
+		Charts: []Entry{
+			{Name: "postgresql", Path: "a/Chart.yaml", Current: "12.0.0", Latest: "15.5.2"},
+		},
+	}
+	current := &Lock{
+		Images: []Entry{
+			{Name: "docker.io/nginx", Path: "a/values.yaml", Current: "1.21", Latest: "1.27"},
+			{Name: "docker.io/redis", Path: "b/values.yaml", Current: "7.0", Latest: "7.2"},
+			{Name: "quay.io/minio/minio", Path: "c/values.yaml", Current: "RELEASE.1", Latest: "RELEASE.2"},
+		},
+		Charts: []Entry{
+			{Name: "postgresql", Path: "a/Chart.yaml", Current: "15.5.2", Latest: "15.5.2"},
+		},
+	}
+
+	var got []string
+	for _, c := range Diff(locked, current) {
+		got = append(got, c.String())
+	}
+
+	want := []string{
+		"- image docker.io/memcached (b/values.yaml): no longer found",
+		"~ image docker.io/nginx (a/values.yaml): latest 1.25 -> 1.27",
+		"+ image quay.io/minio/minio (c/values.yaml): RELEASE.1, latest RELEASE.2",
+		"~ chart postgresql (a/Chart.yaml): current 12.0.0 -> 15.5.2",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Diff() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if changes := Diff(current, current); len(changes) != 0 {
+		t.Errorf("Diff() of identical locks = %v, want none", changes)
+	}
+}
+
+func TestDiff_DuplicateRepository(t *testing.T) {
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "library/nginx", Current: "1.25", Latest: "1.27", Status: checker.StatusUpdateAvailable, Path: "/repo/values.yaml", Line: 3},
+			{Registry: "docker.io", Repository: "library/nginx", Current: "1.0", Latest: "1.27", Status: checker.StatusMajorUpdateAvailable, Path: "/repo/values.yaml", Line: 9},
+		},
+	}
+	locked := FromResults(results, "/repo")
+
+	// Both occurrences are kept, sorted by current version
+	if len(locked.Images) != 2 || locked.Images[0].Current != "1.0" || locked.Images[1].Current != "1.25" {
+		t.Fatalf("Images = %+v, want 1.0 then 1.25", locked.Images)
+	}
+
+	// Identical runs, in either order, don't differ
+	reversed := &checker.Results{Images: []checker.ImageResult{results.Images[1], results.Images[0]}}
+	if changes := Diff(locked, FromResults(reversed, "/repo")); len(changes) != 0 {
+		t.Errorf("Diff() of identical runs = %v, want none", changes)
+	}
+	if filtered := FilterChanged(results, locked, "/repo"); len(filtered.Images) != 0 {
+		t.Errorf("FilterChanged() of identical runs = %+v, want none", filtered.Images)
+	}
+
+	// Bumping one occurrence changes only that one
+	results.Images[1].Current = "1.26"
+	var got []string
+	for _, c := range Diff(locked, FromResults(results, "/repo")) {
+		got = append(got, c.String())
+	}
+	want := "~ image docker.io/library/nginx (values.yaml): current 1.0 -> 1.26"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Diff() = %q, want [%q]", got, want)
+	}
+}
+
+func TestLock_WriteReadJSON(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-lock-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	l := &Lock{
+		Images: []Entry{{Name: "docker.io/nginx", Path: "values.yaml", Current: "1.21", Latest: "1.27"}},
+	}
+
+	lockPath := filepath.Join(tmpDir, "chartup.lock.json")
+	if err := l.Write(lockPath); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("lock is not JSON:\n%s", data)
+	}
+
+	read, err := Read(lockPath)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(read.Images) != 1 || read.Images[0] != l.Images[0] {
+		t.Errorf("Read() images = %+v, want %+v", read.Images, l.Images)
+	}
+}
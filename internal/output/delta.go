@@ -0,0 +1,76 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/nogo/chartup/internal/checker"
+)
+
+// deltaRow is an image or chart whose latest version differs from the
+// cached one of the previous run
+type deltaRow struct {
+	path     string
+	line     int
+	name     string
+	current  string
+	previous string
+	latest   string
+	change   string
+}
+
+// PrintDelta prints only the images and charts whose latest version changed
+// since the previous cached run. Items without a cached version are left out.
+func PrintDelta(results *checker.Results) {
+	var rows []deltaRow
+	for _, img := range results.Images {
+		if change := deltaChange(img.Previous, img.Latest, img.Status); change != "" {
+			rows = append(rows, deltaRow{img.Path, img.Line, img.Registry + "/" + img.Repository,
+				img.Current, img.Previous, img.Latest, change})
+		}
+	}
+	for _, chart := range results.Charts {
+		if change := deltaChange(chart.Previous, chart.Latest, chart.Status); change != "" {
+			rows = append(rows, deltaRow{chart.Path, chart.Line, chart.Name,
+				chart.Current, chart.Previous, chart.Latest, change})
+		}
+	}
+
+	fmt.Fprintf(out, "CHANGES SINCE LAST RUN - %d changed\n", len(rows))
+	fmt.Fprintln(out, strings.Repeat("═", 80))
+
+	if len(rows) == 0 {
+		fmt.Fprintln(out, "No changes since the last run.")
+		return
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].path != rows[j].path {
+			return rows[i].path < rows[j].path
+		}
+		return rows[i].line < rows[j].line
+	})
+
+	t := table.NewWriter()
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"Location", "Name", "Current", "Was", "Latest", "Change"})
+	for _, r := range rows {
+		t.AppendRow(table.Row{formatLocationLink(r.path, r.line), r.name, r.current, r.previous, r.latest, r.change})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
+
+// deltaChange describes how latest differs from the previously cached
+// version, or returns "" if it doesn't
+func deltaChange(previous, latest string, status checker.Status) string {
+	if previous == "" || latest == "" || previous == latest {
+		return ""
+	}
+	if status == checker.StatusUpToDate {
+		return "now up to date"
+	}
+	return "new version appeared"
+}
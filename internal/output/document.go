@@ -0,0 +1,267 @@
+package output
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/nogo/chartup/internal/checker"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the Document layout. Bump it when fields
+// are renamed or removed; adding fields does not require a bump.
+const SchemaVersion = 1
+
+// Document is the canonical machine-readable form of a run. All structured
+// formats are built from it so they share the same field names.
+type Document struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Summary       *DocumentSummary `json:"summary,omitempty"`
+	Images        []ImageEntry     `json:"images"`
+	Charts        []ChartEntry     `json:"charts"`
+	Warnings      []string         `json:"warnings"`
+	Meta          *DocumentMeta    `json:"meta,omitempty"`
+}
+
+// DocumentMeta holds information about the run itself
+type DocumentMeta struct {
+	Registries []RegistryEntry `json:"registries"`
+}
+
+// RegistryEntry counts the requests made to one registry host
+type RegistryEntry struct {
+	Host        string `json:"host"`
+	Requests    int    `json:"requests"`
+	RateLimited int    `json:"rateLimited"`
+	Failed      int    `json:"failed"`
+}
+
+// DocumentSummary holds the number of images and charts per status
+type DocumentSummary struct {
+	Updates  int `json:"updates"` // Including major updates
+	Major    int `json:"major"`   // Major updates, also counted in updates
+	UpToDate int `json:"upToDate"`
+	Skipped  int `json:"skipped"`
+	Errors   int `json:"errors"`
+	Unknown  int `json:"unknown"`
+	Total    int `json:"total"`
+
+	// UnsupportedRegistries lists image hosts no registry type handles
+	UnsupportedRegistries []string `json:"unsupportedRegistries,omitempty"`
+}
+
+// ImageEntry is an image result in a Document
+type ImageEntry struct {
+	Registry     string `json:"registry"`
+	Repository   string `json:"repository"`
+	Current      string `json:"current"`
+	Digest       string `json:"digest,omitempty"` // Digest current is pinned to
+	Latest       string `json:"latest"`
+	LatestAny    string `json:"latestAny,omitempty"`    // Newer pre-release, if any
+	LatestDigest string `json:"latestDigest,omitempty"` // Manifest digest of latest
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	Path         string `json:"path"`
+	Line         int    `json:"line,omitempty"`
+}
+
+// ChartEntry is a chart dependency result in a Document
+type ChartEntry struct {
+	Name             string `json:"name"`
+	Upstream         string `json:"upstream,omitempty"`
+	DependencyOf     string `json:"dependencyOf,omitempty"` // Chart listing this one as a dependency
+	Current          string `json:"current"`
+	Latest           string `json:"latest"`
+	LatestAny        string `json:"latestAny,omitempty"` // Newer pre-release, if any
+	Status           string `json:"status"`
+	Error            string `json:"error,omitempty"`
+	Path             string `json:"path"`
+	Line             int    `json:"line,omitempty"`
+	AppVersion       string `json:"appVersion,omitempty"`
+	LatestAppVersion string `json:"latestAppVersion,omitempty"` // Latest GitHub release of the chart's source
+}
+
+// isUpdateStatus reports whether a Document status is an available update,
+// major or not
+func isUpdateStatus(status string) bool {
+	return status == checker.StatusUpdateAvailable.String() || status == checker.StatusMajorUpdateAvailable.String()
+}
+
+// NewDocument builds a Document from check results. Entries are sorted by
+// file and line, and paths are relative to the base directory.
+func NewDocument(results *checker.Results) *Document {
+	s := results.Summary()
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		Summary: &DocumentSummary{
+			Updates:  s.Updates,
+			Major:    s.Major,
+			UpToDate: s.UpToDate,
+			Skipped:  s.Skipped,
+			Errors:   s.Errors,
+			Unknown:  s.Unknown,
+			Total:    s.Total(),
+
+			UnsupportedRegistries: results.Unsupported,
+		},
+		Images:   make([]ImageEntry, 0, len(results.Images)),
+		Charts:   make([]ChartEntry, 0, len(results.Charts)),
+		Warnings: append([]string{}, results.Warnings...),
+	}
+
+	if len(results.Registries) > 0 {
+		doc.Meta = &DocumentMeta{Registries: make([]RegistryEntry, 0, len(results.Registries))}
+		for _, r := range results.Registries {
+			doc.Meta.Registries = append(doc.Meta.Registries, RegistryEntry{
+				Host:        r.Host,
+				Requests:    r.Requests,
+				RateLimited: r.RateLimited,
+				Failed:      r.Failed,
+			})
+		}
+	}
+
+	for _, img := range results.Images {
+		doc.Images = append(doc.Images, ImageEntry{
+			Registry:     img.Registry,
+			Repository:   img.Repository,
+			Current:      img.Current,
+			Digest:       img.Digest,
+			Latest:       img.Latest,
+			LatestAny:    newerPreRelease(img.Latest, img.LatestAny),
+			LatestDigest: img.LatestDigest,
+			Status:       img.Status.String(),
+			Error:        img.Error,
+			Path:         relativePath(img.Path),
+			Line:         img.Line,
+		})
+	}
+	sort.SliceStable(doc.Images, func(i, j int) bool {
+		a, b := doc.Images[i], doc.Images[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Repository < b.Repository
+	})
+
+	for _, chart := range results.Charts {
+		doc.Charts = append(doc.Charts, ChartEntry{
+			Name:             chart.Name,
+			Upstream:         chart.Upstream,
+			DependencyOf:     chart.DependencyOf,
+			Current:          chart.Current,
+			Latest:           chart.Latest,
+			LatestAny:        newerPreRelease(chart.Latest, chart.LatestAny),
+			Status:           chart.Status.String(),
+			Error:            chart.Error,
+			Path:             relativePath(chart.Path),
+			Line:             chart.Line,
+			AppVersion:       chart.AppVersion,
+			LatestAppVersion: chart.LatestAppVersion,
+		})
+	}
+	sort.SliceStable(doc.Charts, func(i, j int) bool {
+		a, b := doc.Charts[i], doc.Charts[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Name < b.Name
+	})
+
+	return doc
+}
+
+// newerPreRelease returns latestAny if it differs from latest
+func newerPreRelease(latest, latestAny string) string {
+	if latestAny == latest {
+		return ""
+	}
+	return latestAny
+}
+
+// PrintJSON writes results as a single indented JSON Document
+func PrintJSON(results *checker.Results) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewDocument(results))
+}
+
+// JSON Lines records carry their kind ("image", "chart", or "warning")
+// next to the inlined entry fields
+type imageLine struct {
+	Kind string `json:"kind"`
+	ImageEntry
+}
+
+type chartLine struct {
+	Kind string `json:"kind"`
+	ChartEntry
+}
+
+type warningLine struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// PrintJSONL writes one JSON object per image, chart, and warning. It carries
+// the same entries as PrintJSON without the summary.
+func PrintJSONL(results *checker.Results) error {
+	doc := NewDocument(results)
+	enc := json.NewEncoder(out)
+
+	for _, img := range doc.Images {
+		if err := enc.Encode(imageLine{Kind: "image", ImageEntry: img}); err != nil {
+			return err
+		}
+	}
+	for _, chart := range doc.Charts {
+		if err := enc.Encode(chartLine{Kind: "chart", ChartEntry: chart}); err != nil {
+			return err
+		}
+	}
+	for _, w := range doc.Warnings {
+		if err := enc.Encode(warningLine{Kind: "warning", Message: w}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintYAML writes results as a single YAML Document with the same field
+// names and order as PrintJSON
+func PrintYAML(results *checker.Results) error {
+	data, err := json.Marshal(NewDocument(results))
+	if err != nil {
+		return err
+	}
+
+	// JSON is YAML, so decoding it keeps field order; only the flow style
+	// of the JSON syntax is reset to YAML's block style
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetStyle clears the style of node and its children, so the encoder
+// picks block style and quotes only strings that need it
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
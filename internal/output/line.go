@@ -0,0 +1,85 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nogo/chartup/internal/checker"
+)
+
+// lineItem is an image or chart printed by PrintLines
+type lineItem struct {
+	path    string
+	line    int
+	name    string
+	current string
+	latest  string
+	status  checker.Status
+	err     string
+}
+
+// PrintLines prints one line per update without table borders, e.g.
+// "⚠ charts/app/values.yaml:12 nginx 1.21 → 1.27", sorted by file and line.
+// In verbose mode up-to-date, skipped, and failed items are included too.
+func PrintLines(results *checker.Results) {
+	var items []lineItem
+	for _, img := range results.Images {
+		name := img.Repository
+		if img.Registry != "docker.io" && img.Registry != "" {
+			name = img.Registry + "/" + img.Repository
+		}
+		items = append(items, lineItem{img.Path, img.Line, name, img.Current, img.Latest, img.Status, img.Error})
+	}
+	for _, chart := range results.Charts {
+		items = append(items, lineItem{chart.Path, chart.Line, chart.Name, chart.Current, chart.Latest, chart.Status, chart.Error})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].path != items[j].path {
+			return items[i].path < items[j].path
+		}
+		return items[i].line < items[j].line
+	})
+
+	for _, item := range items {
+		if !item.status.IsUpdate() && !verbose {
+			continue
+		}
+		fmt.Fprintln(out, formatLine(item))
+	}
+}
+
+// formatLine renders one item as "<symbol> <path:line> <name> <version>"
+func formatLine(item lineItem) string {
+	location := relativePath(item.path)
+	if item.line > 0 {
+		location = fmt.Sprintf("%s:%d", location, item.line)
+	}
+
+	var symbol, version string
+	switch item.status {
+	case checker.StatusUpdateAvailable:
+		symbol = colorize(colorYellow, "⚠")
+		version = item.current + " → " + item.latest
+	case checker.StatusMajorUpdateAvailable:
+		symbol = colorize(colorRed, "⬆")
+		version = item.current + " → " + item.latest
+	case checker.StatusUpToDate:
+		symbol = colorize(colorGreen, "✓")
+		version = item.current
+	case checker.StatusSkipped:
+		symbol = colorize(colorGray, "⏭")
+		version = item.current
+	case checker.StatusError:
+		symbol = colorize(colorGray, "✗")
+		version = item.current
+	default:
+		symbol = colorize(colorGray, "?")
+		version = item.current
+	}
+	if item.err != "" {
+		version += " (" + item.err + ")"
+	}
+
+	return fmt.Sprintf("%s %s %s %s", symbol, location, item.name, version)
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nogo/chartup/internal/checker"
+)
+
+// PrintMarkdown prints updates as GitHub-flavored Markdown tables, one
+// section for images and one for charts, e.g. for PR comments. Text is
+// plain, without colors or links. In verbose mode all items are included.
+func PrintMarkdown(results *checker.Results) {
+	doc := NewDocument(results)
+
+	var images, charts [][]string
+	for _, img := range doc.Images {
+		if !isUpdateStatus(img.Status) && !verbose {
+			continue
+		}
+		name := img.Repository
+		if img.Registry != "docker.io" && img.Registry != "" {
+			name = img.Registry + "/" + img.Repository
+		}
+		images = append(images, []string{markdownLocation(img.Path, img.Line), name, img.Current, img.Latest, markdownStatus(img.Status, img.Error)})
+	}
+	for _, chart := range doc.Charts {
+		if !isUpdateStatus(chart.Status) && !verbose {
+			continue
+		}
+		name := chart.Name
+		if chart.DependencyOf != "" {
+			name = chart.DependencyOf + " → " + chart.Name
+		}
+		charts = append(charts, []string{markdownLocation(chart.Path, chart.Line), name, chart.Current, chart.Latest, markdownStatus(chart.Status, chart.Error)})
+	}
+
+	if len(images) == 0 && len(charts) == 0 {
+		fmt.Fprintln(out, "No updates available.")
+		return
+	}
+
+	sections := []struct {
+		heading string
+		rows    [][]string
+	}{
+		{"Docker Images", images},
+		{"Helm Charts", charts},
+	}
+	first := true
+	for _, section := range sections {
+		if len(section.rows) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(out)
+		}
+		first = false
+
+		fmt.Fprintf(out, "## %s\n\n", section.heading)
+		fmt.Fprintln(out, "| Location | Name | Current | Latest | Status |")
+		fmt.Fprintln(out, "|----------|------|---------|--------|--------|")
+		for _, row := range section.rows {
+			for i, cell := range row {
+				row[i] = markdownEscape(cell)
+			}
+			fmt.Fprintf(out, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+}
+
+// markdownLocation formats a relative path as "path:line", or the path
+// alone if the line is unknown
+func markdownLocation(path string, line int) string {
+	if line > 0 {
+		return fmt.Sprintf("%s:%d", path, line)
+	}
+	return path
+}
+
+// markdownStatus returns the status, followed by the error if there is one
+func markdownStatus(status, err string) string {
+	if err != "" {
+		return status + " (" + err + ")"
+	}
+	return status
+}
+
+// markdownEscape keeps a cell from breaking out of its table cell
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
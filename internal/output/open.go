@@ -0,0 +1,94 @@
+package output
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/nogo/chartup/internal/checker"
+)
+
+// CommandRunner starts a command without waiting for it to exit
+type CommandRunner func(name string, args ...string) error
+
+// ExecCommandRunner starts commands with os/exec
+func ExecCommandRunner(name string, args ...string) error {
+	return exec.Command(name, args...).Start()
+}
+
+// editorCommand returns the CLI invocation that opens path at line in the
+// configured editor, or nil if links are disabled
+func editorCommand(path string, line int) ([]string, error) {
+	if line < 1 {
+		line = 1
+	}
+	location := fmt.Sprintf("%s:%d", path, line)
+
+	switch scheme := getEditorScheme(); scheme {
+	case "none":
+		return nil, nil
+	case "cursor":
+		return []string{"cursor", "-g", location}, nil
+	case "idea":
+		return []string{"idea", "--line", strconv.Itoa(line), path}, nil
+	case "sublime":
+		return []string{"subl", location}, nil
+	case "zed":
+		return []string{"zed", location}, nil
+	case "atom":
+		return []string{"atom", location}, nil
+	case "gateway":
+		return nil, fmt.Errorf("editor %q has no local command line launcher", scheme)
+	default:
+		// Default to vscode, like makeEditorLink
+		return []string{"code", "-g", location}, nil
+	}
+}
+
+// OpenFirstUpdate opens the first image or chart with an available update,
+// ordered by file and line, in the configured editor. It returns false if
+// there was no update or the editor is "none".
+func OpenFirstUpdate(results *checker.Results, run CommandRunner) (bool, error) {
+	type location struct {
+		path string
+		line int
+	}
+
+	var updates []location
+	for _, img := range results.Images {
+		if img.Status.IsUpdate() {
+			updates = append(updates, location{img.Path, img.Line})
+		}
+	}
+	for _, chart := range results.Charts {
+		if chart.Status.IsUpdate() {
+			updates = append(updates, location{chart.Path, chart.Line})
+		}
+	}
+	if len(updates) == 0 {
+		return false, nil
+	}
+
+	sort.SliceStable(updates, func(i, j int) bool {
+		if updates[i].path != updates[j].path {
+			return updates[i].path < updates[j].path
+		}
+		return updates[i].line < updates[j].line
+	})
+
+	path, err := filepath.Abs(updates[0].path)
+	if err != nil {
+		return false, err
+	}
+
+	argv, err := editorCommand(path, updates[0].line)
+	if err != nil || argv == nil {
+		return false, err
+	}
+	if err := run(argv[0], argv[1:]...); err != nil {
+		return false, fmt.Errorf("running %s: %w", argv[0], err)
+	}
+	return true, nil
+}
@@ -0,0 +1,145 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/nogo/chartup/internal/checker"
+)
+
+// SARIF 2.1.0 rule ids for outdated items
+const (
+	RuleOutdatedImage = "chartup/outdated-image"
+	RuleOutdatedChart = "chartup/outdated-chart"
+)
+
+// sarifLog is the subset of the SARIF 2.1.0 log format chartup writes
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifRules are the rules of every log, indexed by sarifResult.RuleIndex
+var sarifRules = []sarifRule{
+	{ID: RuleOutdatedImage, ShortDescription: sarifMessage{Text: "A newer container image tag is available"}},
+	{ID: RuleOutdatedChart, ShortDescription: sarifMessage{Text: "A newer Helm chart version is available"}},
+}
+
+// PrintSARIF writes the SARIF log of results to stdout
+func PrintSARIF(results *checker.Results) error {
+	return WriteSARIF(out, results)
+}
+
+// WriteSARIF writes one SARIF 2.1.0 result per image and chart with an
+// update available to w, e.g. for GitHub code scanning. Locations are
+// relative to the scanned directory (%SRCROOT%).
+func WriteSARIF(w io.Writer, results *checker.Results) error {
+	doc := NewDocument(results)
+
+	sarifResults := []sarifResult{}
+	for _, img := range doc.Images {
+		if !isUpdateStatus(img.Status) {
+			continue
+		}
+		name := img.Repository
+		if img.Registry != "docker.io" && img.Registry != "" {
+			name = img.Registry + "/" + img.Repository
+		}
+		sarifResults = append(sarifResults, newSARIFResult(0, name, img.Current, img.Latest, img.Path, img.Line))
+	}
+	for _, chart := range doc.Charts {
+		if !isUpdateStatus(chart.Status) {
+			continue
+		}
+		sarifResults = append(sarifResults, newSARIFResult(1, chart.Name, chart.Current, chart.Latest, chart.Path, chart.Line))
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "chartup",
+				InformationURI: "https://github.com/nogo/chartup",
+				Rules:          sarifRules,
+			}},
+			Results: sarifResults,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// newSARIFResult describes an update of name at path:line under the rule at
+// ruleIndex
+func newSARIFResult(ruleIndex int, name, current, latest, path string, line int) sarifResult {
+	location := sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(path), URIBaseID: "%SRCROOT%"},
+	}
+	if line > 0 {
+		location.Region = &sarifRegion{StartLine: line}
+	}
+
+	return sarifResult{
+		RuleID:    sarifRules[ruleIndex].ID,
+		RuleIndex: ruleIndex,
+		Level:     "warning",
+		Message:   sarifMessage{Text: fmt.Sprintf("%s %s -> %s available", name, current, latest)},
+		Locations: []sarifLocation{{PhysicalLocation: location}},
+	}
+}
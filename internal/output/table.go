@@ -2,16 +2,29 @@ package output
 
 import (
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/nogo/chartup/internal/checker"
+	"github.com/nogo/chartup/internal/registry"
 )
 
+// out is where all output is written
+var out io.Writer = os.Stdout
+
+// errOut is where diagnostics are written
+var errOut io.Writer = os.Stderr
+
+// debugLinks logs the generated editor and registry URLs for each row
+var debugLinks = false
+
 // baseDir is used to make paths relative
 var baseDir string
 
@@ -21,6 +34,14 @@ var editorScheme = ""
 // verbose controls whether to show all items or only updates
 var verbose = false
 
+// colorEnabled controls ANSI colors and OSC 8 hyperlinks
+var colorEnabled = true
+
+// SetOutput sets the writer used for all output
+func SetOutput(w io.Writer) {
+	out = w
+}
+
 // SetBaseDir sets the base directory for relative path display
 func SetBaseDir(dir string) {
 	baseDir = dir
@@ -32,11 +53,21 @@ func SetEditor(editor string) {
 	editorScheme = editor
 }
 
+// SetDebugLinks sets whether generated link URLs are logged to stderr
+func SetDebugLinks(d bool) {
+	debugLinks = d
+}
+
 // SetVerbose sets whether to show all items or only updates
 func SetVerbose(v bool) {
 	verbose = v
 }
 
+// SetColor sets whether output uses ANSI colors and OSC 8 hyperlinks
+func SetColor(enabled bool) {
+	colorEnabled = enabled
+}
+
 // detectEditor tries to determine the editor from environment variables
 func detectEditor() string {
 	// Check VISUAL first (preferred for GUI editors), then EDITOR
@@ -85,12 +116,17 @@ func getEditorScheme() string {
 // PrintTable prints the results as formatted tables using go-pretty
 func PrintTable(results *checker.Results) {
 	printImagesTables(results.Images)
-	fmt.Println()
+	fmt.Fprintln(out)
 	printChartsTables(results.Charts)
-	fmt.Println()
+	fmt.Fprintln(out)
 	printSummary(results)
 }
 
+// PrintCount prints only the number of available updates
+func PrintCount(results *checker.Results) {
+	fmt.Fprintln(out, results.Summary().Updates)
+}
+
 // imagesByFile groups images by their file path
 func imagesByFile(images []checker.ImageResult) map[string][]checker.ImageResult {
 	grouped := make(map[string][]checker.ImageResult)
@@ -119,9 +155,9 @@ func chartsByFile(charts []checker.ChartResult) map[string][]checker.ChartResult
 
 func printImagesTables(images []checker.ImageResult) {
 	if len(images) == 0 {
-		fmt.Println("DOCKER IMAGES")
-		fmt.Println(strings.Repeat("═", 80))
-		fmt.Println("No Docker images found.")
+		fmt.Fprintln(out, "DOCKER IMAGES")
+		fmt.Fprintln(out, strings.Repeat("═", 80))
+		fmt.Fprintln(out, "No Docker images found.")
 		return
 	}
 
@@ -130,7 +166,7 @@ func printImagesTables(images []checker.ImageResult) {
 	if !verbose {
 		filtered = make([]checker.ImageResult, 0)
 		for _, img := range images {
-			if img.Status == checker.StatusUpdateAvailable {
+			if img.Status.IsUpdate() {
 				filtered = append(filtered, img)
 			}
 		}
@@ -139,21 +175,21 @@ func printImagesTables(images []checker.ImageResult) {
 	// Count updates for header
 	updateCount := 0
 	for _, img := range images {
-		if img.Status == checker.StatusUpdateAvailable {
+		if img.Status.IsUpdate() {
 			updateCount++
 		}
 	}
 
 	// Print header with count
 	if verbose {
-		fmt.Printf("DOCKER IMAGES - %d updates of %d total\n", updateCount, len(images))
+		fmt.Fprintf(out, "DOCKER IMAGES - %d updates of %d total\n", updateCount, len(images))
 	} else {
-		fmt.Printf("DOCKER IMAGES - %d updates\n", updateCount)
+		fmt.Fprintf(out, "DOCKER IMAGES - %d updates\n", updateCount)
 	}
-	fmt.Println(strings.Repeat("═", 80))
+	fmt.Fprintln(out, strings.Repeat("═", 80))
 
 	if len(filtered) == 0 {
-		fmt.Println("No updates available.")
+		fmt.Fprintln(out, "No updates available.")
 		return
 	}
 
@@ -167,7 +203,7 @@ func printImagesTables(images []checker.ImageResult) {
 
 	// Create single table
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(out)
 
 	if verbose {
 		t.AppendHeader(table.Row{"Location", "Image", "Current", "Latest", "Status"})
@@ -181,22 +217,45 @@ func printImagesTables(images []checker.ImageResult) {
 			repo = img.Registry + "/" + img.Repository
 		}
 
+		current := img.Current
+		if img.Digest != "" && img.Current != img.Digest {
+			current += colorize(colorGray, " (pinned)")
+		}
+
 		latest := img.Latest
 		if img.Skipped {
 			latest = "-"
 		} else if latest != "" {
 			// Add clickable link to registry
 			latest = formatImageLatestLink(img.Registry, img.Repository, latest)
+			if verbose {
+				latest += formatPreRelease(img.Latest, img.LatestAny)
+			}
+			latest += formatDigest(img.LatestDigest)
+			if verbose {
+				latest += formatCachedAt(img.CachedAt)
+			} else {
+				latest += formatMajor(img.Status)
+			}
 		}
 
 		// Format location as relative/path:line with clickable link
 		location := formatLocationLink(img.Path, img.Line)
 
+		latestURL := ""
+		if !img.Skipped && img.Latest != "" {
+			latestURL = imageTagURL(img.Registry, img.Repository, img.Latest)
+		}
+		logLinks(img.Path, img.Line, repo, latestURL)
+
 		if verbose {
 			status := formatStatus(img.Status)
-			t.AppendRow(table.Row{location, repo, img.Current, latest, status})
+			if img.Container != "" {
+				repo += " (container: " + img.Container + ")"
+			}
+			t.AppendRow(table.Row{location, repo, current, latest, status})
 		} else {
-			t.AppendRow(table.Row{location, repo, img.Current, latest})
+			t.AppendRow(table.Row{location, repo, current, latest})
 		}
 	}
 
@@ -212,9 +271,9 @@ func printImagesTables(images []checker.ImageResult) {
 
 func printChartsTables(charts []checker.ChartResult) {
 	if len(charts) == 0 {
-		fmt.Println("HELM CHARTS")
-		fmt.Println(strings.Repeat("═", 80))
-		fmt.Println("No Helm charts found.")
+		fmt.Fprintln(out, "HELM CHARTS")
+		fmt.Fprintln(out, strings.Repeat("═", 80))
+		fmt.Fprintln(out, "No Helm charts found.")
 		return
 	}
 
@@ -223,7 +282,7 @@ func printChartsTables(charts []checker.ChartResult) {
 	if !verbose {
 		filtered = make([]checker.ChartResult, 0)
 		for _, chart := range charts {
-			if chart.Status == checker.StatusUpdateAvailable {
+			if chart.Status.IsUpdate() || chart.AppBehind() {
 				filtered = append(filtered, chart)
 			}
 		}
@@ -232,21 +291,21 @@ func printChartsTables(charts []checker.ChartResult) {
 	// Count updates for header
 	updateCount := 0
 	for _, chart := range charts {
-		if chart.Status == checker.StatusUpdateAvailable {
+		if chart.Status.IsUpdate() {
 			updateCount++
 		}
 	}
 
 	// Print header with count
 	if verbose {
-		fmt.Printf("HELM CHARTS - %d updates of %d total\n", updateCount, len(charts))
+		fmt.Fprintf(out, "HELM CHARTS - %d updates of %d total\n", updateCount, len(charts))
 	} else {
-		fmt.Printf("HELM CHARTS - %d updates\n", updateCount)
+		fmt.Fprintf(out, "HELM CHARTS - %d updates\n", updateCount)
 	}
-	fmt.Println(strings.Repeat("═", 80))
+	fmt.Fprintln(out, strings.Repeat("═", 80))
 
 	if len(filtered) == 0 {
-		fmt.Println("No updates available.")
+		fmt.Fprintln(out, "No updates available.")
 		return
 	}
 
@@ -260,7 +319,7 @@ func printChartsTables(charts []checker.ChartResult) {
 
 	// Create single table
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(out)
 
 	if verbose {
 		t.AppendHeader(table.Row{"Location", "Chart", "Current", "Latest", "Status"})
@@ -275,16 +334,36 @@ func printChartsTables(charts []checker.ChartResult) {
 		} else if latest != "" {
 			// Add clickable link to ArtifactHub
 			latest = formatChartLatestLink(chart.Name, chart.Upstream, latest)
+			if verbose {
+				latest += formatPreRelease(chart.Latest, chart.LatestAny)
+				latest += formatCachedAt(chart.CachedAt)
+			} else {
+				latest += formatMajor(chart.Status)
+			}
+		}
+		if chart.AppBehind() {
+			latest += formatAppRelease(chart.AppVersion, chart.LatestAppVersion)
 		}
 
 		// Format location as relative/path:line with clickable link
 		location := formatLocationLink(chart.Path, chart.Line)
 
+		latestURL := ""
+		if chart.Status != checker.StatusSkipped && chart.Latest != "" {
+			latestURL = chartVersionURL(chart.Name, chart.Upstream, chart.Latest)
+		}
+		logLinks(chart.Path, chart.Line, chart.Name, latestURL)
+
+		name := chart.Name
+		if chart.DependencyOf != "" {
+			name = chart.DependencyOf + " → " + chart.Name
+		}
+
 		if verbose {
 			status := formatStatus(chart.Status)
-			t.AppendRow(table.Row{location, chart.Name, chart.Current, latest, status})
+			t.AppendRow(table.Row{location, name, chart.Current, latest, status})
 		} else {
-			t.AppendRow(table.Row{location, chart.Name, chart.Current, latest})
+			t.AppendRow(table.Row{location, name, chart.Current, latest})
 		}
 	}
 
@@ -306,10 +385,9 @@ func printFileHeader(path string) {
 	scheme := getEditorScheme()
 	link := makeEditorLink(absPath, 1)
 	if link != "" && scheme != "none" {
-		// OSC 8 hyperlink format: \e]8;;URL\e\\TEXT\e]8;;\e\\
-		fmt.Printf("\033]8;;%s\033\\📄 %s\033]8;;\033\\\n", link, relPath)
+		fmt.Fprintln(out, hyperlink(link, "📄 "+relPath))
 	} else {
-		fmt.Printf("📄 %s\n", relPath)
+		fmt.Fprintf(out, "📄 %s\n", relPath)
 	}
 }
 
@@ -325,7 +403,7 @@ func formatLineLink(path string, line int) string {
 	link := makeEditorLink(path, line)
 	if link != "" && scheme != "none" {
 		// OSC 8 hyperlink format
-		return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", link, lineStr)
+		return hyperlink(link, lineStr)
 	}
 
 	return lineStr
@@ -337,35 +415,51 @@ func formatImageLatestLink(registry, repository, tag string) string {
 		return tag
 	}
 
-	var url string
+	url := imageTagURL(registry, repository, tag)
+	if url == "" {
+		return tag
+	}
+
+	// OSC 8 hyperlink format
+	return hyperlink(url, tag)
+}
+
+// imageTagURL returns the registry web page for an image tag, or "" if the
+// registry has no web UI. Tags and repository path segments are URL-encoded.
+func imageTagURL(imageRegistry, repository, tag string) string {
 	switch {
-	case registry == "docker.io" || registry == "":
+	case imageRegistry == "docker.io" || imageRegistry == "":
 		// Docker Hub
-		if strings.Contains(repository, "/") {
-			url = fmt.Sprintf("https://hub.docker.com/r/%s/tags?name=%s", repository, tag)
-		} else {
-			// Official images
-			url = fmt.Sprintf("https://hub.docker.com/_/%s/tags?name=%s", repository, tag)
+		namespace, name, official := registry.NormalizeDockerRepo(repository)
+		if official {
+			return fmt.Sprintf("https://hub.docker.com/_/%s/tags?name=%s", escapePath(name), url.QueryEscape(tag))
 		}
-	case strings.Contains(registry, "quay.io"):
-		url = fmt.Sprintf("https://quay.io/repository/%s?tab=tags&tag=%s", repository, tag)
-	case strings.Contains(registry, "ghcr.io"):
+		return fmt.Sprintf("https://hub.docker.com/r/%s/%s/tags?name=%s", url.PathEscape(namespace), escapePath(name), url.QueryEscape(tag))
+	case strings.Contains(imageRegistry, "quay.io"):
+		return fmt.Sprintf("https://quay.io/repository/%s?tab=tags&tag=%s", escapePath(repository), url.QueryEscape(tag))
+	case strings.Contains(imageRegistry, "ghcr.io"):
 		// GitHub Container Registry - link to package versions
-		url = fmt.Sprintf("https://github.com/%s/pkgs/container/%s",
-			strings.Split(repository, "/")[0],
-			strings.Split(repository, "/")[len(strings.Split(repository, "/"))-1])
-	case strings.Contains(registry, "gcr.io"):
+		parts := strings.Split(repository, "/")
+		return fmt.Sprintf("https://github.com/%s/pkgs/container/%s",
+			url.PathEscape(parts[0]), url.PathEscape(parts[len(parts)-1]))
+	case strings.Contains(imageRegistry, "gcr.io"):
 		// GCR doesn't have a nice web UI for tags
-		return tag
-	case strings.Contains(registry, "registry.k8s.io"):
+		return ""
+	case strings.Contains(imageRegistry, "registry.k8s.io"):
 		// k8s registry doesn't have a web UI
-		return tag
+		return ""
 	default:
-		return tag
+		return ""
 	}
+}
 
-	// OSC 8 hyperlink format
-	return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", url, tag)
+// escapePath URL-encodes each segment of a slash-separated path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
 }
 
 // formatChartLatestLink creates a clickable link to ArtifactHub for the chart version
@@ -374,18 +468,103 @@ func formatChartLatestLink(name, upstream, version string) string {
 		return version
 	}
 
-	var url string
-	switch upstream {
-	case "bitnami":
-		url = fmt.Sprintf("https://artifacthub.io/packages/helm/bitnami/%s/%s", name, version)
-	case "trinodb":
-		url = fmt.Sprintf("https://artifacthub.io/packages/helm/trino/%s/%s", name, version)
-	default:
+	url := chartVersionURL(name, upstream, version)
+	if url == "" {
 		return version
 	}
 
 	// OSC 8 hyperlink format
-	return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", url, version)
+	return hyperlink(url, version)
+}
+
+// chartVersionURL returns the ArtifactHub page for a chart version, or ""
+// if the chart's upstream isn't known. The upstream is the ArtifactHub
+// repository, except for "trinodb". Name and version are URL-encoded.
+func chartVersionURL(name, upstream, version string) string {
+	switch upstream {
+	case "":
+		return ""
+	case "trinodb":
+		upstream = "trino"
+	}
+	return fmt.Sprintf("https://artifacthub.io/packages/helm/%s/%s/%s",
+		url.PathEscape(upstream), url.PathEscape(name), url.PathEscape(version))
+}
+
+// logLinks writes the URLs generated for a row to stderr when --debug-links is set
+func logLinks(path string, line int, name, latestURL string) {
+	if !debugLinks {
+		return
+	}
+
+	location := relativePath(path)
+	if line > 0 {
+		location = fmt.Sprintf("%s:%d", location, line)
+	}
+
+	editorURL := makeEditorLink(path, line)
+	if editorURL == "" {
+		editorURL = "-"
+	}
+	if latestURL == "" {
+		latestURL = "-"
+	}
+
+	fmt.Fprintf(errOut, "links: %s %s editor=%s latest=%s\n", location, name, editorURL, latestURL)
+}
+
+// formatPreRelease annotates a newer pre-release beyond the latest stable version
+func formatPreRelease(latest, latestAny string) string {
+	if latestAny == "" || latestAny == latest {
+		return ""
+	}
+	return colorize(colorGray, " (pre: "+latestAny+")")
+}
+
+// formatAppRelease notes that a newer release of the chart's application
+// exists than its appVersion
+func formatAppRelease(appVersion, latest string) string {
+	return colorize(colorGray, " (app: "+appVersion+" → "+latest+")")
+}
+
+// formatDigest annotates the latest tag with its full manifest digest, so
+// it can be copied to pin the image
+func formatDigest(digest string) string {
+	if digest == "" {
+		return ""
+	}
+	return colorize(colorGray, " ("+digest+")")
+}
+
+// formatMajor marks a major update where there is no status column
+func formatMajor(status checker.Status) string {
+	if status != checker.StatusMajorUpdateAvailable {
+		return ""
+	}
+	return colorize(colorRed, " ⬆ major")
+}
+
+// formatCachedAt notes how old a latest version served from the cache is,
+// e.g. " (cached 34m ago)"
+func formatCachedAt(checkedAt time.Time) string {
+	if checkedAt.IsZero() {
+		return ""
+	}
+	return colorize(colorGray, " (cached "+formatAge(time.Since(checkedAt))+" ago)")
+}
+
+// formatAge rounds d down to its largest unit, e.g. "34m", "5h", or "3d"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
 }
 
 func formatLocationLink(path string, line int) string {
@@ -404,7 +583,7 @@ func formatLocationLink(path string, line int) string {
 	link := makeEditorLink(path, line)
 	if link != "" && scheme != "none" {
 		// OSC 8 hyperlink format
-		return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", link, location)
+		return hyperlink(link, location)
 	}
 
 	return location
@@ -425,10 +604,14 @@ func makeEditorLink(path string, line int) string {
 		return fmt.Sprintf("vscode://file%s:%d:1", absPath, line)
 	case "idea":
 		// idea://open?file=/path&line=N
-		return fmt.Sprintf("idea://open?file=%s&line=%d", absPath, line)
+		return fmt.Sprintf("idea://open?file=%s&line=%d", url.QueryEscape(absPath), line)
+	case "gateway":
+		// jetbrains://idea/navigate/reference?project=NAME&path=rel/path:N
+		// Resolved by project name, so it also opens files in remote (Gateway) IDEs
+		return makeGatewayLink(absPath, line)
 	case "sublime":
 		// subl://open?url=file:///path&line=N
-		return fmt.Sprintf("subl://open?url=file://%s&line=%d", absPath, line)
+		return fmt.Sprintf("subl://open?url=%s&line=%d", url.QueryEscape("file://"+absPath), line)
 	case "cursor":
 		// cursor://file/path:line:column
 		return fmt.Sprintf("cursor://file%s:%d:1", absPath, line)
@@ -437,7 +620,7 @@ func makeEditorLink(path string, line int) string {
 		return fmt.Sprintf("zed://file%s:%d", absPath, line)
 	case "atom":
 		// atom://open?url=file:///path&line=N
-		return fmt.Sprintf("atom://open?url=file://%s&line=%d", absPath, line)
+		return fmt.Sprintf("atom://open?url=%s&line=%d", url.QueryEscape("file://"+absPath), line)
 	case "none":
 		return ""
 	default:
@@ -446,26 +629,66 @@ func makeEditorLink(path string, line int) string {
 	}
 }
 
+// makeGatewayLink creates a JetBrains Toolbox navigation link. The project is
+// the base directory's name and the path is relative to it, since the local
+// absolute path does not exist on the remote host.
+func makeGatewayLink(absPath string, line int) string {
+	root := filepath.Dir(absPath)
+	if baseDir != "" {
+		if abs, err := filepath.Abs(baseDir); err == nil {
+			root = abs
+		}
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		root, rel = filepath.Dir(absPath), filepath.Base(absPath)
+	}
+	path := fmt.Sprintf("%s:%d", filepath.ToSlash(rel), line)
+	return fmt.Sprintf("jetbrains://idea/navigate/reference?project=%s&path=%s",
+		url.QueryEscape(filepath.Base(root)), url.QueryEscape(path))
+}
+
 // ANSI color codes
 const (
 	colorReset  = "\033[0m"
 	colorGreen  = "\033[32m"
 	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
 	colorGray   = "\033[90m"
 )
 
+// colorize wraps s in an ANSI color, unless colors are disabled
+func colorize(color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// hyperlink makes text a clickable OSC 8 link to url, unless colors are
+// disabled
+func hyperlink(url, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	// OSC 8 hyperlink format: \e]8;;URL\e\\TEXT\e]8;;\e\\
+	return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", url, text)
+}
+
 func formatStatus(status checker.Status) string {
 	switch status {
 	case checker.StatusUpToDate:
-		return colorGreen + "✓ OK" + colorReset
+		return colorize(colorGreen, "✓ OK")
 	case checker.StatusUpdateAvailable:
-		return colorYellow + "⚠ UPDATE" + colorReset
+		return colorize(colorYellow, "⚠ UPDATE")
+	case checker.StatusMajorUpdateAvailable:
+		return colorize(colorRed, "⬆ MAJOR")
 	case checker.StatusSkipped:
-		return colorGray + "⏭ SKIP" + colorReset
+		return colorize(colorGray, "⏭ SKIP")
 	case checker.StatusError:
-		return colorGray + "✗ ERROR" + colorReset
+		return colorize(colorGray, "✗ ERROR")
 	default:
-		return colorGray + "? UNKNOWN" + colorReset
+		return colorize(colorGray, "? UNKNOWN")
 	}
 }
 
@@ -497,53 +720,54 @@ func relativePath(path string) string {
 	return relPath
 }
 
-func printSummary(results *checker.Results) {
-	var updates, upToDate, skipped, errors, unknown int
-
-	for _, img := range results.Images {
-		switch img.Status {
-		case checker.StatusUpdateAvailable:
-			updates++
-		case checker.StatusUpToDate:
-			upToDate++
-		case checker.StatusSkipped:
-			skipped++
-		case checker.StatusError:
-			errors++
-		default:
-			unknown++
-		}
+// printRegistries lists the registries contacted during the run; nothing
+// is printed when every lookup was served from the cache
+func printRegistries(results *checker.Results) {
+	if len(results.Registries) == 0 {
+		return
 	}
 
-	for _, chart := range results.Charts {
-		switch chart.Status {
-		case checker.StatusUpdateAvailable:
-			updates++
-		case checker.StatusUpToDate:
-			upToDate++
-		case checker.StatusSkipped:
-			skipped++
-		case checker.StatusError:
-			errors++
-		default:
-			unknown++
+	t := table.NewWriter()
+	t.SetOutputMirror(out)
+	t.SetTitle("REGISTRIES")
+	t.AppendHeader(table.Row{"Host", "Requests", "Rate limited", "Failed"})
+
+	for _, r := range results.Registries {
+		limited := fmt.Sprintf("%d", r.RateLimited)
+		if r.RateLimited > 0 {
+			limited = colorize(colorYellow, limited)
 		}
+		t.AppendRow(table.Row{r.Host, r.Requests, limited, r.Failed})
 	}
 
-	total := updates + upToDate + skipped + errors + unknown
+	fmt.Fprintln(out)
+	t.SetStyle(table.StyleRounded)
+	t.Style().Title.Align = text.AlignCenter
+	t.Render()
+}
+
+func printSummary(results *checker.Results) {
+	summary := results.Summary()
+	total := summary.Total()
 
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(out)
 	t.SetTitle("SUMMARY")
 
-	t.AppendRow(table.Row{"Updates available", colorYellow + fmt.Sprintf("%d", updates) + colorReset})
-	t.AppendRow(table.Row{"Up to date", colorGreen + fmt.Sprintf("%d", upToDate) + colorReset})
-	t.AppendRow(table.Row{"Skipped", colorGray + fmt.Sprintf("%d", skipped) + colorReset})
-	if errors > 0 {
-		t.AppendRow(table.Row{"Errors", colorGray + fmt.Sprintf("%d", errors) + colorReset})
+	t.AppendRow(table.Row{"Updates available", colorize(colorYellow, fmt.Sprintf("%d", summary.Updates))})
+	if summary.Major > 0 {
+		t.AppendRow(table.Row{"  of which major", colorize(colorRed, fmt.Sprintf("%d", summary.Major))})
 	}
-	if unknown > 0 {
-		t.AppendRow(table.Row{"Unknown", colorGray + fmt.Sprintf("%d", unknown) + colorReset})
+	t.AppendRow(table.Row{"Up to date", colorize(colorGreen, fmt.Sprintf("%d", summary.UpToDate))})
+	t.AppendRow(table.Row{"Skipped", colorize(colorGray, fmt.Sprintf("%d", summary.Skipped))})
+	if summary.Errors > 0 {
+		t.AppendRow(table.Row{"Errors", colorize(colorGray, fmt.Sprintf("%d", summary.Errors))})
+	}
+	if summary.Unknown > 0 {
+		t.AppendRow(table.Row{"Unknown", colorize(colorGray, fmt.Sprintf("%d", summary.Unknown))})
+	}
+	if len(results.Unsupported) > 0 {
+		t.AppendRow(table.Row{"Unsupported registries", colorize(colorGray, strings.Join(results.Unsupported, ", "))})
 	}
 	t.AppendSeparator()
 	t.AppendRow(table.Row{"Total", fmt.Sprintf("%d", total)})
@@ -552,10 +776,12 @@ func printSummary(results *checker.Results) {
 	t.Style().Title.Align = text.AlignCenter
 	t.Render()
 
+	printRegistries(results)
+
 	// Print hint about verbose mode
 	if verbose {
-		fmt.Printf("\n%sHint: Run without --verbose to show only updates%s\n", colorGray, colorReset)
+		fmt.Fprintf(out, "\n%s\n", colorize(colorGray, "Hint: Run without --verbose to show only updates"))
 	} else {
-		fmt.Printf("\n%sHint: Run with --verbose to show all %d items%s\n", colorGray, total, colorReset)
+		fmt.Fprintf(out, "\n%s\n", colorize(colorGray, fmt.Sprintf("Hint: Run with --verbose to show all %d items", total)))
 	}
 }
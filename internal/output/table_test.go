@@ -0,0 +1,931 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nogo/chartup/internal/checker"
+	"github.com/nogo/chartup/internal/registry"
+	"github.com/nogo/chartup/internal/scanner"
+	"gopkg.in/yaml.v3"
+)
+
+// captureOutput runs fn with output redirected and returns what was written
+// updateGolden rewrites golden files with the current output
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetEditor("none")
+	t.Cleanup(func() {
+		SetOutput(os.Stdout)
+		SetEditor("")
+		SetVerbose(false)
+		SetDebugLinks(false)
+		SetColor(true)
+	})
+
+	fn()
+	return buf.String()
+}
+
+func TestPrintImagesTables_RegistryFilterVerbose(t *testing.T) {
+	scan := &scanner.ScanResults{
+		Images: []scanner.ImageInfo{
+			{Registry: "registry.k8s.io", Repository: "ingress-nginx/controller", Tag: "v1.0.0", Path: "a/values.yaml", Line: 3},
+			{Registry: "docker.io", Repository: "nginx", Tag: "1.21", Path: "a/values.yaml", Line: 7},
+			{Registry: "registry.k8s.io", Repository: "pause", Tag: "3.9", Path: "b/values.yaml", Line: 1},
+		},
+		Charts: []scanner.ChartInfo{
+			{Name: "app", Version: "1.0.0", Path: "a/Chart.yaml"},
+		},
+	}
+	scan.FilterRegistries([]string{"registry.k8s.io"})
+
+	results := &checker.Results{}
+	for _, img := range scan.Images {
+		result := checker.ImageResult{
+			Registry:   img.Registry,
+			Repository: img.Repository,
+			Current:    img.Tag,
+			Latest:     img.Tag,
+			Status:     checker.StatusUpToDate,
+			Path:       img.Path,
+			Line:       img.Line,
+		}
+		if img.Repository == "pause" {
+			result.Latest = "3.10"
+			result.Status = checker.StatusUpdateAvailable
+		}
+		results.Images = append(results.Images, result)
+	}
+
+	got := captureOutput(t, func() {
+		SetVerbose(true)
+		printImagesTables(results.Images)
+	})
+
+	if !strings.Contains(got, "DOCKER IMAGES - 1 updates of 2 total") {
+		t.Errorf("expected verbose header counting only filtered images, got:\n%s", got)
+	}
+	for _, want := range []string{"ingress-nginx/controller", "pause"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in verbose output, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "│ nginx") {
+		t.Errorf("expected docker.io image to be filtered out, got:\n%s", got)
+	}
+	if len(scan.Charts) != 0 {
+		t.Errorf("expected charts to be dropped by registry filter, got %d", len(scan.Charts))
+	}
+}
+
+func TestPrintImagesTables_AboveLevel(t *testing.T) {
+	results := &checker.Results{Images: []checker.ImageResult{
+		{Registry: "docker.io", Repository: "redis", Current: "7.0.0", Latest: "7.0.5", Status: checker.StatusUpdateAvailable, Bump: registry.BumpPatch, Path: "values.yaml", Line: 1},
+		{Registry: "docker.io", Repository: "postgres", Current: "15.0.0", Latest: "16.0.0", Status: checker.StatusMajorUpdateAvailable, Bump: registry.BumpMajor, Path: "values.yaml", Line: 5},
+	}}
+	results.ApplyLevel(registry.BumpMinor)
+
+	got := captureOutput(t, func() {
+		SetVerbose(false)
+		printImagesTables(results.Images)
+	})
+	if !strings.Contains(got, "DOCKER IMAGES - 1 updates") || strings.Contains(got, "postgres") {
+		t.Errorf("expected the major update to be left out without --verbose, got:\n%s", got)
+	}
+
+	got = captureOutput(t, func() {
+		SetVerbose(true)
+		printImagesTables(results.Images)
+	})
+	if !strings.Contains(got, "DOCKER IMAGES - 1 updates of 2 total") {
+		t.Errorf("expected verbose header counting one update, got:\n%s", got)
+	}
+	if !strings.Contains(got, "postgres") || !strings.Contains(got, "16.0.0") || !strings.Contains(got, "OK") {
+		t.Errorf("expected the major update listed as OK with 16.0.0 in verbose output, got:\n%s", got)
+	}
+}
+
+func TestPrintCount(t *testing.T) {
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Repository: "nginx", Status: checker.StatusUpdateAvailable},
+			{Repository: "redis", Status: checker.StatusUpToDate},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Status: checker.StatusUpdateAvailable},
+			{Name: "app", Status: checker.StatusSkipped},
+		},
+	}
+
+	got := captureOutput(t, func() {
+		PrintCount(results)
+	})
+
+	if got != "2\n" {
+		t.Errorf("PrintCount() output = %q, want %q", got, "2\n")
+	}
+}
+
+func TestPrintSummary_Unsupported(t *testing.T) {
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "harbor.internal", Repository: "team/api", Status: checker.StatusError, Error: "unsupported registry: harbor.internal"},
+		},
+		Unsupported: []string{"harbor.internal"},
+	}
+
+	got := captureOutput(t, func() {
+		printSummary(results)
+	})
+
+	if !strings.Contains(got, "Unsupported registries") || !strings.Contains(got, "harbor.internal") {
+		t.Errorf("summary does not list the unsupported registry:\n%s", got)
+	}
+
+	got = captureOutput(t, func() {
+		printSummary(&checker.Results{})
+	})
+	if strings.Contains(got, "Unsupported registries") {
+		t.Errorf("summary lists unsupported registries when there are none:\n%s", got)
+	}
+}
+
+func TestPrintSummary_Major(t *testing.T) {
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Repository: "nginx", Current: "1.25.0", Latest: "2.0.0", Status: checker.StatusMajorUpdateAvailable},
+			{Repository: "redis", Current: "7.2.0", Latest: "7.4.0", Status: checker.StatusUpdateAvailable},
+		},
+	}
+
+	got := captureOutput(t, func() {
+		printSummary(results)
+	})
+	if !strings.Contains(got, "Updates available │ "+colorYellow+"2") {
+		t.Errorf("summary does not count both updates:\n%s", got)
+	}
+	if !strings.Contains(got, "of which major") || !strings.Contains(got, colorRed+"1") {
+		t.Errorf("summary does not count the major update separately:\n%s", got)
+	}
+
+	// Majors are shown and marked without --verbose
+	got = captureOutput(t, func() {
+		printImagesTables(results.Images)
+	})
+	if !strings.Contains(got, "nginx") || strings.Count(got, "⬆ major") != 1 {
+		t.Errorf("images table does not mark the major update:\n%s", got)
+	}
+	if !strings.Contains(got, "2 updates") {
+		t.Errorf("images table header does not count the major update:\n%s", got)
+	}
+
+	got = captureOutput(t, func() {
+		SetVerbose(true)
+		printImagesTables(results.Images)
+	})
+	if !strings.Contains(got, colorRed+"⬆ MAJOR"+colorReset) {
+		t.Errorf("verbose images table does not show the MAJOR status:\n%s", got)
+	}
+}
+
+func TestPrintChartsTables_DependencyOf(t *testing.T) {
+	t.Cleanup(func() { SetEditor("") })
+	SetEditor("none")
+
+	charts := []checker.ChartResult{
+		{Name: "postgresql", DependencyOf: "mychart", Current: "12.0.0", Latest: "13.0.0", Status: checker.StatusUpdateAvailable, Path: "Chart.yaml"},
+	}
+
+	got := captureOutput(t, func() {
+		printChartsTables(charts)
+	})
+
+	if !strings.Contains(got, "mychart → postgresql") {
+		t.Errorf("expected dependency path in output:\n%s", got)
+	}
+}
+
+func TestDebugLinks(t *testing.T) {
+	var debug bytes.Buffer
+	errOut = &debug
+	SetBaseDir("/repo")
+	t.Cleanup(func() {
+		errOut = os.Stderr
+		SetBaseDir("")
+	})
+
+	images := []checker.ImageResult{
+		{Registry: "docker.io", Repository: "bitnami/redis", Current: "7.0", Latest: "7.2", Status: checker.StatusUpdateAvailable, Path: "/repo/app/values.yaml", Line: 12},
+	}
+	charts := []checker.ChartResult{
+		{Name: "postgresql", Upstream: "bitnami", Current: "12.0.0", Latest: "15.5.2", Status: checker.StatusUpdateAvailable, Path: "/repo/app/Chart.yaml"},
+	}
+
+	captureOutput(t, func() {
+		SetEditor("vscode")
+		SetDebugLinks(true)
+		printImagesTables(images)
+		printChartsTables(charts)
+	})
+
+	got := debug.String()
+	wantLines := []string{
+		"links: app/values.yaml:12 bitnami/redis editor=vscode://file/repo/app/values.yaml:12:1 latest=https://hub.docker.com/r/bitnami/redis/tags?name=7.2",
+		"links: app/Chart.yaml postgresql editor=vscode://file/repo/app/Chart.yaml:0:1 latest=https://artifacthub.io/packages/helm/bitnami/postgresql/15.5.2",
+	}
+	for _, want := range wantLines {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing debug line %q in:\n%s", want, got)
+		}
+	}
+
+	// Nothing is logged unless enabled
+	debug.Reset()
+	captureOutput(t, func() {
+		SetDebugLinks(false)
+		printImagesTables(images)
+	})
+	if debug.Len() != 0 {
+		t.Errorf("expected no debug output when disabled, got %q", debug.String())
+	}
+}
+
+func TestImageTagURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		registry   string
+		repository string
+		tag        string
+		want       string
+	}{
+		{
+			name:       "official image",
+			registry:   "docker.io",
+			repository: "nginx",
+			tag:        "1.27",
+			want:       "https://hub.docker.com/_/nginx/tags?name=1.27",
+		},
+		{
+			name:       "official image with library namespace",
+			registry:   "docker.io",
+			repository: "library/nginx",
+			tag:        "1.27",
+			want:       "https://hub.docker.com/_/nginx/tags?name=1.27",
+		},
+		{
+			name:       "docker hub org image",
+			registry:   "docker.io",
+			repository: "bitnami/postgresql",
+			tag:        "16.1.0",
+			want:       "https://hub.docker.com/r/bitnami/postgresql/tags?name=16.1.0",
+		},
+		{
+			name:       "tag with plus",
+			registry:   "docker.io",
+			repository: "org/app",
+			tag:        "1.2.3+build.4",
+			want:       "https://hub.docker.com/r/org/app/tags?name=1.2.3%2Bbuild.4",
+		},
+		{
+			name:       "quay tag with tilde and space",
+			registry:   "quay.io",
+			repository: "org/app",
+			tag:        "2024.01~rc 1",
+			want:       "https://quay.io/repository/org/app?tab=tags&tag=2024.01~rc+1",
+		},
+		{
+			name:       "ghcr nested repository",
+			registry:   "ghcr.io",
+			repository: "org/team/app",
+			tag:        "v1",
+			want:       "https://github.com/org/pkgs/container/app",
+		},
+		{
+			name:       "no web ui",
+			registry:   "registry.k8s.io",
+			repository: "pause",
+			tag:        "3.9",
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imageTagURL(tt.registry, tt.repository, tt.tag); got != tt.want {
+				t.Errorf("imageTagURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetColor(t *testing.T) {
+	t.Cleanup(func() {
+		SetEditor("")
+		SetColor(true)
+	})
+	SetEditor("vscode")
+
+	if got := formatStatus(checker.StatusUpdateAvailable); !strings.Contains(got, "\033[") {
+		t.Errorf("formatStatus() = %q, want ANSI colors by default", got)
+	}
+	if got := formatLocationLink("/repo/values.yaml", 3); !strings.Contains(got, "\033]8;;") {
+		t.Errorf("formatLocationLink() = %q, want an OSC 8 link by default", got)
+	}
+
+	SetColor(false)
+	if got := formatStatus(checker.StatusUpdateAvailable); got != "⚠ UPDATE" {
+		t.Errorf("formatStatus() = %q, want %q", got, "⚠ UPDATE")
+	}
+	if got := formatLocationLink("/repo/values.yaml", 3); got != "/repo/values.yaml:3" {
+		t.Errorf("formatLocationLink() = %q, want %q", got, "/repo/values.yaml:3")
+	}
+	if got := formatLine(lineItem{status: checker.StatusMajorUpdateAvailable, path: "/repo/values.yaml", line: 3, name: "nginx", current: "1.0", latest: "2.0"}); strings.Contains(got, "\033") {
+		t.Errorf("formatLine() = %q, want no escape sequences", got)
+	}
+}
+
+func TestChartVersionURL(t *testing.T) {
+	got := chartVersionURL("postgresql", "bitnami", "1.0.0+a/b")
+	want := "https://artifacthub.io/packages/helm/bitnami/postgresql/1.0.0+a%2Fb"
+	if got != want {
+		t.Errorf("chartVersionURL() = %q, want %q", got, want)
+	}
+
+	if got := chartVersionURL("app", "", "1.0.0"); got != "" {
+		t.Errorf("chartVersionURL() for unknown upstream = %q, want empty", got)
+	}
+
+	got = chartVersionURL("trino", "trinodb", "1.0.0")
+	if want := "https://artifacthub.io/packages/helm/trino/trino/1.0.0"; got != want {
+		t.Errorf("chartVersionURL() = %q, want %q", got, want)
+	}
+	got = chartVersionURL("kube-prometheus-stack", "prometheus-community", "1.0.0")
+	if want := "https://artifacthub.io/packages/helm/prometheus-community/kube-prometheus-stack/1.0.0"; got != want {
+		t.Errorf("chartVersionURL() = %q, want %q", got, want)
+	}
+}
+
+func TestMakeEditorLink(t *testing.T) {
+	SetBaseDir("/home/me/my charts")
+	t.Cleanup(func() {
+		SetBaseDir("")
+		SetEditor("")
+	})
+
+	tests := []struct {
+		editor string
+		want   string
+	}{
+		{"vscode", "vscode://file/home/me/my charts/app/values.yaml:7:1"},
+		{"idea", "idea://open?file=%2Fhome%2Fme%2Fmy+charts%2Fapp%2Fvalues.yaml&line=7"},
+		{"sublime", "subl://open?url=file%3A%2F%2F%2Fhome%2Fme%2Fmy+charts%2Fapp%2Fvalues.yaml&line=7"},
+		{"gateway", "jetbrains://idea/navigate/reference?project=my+charts&path=app%2Fvalues.yaml%3A7"},
+		{"none", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			SetEditor(tt.editor)
+			if got := makeEditorLink("app/values.yaml", 7); got != tt.want {
+				t.Errorf("makeEditorLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintImagesTables_PreRelease(t *testing.T) {
+	images := []checker.ImageResult{
+		{Registry: "docker.io", Repository: "org/app", Current: "1.3.0", Latest: "1.4.0", LatestStable: "1.4.0", LatestAny: "1.5.0-rc1", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 1},
+	}
+
+	got := captureOutput(t, func() {
+		SetVerbose(true)
+		printImagesTables(images)
+	})
+	if !strings.Contains(got, "(pre: 1.5.0-rc1)") {
+		t.Errorf("expected pre-release annotation in verbose output, got:\n%s", got)
+	}
+
+	got = captureOutput(t, func() {
+		SetVerbose(false)
+		printImagesTables(images)
+	})
+	if strings.Contains(got, "1.5.0-rc1") {
+		t.Errorf("expected no pre-release annotation in default output, got:\n%s", got)
+	}
+}
+
+func TestPrintImagesTables_Digest(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	images := []checker.ImageResult{
+		{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Latest: "1.26.0", LatestDigest: digest, Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 1},
+		{Registry: "docker.io", Repository: "redis", Current: "7.0", Latest: "7.2", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 2},
+	}
+
+	got := captureOutput(t, func() {
+		printImagesTables(images)
+	})
+	if !strings.Contains(got, colorGray+" ("+digest+")") {
+		t.Errorf("expected full digest next to the latest tag, got:\n%s", got)
+	}
+	if strings.Count(got, "sha256:") != 1 {
+		t.Errorf("expected a digest only for the image that has one, got:\n%s", got)
+	}
+}
+
+func TestPrintImagesTables_PinnedCurrent(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	images := []checker.ImageResult{
+		{Registry: "ghcr.io", Repository: "org/app", Current: "v1.2.3", Digest: digest, Latest: "v1.3.0", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 1},
+		{Registry: "docker.io", Repository: "nginx", Current: digest, Digest: digest, Status: checker.StatusSkipped, Skipped: true, Error: "pinned by digest", Path: "values.yaml", Line: 2},
+	}
+
+	got := captureOutput(t, func() {
+		printImagesTables(images)
+	})
+	if !strings.Contains(got, "v1.2.3"+colorGray+" (pinned)") {
+		t.Errorf("expected the tag noted as pinned, got:\n%s", got)
+	}
+	if strings.Count(got, "(pinned)") != 1 {
+		t.Errorf("expected only the tagged image noted as pinned, got:\n%s", got)
+	}
+}
+
+func TestPrintImagesTables_CachedAt(t *testing.T) {
+	images := []checker.ImageResult{
+		{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Latest: "1.26.0", CachedAt: time.Now().Add(-34 * time.Minute), Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 1},
+		{Registry: "docker.io", Repository: "redis", Current: "7.0", Latest: "7.2", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 2},
+	}
+
+	got := captureOutput(t, func() {
+		SetVerbose(true)
+		printImagesTables(images)
+	})
+	if !strings.Contains(got, "(cached 34m ago)") {
+		t.Errorf("expected the age of the cached lookup, got:\n%s", got)
+	}
+	if strings.Count(got, "cached") != 1 {
+		t.Errorf("expected only the cached image annotated, got:\n%s", got)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "<1m"},
+		{34*time.Minute + 50*time.Second, "34m"},
+		{5*time.Hour + 59*time.Minute, "5h"},
+		{72 * time.Hour, "3d"},
+	}
+	for _, tt := range tests {
+		if got := formatAge(tt.d); got != tt.want {
+			t.Errorf("formatAge(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	SetBaseDir("/charts")
+	t.Cleanup(func() { SetBaseDir("") })
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "redis", Current: "7.0", Latest: "7.2", LatestDigest: "sha256:abc", Status: checker.StatusUpdateAvailable, Path: "/charts/b/values.yaml", Line: 3},
+			{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Latest: "1.25.0", LatestAny: "1.26.0-rc1", Status: checker.StatusUpToDate, Path: "/charts/a/values.yaml", Line: 9},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Upstream: "bitnami", Current: "12.0.0", Latest: "13.0.0", Status: checker.StatusUpdateAvailable, Path: "/charts/a/Chart.yaml", Line: 7},
+		},
+		Warnings:   []string{"rate limit hit; remaining lookups were skipped"},
+		Registries: []registry.RegistryStats{{Host: "hub.docker.com", Requests: 3, RateLimited: 1}},
+	}
+
+	got := captureOutput(t, func() {
+		if err := PrintJSON(results); err != nil {
+			t.Fatalf("PrintJSON() error = %v", err)
+		}
+	})
+
+	var doc Document
+	if err := json.Unmarshal([]byte(got), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, got)
+	}
+	if doc.SchemaVersion != SchemaVersion {
+		t.Errorf("schemaVersion = %d, want %d", doc.SchemaVersion, SchemaVersion)
+	}
+	if doc.Summary == nil || doc.Summary.Updates != 2 || doc.Summary.Total != 3 {
+		t.Errorf("summary = %+v, want 2 updates of 3", doc.Summary)
+	}
+	if len(doc.Images) != 2 || doc.Images[0].Repository != "nginx" || doc.Images[0].Path != "a/values.yaml" {
+		t.Errorf("images not sorted by path: %+v", doc.Images)
+	}
+	if doc.Images[0].LatestAny != "1.26.0-rc1" || doc.Images[1].LatestAny != "" {
+		t.Errorf("latestAny should only be set for newer pre-releases: %+v", doc.Images)
+	}
+	if doc.Images[1].LatestDigest != "sha256:abc" {
+		t.Errorf("latestDigest = %q, want %q", doc.Images[1].LatestDigest, "sha256:abc")
+	}
+	if len(doc.Charts) != 1 || doc.Charts[0].Status != "UPDATE" {
+		t.Errorf("charts = %+v", doc.Charts)
+	}
+	if len(doc.Warnings) != 1 {
+		t.Errorf("warnings = %v, want 1", doc.Warnings)
+	}
+	if doc.Meta == nil || len(doc.Meta.Registries) != 1 || doc.Meta.Registries[0].RateLimited != 1 {
+		t.Errorf("meta = %+v, want hub.docker.com with 1 rate-limited request", doc.Meta)
+	}
+}
+
+func TestPrintYAML(t *testing.T) {
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "redis", Current: "7.0", Latest: "7.2", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 3},
+			{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Status: checker.StatusError, Error: "rate limit exceeded", Path: "values.yaml", Line: 9},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Latest: "12.0.0", Status: checker.StatusUpToDate, Path: "Chart.yaml", Line: 7},
+		},
+	}
+
+	got := captureOutput(t, func() {
+		if err := PrintYAML(results); err != nil {
+			t.Fatalf("PrintYAML() error = %v", err)
+		}
+	})
+
+	var doc struct {
+		SchemaVersion int `yaml:"schemaVersion"`
+		Summary       struct {
+			Updates int `yaml:"updates"`
+			Errors  int `yaml:"errors"`
+			Total   int `yaml:"total"`
+		} `yaml:"summary"`
+		Images []struct {
+			Current string `yaml:"current"`
+			Latest  string `yaml:"latest"`
+			Status  string `yaml:"status"`
+			Error   string `yaml:"error"`
+			Line    int    `yaml:"line"`
+		} `yaml:"images"`
+		Charts []struct {
+			Name string `yaml:"name"`
+		} `yaml:"charts"`
+	}
+	if err := yaml.Unmarshal([]byte(got), &doc); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, got)
+	}
+	if doc.SchemaVersion != SchemaVersion || doc.Summary.Updates != 1 || doc.Summary.Errors != 1 || doc.Summary.Total != 3 {
+		t.Errorf("document header = %+v, want 1 update and 1 error of 3", doc)
+	}
+	if len(doc.Images) != 2 || len(doc.Charts) != 1 {
+		t.Fatalf("got %d images and %d charts, want 2 and 1:\n%s", len(doc.Images), len(doc.Charts), got)
+	}
+	// Versions that look like numbers must stay strings
+	if doc.Images[0].Current != "7.0" || doc.Images[0].Latest != "7.2" || doc.Images[0].Line != 3 {
+		t.Errorf("image = %+v, want 7.0 -> 7.2 at line 3", doc.Images[0])
+	}
+	if doc.Images[1].Status != "ERROR" || doc.Images[1].Error != "rate limit exceeded" {
+		t.Errorf("image = %+v, want error status with message", doc.Images[1])
+	}
+	if strings.Contains(got, "\033") || strings.Contains(got, "{") {
+		t.Errorf("output contains escape codes or flow style:\n%s", got)
+	}
+}
+
+func TestPrintJSONL(t *testing.T) {
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Latest: "1.26.0", Status: checker.StatusUpdateAvailable, Path: "values.yaml"},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Latest: "12.0.0", Status: checker.StatusUpToDate, Path: "Chart.yaml"},
+		},
+		Warnings: []string{"partial results"},
+	}
+
+	got := captureOutput(t, func() {
+		if err := PrintJSONL(results); err != nil {
+			t.Fatalf("PrintJSONL() error = %v", err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	want := []string{
+		`{"kind":"image","registry":"docker.io","repository":"nginx","current":"1.25.0","latest":"1.26.0","status":"UPDATE","path":"values.yaml"}`,
+		`{"kind":"chart","name":"postgresql","current":"12.0.0","latest":"12.0.0","status":"OK","path":"Chart.yaml"}`,
+		`{"kind":"warning","message":"partial results"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), got)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %s, want %s", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestPrintSARIF(t *testing.T) {
+	SetBaseDir("/charts")
+	t.Cleanup(func() { SetBaseDir("") })
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "nginx", Current: "1.21", Latest: "1.27", Status: checker.StatusUpdateAvailable, Path: "/charts/app/values.yaml", Line: 12},
+			{Registry: "ghcr.io", Repository: "org/app", Current: "1.0.0", Latest: "1.1.0", Status: checker.StatusUpdateAvailable, Path: "/charts/app/values.yaml"},
+			{Registry: "docker.io", Repository: "redis", Current: "7.2", Latest: "7.2", Status: checker.StatusUpToDate, Path: "/charts/app/values.yaml", Line: 20},
+			{Registry: "docker.io", Repository: "busybox", Current: "latest", Status: checker.StatusSkipped, Path: "/charts/app/values.yaml", Line: 30},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Latest: "13.0.0", Status: checker.StatusUpdateAvailable, Path: "/charts/app/Chart.yaml", Line: 7},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, results); err != nil {
+		t.Fatalf("WriteSARIF() error = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("version %q with %d runs, want 2.1.0 with 1 run", log.Version, len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Results) != 3 {
+		t.Fatalf("got %d results, want one per update: %+v", len(run.Results), run.Results)
+	}
+
+	// Sorted by file and line, so the image without a line comes first
+	nginx := run.Results[1]
+	if nginx.RuleID != RuleOutdatedImage || nginx.Message.Text != "nginx 1.21 -> 1.27 available" {
+		t.Errorf("nginx result = %+v", nginx)
+	}
+	location := nginx.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "app/values.yaml" || location.Region == nil || location.Region.StartLine != 12 {
+		t.Errorf("nginx location = %+v, want app/values.yaml line 12", location)
+	}
+
+	if app := run.Results[0]; app.Message.Text != "ghcr.io/org/app 1.0.0 -> 1.1.0 available" || app.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("result without line = %+v, want no region", app)
+	}
+
+	chart := run.Results[2]
+	if chart.RuleID != RuleOutdatedChart || run.Tool.Driver.Rules[chart.RuleIndex].ID != RuleOutdatedChart {
+		t.Errorf("chart result = %+v, want rule %s", chart, RuleOutdatedChart)
+	}
+}
+
+func TestPrintMarkdown(t *testing.T) {
+	SetBaseDir("/charts")
+	t.Cleanup(func() { SetBaseDir("") })
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "nginx", Current: "1.21", Latest: "1.27", Status: checker.StatusUpdateAvailable, Path: "/charts/app/values.yaml", Line: 12},
+			{Registry: "ghcr.io", Repository: "org/app", Current: "1.0.0", Latest: "1.1.0", Status: checker.StatusUpdateAvailable, Path: "/charts/app/values.yaml"},
+			{Registry: "docker.io", Repository: "redis", Current: "7.2", Latest: "7.2", Status: checker.StatusUpToDate, Path: "/charts/app/values.yaml", Line: 20},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", DependencyOf: "app", Current: "12.0.0", Latest: "13.0.0", Status: checker.StatusUpdateAvailable, Path: "/charts/app/Chart.yaml", Line: 7},
+		},
+	}
+
+	got := captureOutput(t, func() {
+		PrintMarkdown(results)
+	})
+	golden := filepath.Join("testdata", "markdown.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("markdown output differs from %s (run with -update to refresh):\n%s", golden, got)
+	}
+	if strings.Contains(got, "\x1b") {
+		t.Errorf("markdown output contains escape sequences:\n%q", got)
+	}
+
+	got = captureOutput(t, func() {
+		PrintMarkdown(&checker.Results{Images: results.Images[2:]})
+	})
+	if got != "No updates available.\n" {
+		t.Errorf("output without updates = %q, want only the no updates line", got)
+	}
+}
+
+func TestOpenFirstUpdate(t *testing.T) {
+	t.Cleanup(func() { SetEditor("") })
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Repository: "redis", Status: checker.StatusUpdateAvailable, Path: "/charts/b/values.yaml", Line: 4},
+			{Repository: "nginx", Status: checker.StatusUpToDate, Path: "/charts/a/values.yaml", Line: 2},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Status: checker.StatusUpdateAvailable, Path: "/charts/a/Chart.yaml", Line: 9},
+		},
+	}
+
+	tests := []struct {
+		editor string
+		want   []string
+	}{
+		{"vscode", []string{"code", "-g", "/charts/a/Chart.yaml:9"}},
+		{"cursor", []string{"cursor", "-g", "/charts/a/Chart.yaml:9"}},
+		{"idea", []string{"idea", "--line", "9", "/charts/a/Chart.yaml"}},
+		{"sublime", []string{"subl", "/charts/a/Chart.yaml:9"}},
+		{"zed", []string{"zed", "/charts/a/Chart.yaml:9"}},
+		{"none", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			SetEditor(tt.editor)
+
+			var got []string
+			opened, err := OpenFirstUpdate(results, func(name string, args ...string) error {
+				got = append([]string{name}, args...)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("OpenFirstUpdate() error = %v", err)
+			}
+			if opened != (tt.want != nil) {
+				t.Errorf("opened = %v, want %v", opened, tt.want != nil)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("argv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	SetEditor("vscode")
+	opened, err := OpenFirstUpdate(&checker.Results{}, func(string, ...string) error {
+		t.Error("runner called without updates")
+		return nil
+	})
+	if opened || err != nil {
+		t.Errorf("OpenFirstUpdate() with no updates = %v, %v", opened, err)
+	}
+}
+
+func TestPrintTriage(t *testing.T) {
+	t.Cleanup(func() { SetEditor("") })
+	SetEditor("none")
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Latest: "1.27.0", Bump: registry.BumpMinor, Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 3},
+			{Registry: "docker.io", Repository: "redis", Current: "7.2.0", Latest: "7.2.0", Status: checker.StatusUpToDate, Path: "values.yaml", Line: 8},
+			{Registry: "docker.io", Repository: "busybox", Current: "1.36.0", Latest: "1.36.1", Bump: registry.BumpPatch, Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 12},
+			{Registry: "harbor.internal", Repository: "team/api", Current: "1.0.0", Status: checker.StatusError, Error: "unsupported registry", Path: "values.yaml", Line: 15},
+			{Registry: "docker.io", Repository: "alpine", Current: "3.19", Status: checker.StatusSkipped, Path: "values.yaml", Line: 20},
+			{Registry: "ghcr.io", Repository: "org/nightly", Current: "latest", Latest: "20240101", Bump: registry.BumpUnknown, Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 25},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Latest: "13.0.0", Bump: registry.BumpMajor, Status: checker.StatusMajorUpdateAvailable, Path: "Chart.yaml", Line: 5},
+			{Name: "minio", Current: "5.0.0", Status: checker.StatusError, Error: "rate limit hit", Path: "Chart.yaml", Line: 9},
+		},
+	}
+
+	got := captureOutput(t, func() { PrintTriage(results) })
+
+	// Sections appear in severity order, each with its members
+	sections := []struct {
+		header  string
+		members []string
+	}{
+		{"ERRORS - 2", []string{"minio", "harbor.internal/team/api"}},
+		{"MAJOR UPDATES - 1", []string{"postgresql"}},
+		{"MINOR UPDATES - 1", []string{"docker.io/nginx"}},
+		{"PATCH UPDATES - 1", []string{"docker.io/busybox"}},
+		{"OTHER UPDATES - 1", []string{"ghcr.io/org/nightly"}},
+	}
+	pos := 0
+	for i, s := range sections {
+		idx := strings.Index(got[pos:], s.header)
+		if idx < 0 {
+			t.Fatalf("section %q missing or out of order:\n%s", s.header, got)
+		}
+		start := pos + idx
+		end := len(got)
+		if i+1 < len(sections) {
+			if next := strings.Index(got[start:], sections[i+1].header); next >= 0 {
+				end = start + next
+			}
+		}
+		for _, member := range s.members {
+			if !strings.Contains(got[start:end], member) {
+				t.Errorf("section %q missing %q:\n%s", s.header, member, got[start:end])
+			}
+		}
+		pos = start
+	}
+
+	for _, unwanted := range []string{"redis", "alpine"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output contains up-to-date or skipped item %q:\n%s", unwanted, got)
+		}
+	}
+
+	got = captureOutput(t, func() { PrintTriage(&checker.Results{}) })
+	if !strings.Contains(got, "Nothing to triage") {
+		t.Errorf("expected nothing-to-triage message, got:\n%s", got)
+	}
+}
+
+func TestPrintLines(t *testing.T) {
+	SetBaseDir("/repo")
+	t.Cleanup(func() { SetBaseDir(""); SetVerbose(false) })
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "nginx", Current: "1.21", Latest: "1.27", Status: checker.StatusUpdateAvailable, Path: "/repo/charts/app/values.yaml", Line: 12},
+			{Registry: "docker.io", Repository: "redis", Current: "7.2", Latest: "7.2", Status: checker.StatusUpToDate, Path: "/repo/charts/app/values.yaml", Line: 20},
+			{Registry: "ghcr.io", Repository: "org/api", Current: "1.0.0", Status: checker.StatusError, Error: "rate limit hit", Path: "/repo/charts/app/values.yaml", Line: 30},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Latest: "13.0.0", Status: checker.StatusMajorUpdateAvailable, Path: "/repo/charts/app/Chart.yaml", Line: 5},
+		},
+	}
+
+	SetVerbose(false)
+	got := captureOutput(t, func() { PrintLines(results) })
+	want := colorRed + "⬆" + colorReset + " charts/app/Chart.yaml:5 postgresql 12.0.0 → 13.0.0\n" +
+		colorYellow + "⚠" + colorReset + " charts/app/values.yaml:12 nginx 1.21 → 1.27\n"
+	if got != want {
+		t.Errorf("PrintLines() =\n%q\nwant\n%q", got, want)
+	}
+
+	SetVerbose(true)
+	got = captureOutput(t, func() { PrintLines(results) })
+	for _, line := range []string{
+		" charts/app/values.yaml:20 redis 7.2\n",
+		" charts/app/values.yaml:30 ghcr.io/org/api 1.0.0 (rate limit hit)\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("verbose output missing %q:\n%s", line, got)
+		}
+	}
+}
+
+func TestPrintDelta(t *testing.T) {
+	t.Cleanup(func() { SetEditor("") })
+	SetEditor("none")
+
+	results := &checker.Results{
+		Images: []checker.ImageResult{
+			{Registry: "docker.io", Repository: "nginx", Current: "1.25.0", Previous: "1.26.0", Latest: "1.27.0", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 3},
+			{Registry: "docker.io", Repository: "redis", Current: "7.2.0", Previous: "7.3.0", Latest: "7.2.0", Status: checker.StatusUpToDate, Path: "values.yaml", Line: 8},
+			{Registry: "docker.io", Repository: "busybox", Current: "1.35.0", Previous: "1.36.0", Latest: "1.36.0", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 12},
+			{Registry: "docker.io", Repository: "alpine", Current: "3.19", Latest: "3.20", Status: checker.StatusUpdateAvailable, Path: "values.yaml", Line: 15},
+		},
+		Charts: []checker.ChartResult{
+			{Name: "postgresql", Current: "12.0.0", Previous: "12.1.0", Latest: "13.0.0", Status: checker.StatusUpdateAvailable, Path: "Chart.yaml", Line: 5},
+		},
+	}
+
+	got := captureOutput(t, func() { PrintDelta(results) })
+
+	if !strings.Contains(got, "3 changed") {
+		t.Errorf("expected 3 changes in header:\n%s", got)
+	}
+	for _, want := range []string{"docker.io/nginx", "new version appeared", "docker.io/redis", "now up to date", "postgresql"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"busybox", "alpine"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output contains unchanged item %q:\n%s", unwanted, got)
+		}
+	}
+
+	got = captureOutput(t, func() { PrintDelta(&checker.Results{}) })
+	if !strings.Contains(got, "No changes since the last run.") {
+		t.Errorf("expected no-changes message, got:\n%s", got)
+	}
+}
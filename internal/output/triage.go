@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/nogo/chartup/internal/checker"
+	"github.com/nogo/chartup/internal/registry"
+)
+
+// triageRow is an image or chart that needs attention
+type triageRow struct {
+	path    string
+	line    int
+	name    string
+	current string
+	latest  string // Latest version, or the error for failed lookups
+}
+
+// triageUpdates are the update sections in the order they are printed
+var triageUpdates = []struct {
+	bump  registry.Bump
+	title string
+}{
+	{registry.BumpMajor, "MAJOR UPDATES"},
+	{registry.BumpMinor, "MINOR UPDATES"},
+	{registry.BumpPatch, "PATCH UPDATES"},
+	{registry.BumpUnknown, "OTHER UPDATES"},
+}
+
+// PrintTriage prints errors and updates grouped by severity: errors first,
+// then major, minor, and patch updates, then updates whose size can't be
+// told from the version numbers. Up-to-date and skipped items are left out.
+func PrintTriage(results *checker.Results) {
+	var errs []triageRow
+	updates := make(map[registry.Bump][]triageRow)
+	add := func(status checker.Status, bump registry.Bump, row triageRow, errMsg string) {
+		switch {
+		case status == checker.StatusError:
+			row.latest = errMsg
+			errs = append(errs, row)
+		case status.IsUpdate():
+			updates[bump] = append(updates[bump], row)
+		}
+	}
+
+	for _, img := range results.Images {
+		add(img.Status, img.Bump, triageRow{img.Path, img.Line, img.Registry + "/" + img.Repository, img.Current, img.Latest}, img.Error)
+	}
+	for _, chart := range results.Charts {
+		add(chart.Status, chart.Bump, triageRow{chart.Path, chart.Line, chart.Name, chart.Current, chart.Latest}, chart.Error)
+	}
+
+	printed := false
+	section := func(title, last string, rows []triageRow) {
+		if len(rows) == 0 {
+			return
+		}
+		if printed {
+			fmt.Fprintln(out)
+		}
+		printed = true
+		printTriageSection(title, last, rows)
+	}
+
+	section("ERRORS", "Error", errs)
+	for _, u := range triageUpdates {
+		section(u.title, "Latest", updates[u.bump])
+	}
+	if !printed {
+		fmt.Fprintln(out, "Nothing to triage: no updates or errors.")
+	}
+
+	fmt.Fprintln(out)
+	printSummary(results)
+}
+
+// printTriageSection prints one triage group sorted by file and line, with
+// last as the header of the version or error column
+func printTriageSection(title, last string, rows []triageRow) {
+	fmt.Fprintf(out, "%s - %d\n", title, len(rows))
+	fmt.Fprintln(out, strings.Repeat("═", 80))
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].path != rows[j].path {
+			return rows[i].path < rows[j].path
+		}
+		return rows[i].line < rows[j].line
+	})
+
+	t := table.NewWriter()
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"Location", "Name", "Current", last})
+	for _, r := range rows {
+		t.AppendRow(table.Row{formatLocationLink(r.path, r.line), r.name, r.current, r.latest})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
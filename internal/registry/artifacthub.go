@@ -1,9 +1,13 @@
 package registry
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 // ArtifactHub API response structures
@@ -14,49 +18,124 @@ type artifactHubPackage struct {
 }
 
 type artifactHubSearchResponse struct {
-	Packages []struct {
-		Version    string `json:"version"`
-		Name       string `json:"name"`
-		Repository struct {
-			Name string `json:"name"`
-		} `json:"repository"`
-	} `json:"packages"`
+	Packages []artifactHubSearchPackage `json:"packages"`
+}
+
+type artifactHubSearchPackage struct {
+	Version    string                `json:"version"`
+	Name       string                `json:"name"`
+	Official   bool                  `json:"official"`
+	Repository artifactHubRepository `json:"repository"`
+}
+
+type artifactHubRepository struct {
+	Name              string `json:"name"`
+	URL               string `json:"url"`
+	OrganizationName  string `json:"organization_name"`
+	UserAlias         string `json:"user_alias"`
+	Official          bool   `json:"official"`
+	VerifiedPublisher bool   `json:"verified_publisher"`
 }
 
 // ChartVersionInfo holds information about a Helm chart version
 type ChartVersionInfo struct {
 	Name          string
-	LatestVersion string
+	LatestVersion string // Latest stable version
+	LatestAny     string // Latest version including pre-releases, if known
 	AppVersion    string
-	FromCache     bool
 }
 
-// GetChartVersion fetches the latest version of a Helm chart from ArtifactHub
-func (c *Client) GetChartVersion(chartName, upstream string) (*ChartVersionInfo, error) {
+// GetChartVersion fetches the latest version of a Helm chart from ArtifactHub.
+// Without an upstream, the chart is looked up in the ArtifactHub repository
+// indexing the Helm repository URL, if any. If ArtifactHub can't resolve the
+// chart and a Helm repository URL is known, the repository's index.yaml is
+// used instead. Charts in an OCI registry (an "oci://" repository) are
+// listed from the registry's tags.
+func (c *Client) GetChartVersion(ctx context.Context, chartName, upstream, repository string) (*ChartVersionInfo, error) {
+	lookupCtx, cancel := c.lookupContext(ctx)
+	defer cancel()
+
+	info, err := c.getChartVersion(lookupCtx, chartName, upstream, repository)
+	return info, c.lookupError(ctx, lookupCtx, err)
+}
+
+func (c *Client) getChartVersion(ctx context.Context, chartName, upstream, repository string) (*ChartVersionInfo, error) {
+	if upstream == "" && IsOCIRepoURL(repository) {
+		return c.getChartVersionFromOCI(ctx, repository, chartName)
+	}
+
+	// Charts from other Helm repositories are looked up in the ArtifactHub
+	// repository with the same URL, then in the index
+	if upstream == "" && IsHelmRepoURL(repository) {
+		if repoName := c.artifactHubRepoName(ctx, repository); repoName != "" {
+			info, err := c.getArtifactHubPackage(ctx, repoName, chartName)
+			if info != nil {
+				return info, nil
+			}
+			if err != nil {
+				debugf("artifacthub: %s/%s: %v, using %s", repoName, chartName, err, repository)
+			}
+		}
+		return c.getChartVersionFromIndex(ctx, repository, chartName)
+	}
+
+	info, err := c.getArtifactHubVersion(ctx, chartName, upstream)
+	if err == nil || errors.Is(err, ErrRateLimit) {
+		return info, err
+	}
+
+	// ArtifactHub is down or doesn't index the chart, try the repo itself
+	switch {
+	case IsHelmRepoURL(repository):
+		indexInfo, indexErr := c.getChartVersionFromIndex(ctx, repository, chartName)
+		if indexErr != nil {
+			return nil, fmt.Errorf("%v; index.yaml fallback: %v", err, indexErr)
+		}
+		return indexInfo, nil
+	case IsOCIRepoURL(repository):
+		ociInfo, ociErr := c.getChartVersionFromOCI(ctx, repository, chartName)
+		if ociErr != nil {
+			return nil, fmt.Errorf("%v; OCI fallback: %v", err, ociErr)
+		}
+		return ociInfo, nil
+	}
+	return info, err
+}
+
+func (c *Client) getArtifactHubVersion(ctx context.Context, chartName, upstream string) (*ChartVersionInfo, error) {
 	if upstream == "" {
 		return nil, fmt.Errorf("no upstream configured for chart %s", chartName)
 	}
 
-	// Map upstream to ArtifactHub repo names
-	repoName := mapUpstreamToRepo(upstream)
-
 	// Try direct package lookup first
-	url := fmt.Sprintf("https://artifacthub.io/api/v1/packages/helm/%s/%s", repoName, chartName)
+	info, err := c.getArtifactHubPackage(ctx, mapUpstreamToRepo(upstream), chartName)
+	if info != nil || err != nil {
+		return info, err
+	}
+
+	// If direct lookup fails, try search
+	return c.searchChart(ctx, chartName, upstream)
+}
+
+// getArtifactHubPackage fetches a chart of an ArtifactHub repository. It
+// returns nil without an error if ArtifactHub has no such package.
+func (c *Client) getArtifactHubPackage(ctx context.Context, repoName, chartName string) (*ChartVersionInfo, error) {
+	url := fmt.Sprintf("%s/api/v1/packages/helm/%s/%s", c.artifactHubURL, repoName, chartName)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return nil, ErrRateLimit
+		return nil, rateLimitError(resp)
 	}
 
 	if resp.StatusCode == 200 {
@@ -71,29 +150,90 @@ func (c *Client) GetChartVersion(chartName, upstream string) (*ChartVersionInfo,
 			AppVersion:    pkg.AppVersion,
 		}, nil
 	}
+	return nil, nil
+}
 
-	// If direct lookup fails, try search
-	return c.searchChart(chartName, upstream)
+// artifactHubRepoName returns the name of the ArtifactHub repository of the
+// Helm repository at repoURL, or "" if ArtifactHub doesn't index it or can't
+// be reached. Answers are remembered for the client's lifetime.
+func (c *Client) artifactHubRepoName(ctx context.Context, repoURL string) string {
+	repoURL = strings.TrimSuffix(repoURL, "/")
+
+	c.hubReposMu.Lock()
+	name, ok := c.hubRepos[repoURL]
+	c.hubReposMu.Unlock()
+	if ok {
+		return name
+	}
+
+	name, err := c.searchArtifactHubRepo(ctx, repoURL)
+	if err != nil {
+		debugf("artifacthub: looking up repository %s: %v", repoURL, err)
+		return ""
+	}
+
+	c.hubReposMu.Lock()
+	if c.hubRepos == nil {
+		c.hubRepos = make(map[string]string)
+	}
+	c.hubRepos[repoURL] = name
+	c.hubReposMu.Unlock()
+	return name
+}
+
+// searchArtifactHubRepo searches ArtifactHub's Helm repositories by URL
+func (c *Client) searchArtifactHubRepo(ctx context.Context, repoURL string) (string, error) {
+	searchURL := fmt.Sprintf("%s/api/v1/repositories/search?kind=0&limit=10&url=%s", c.artifactHubURL, url.QueryEscape(repoURL))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return "", rateLimitError(resp)
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("ArtifactHub API returned status %d", resp.StatusCode)
+	}
+
+	var repos []artifactHubRepository
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return "", err
+	}
+	for _, repo := range repos {
+		if strings.EqualFold(strings.TrimSuffix(repo.URL, "/"), repoURL) {
+			return repo.Name, nil
+		}
+	}
+	return "", nil
 }
 
-func (c *Client) searchChart(chartName, upstream string) (*ChartVersionInfo, error) {
+func (c *Client) searchChart(ctx context.Context, chartName, upstream string) (*ChartVersionInfo, error) {
 	repoName := mapUpstreamToRepo(upstream)
-	url := fmt.Sprintf("https://artifacthub.io/api/v1/packages/search?ts_query_web=%s&kind=0&limit=10", chartName)
+	url := fmt.Sprintf("%s/api/v1/packages/search?ts_query_web=%s&kind=0&limit=10", c.artifactHubURL, chartName)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return nil, ErrRateLimit
+		return nil, rateLimitError(resp)
 	}
 
 	if resp.StatusCode != 200 {
@@ -105,27 +245,66 @@ func (c *Client) searchChart(chartName, upstream string) (*ChartVersionInfo, err
 		return nil, err
 	}
 
-	// Find matching package from the correct repo
-	for _, pkg := range searchResp.Packages {
-		if pkg.Name == chartName && pkg.Repository.Name == repoName {
-			return &ChartVersionInfo{
-				Name:          chartName,
-				LatestVersion: pkg.Version,
-			}, nil
-		}
+	pkg, exact := bestSearchMatch(searchResp.Packages, chartName, repoName)
+	if pkg == nil {
+		return nil, fmt.Errorf("chart %s not found on ArtifactHub", chartName)
+	}
+	if !exact {
+		debugf("artifacthub: no %s/%s package, using fuzzy match %s/%s %s",
+			repoName, chartName, pkg.Repository.Name, pkg.Name, pkg.Version)
 	}
 
-	// Try any matching package name
-	for _, pkg := range searchResp.Packages {
-		if pkg.Name == chartName {
-			return &ChartVersionInfo{
-				Name:          chartName,
-				LatestVersion: pkg.Version,
-			}, nil
+	return &ChartVersionInfo{
+		Name:          chartName,
+		LatestVersion: pkg.Version,
+	}, nil
+}
+
+// bestSearchMatch picks the search result for chartName, preferring the exact
+// repository. Otherwise same-named packages are ranked by how trustworthy and
+// close to repoName their repository is, so a fork isn't picked over the
+// official chart. exact reports whether the repository matched.
+func bestSearchMatch(packages []artifactHubSearchPackage, chartName, repoName string) (pkg *artifactHubSearchPackage, exact bool) {
+	bestScore := -1
+	for i := range packages {
+		candidate := &packages[i]
+		if candidate.Name != chartName {
+			continue
 		}
+		if candidate.Repository.Name == repoName {
+			return candidate, true
+		}
+
+		if score := searchMatchScore(candidate, repoName); score > bestScore {
+			pkg, bestScore = candidate, score
+		}
+	}
+	return pkg, false
+}
+
+// searchMatchScore ranks a fuzzy search candidate; higher is better
+func searchMatchScore(pkg *artifactHubSearchPackage, repoName string) int {
+	score := 0
+	if pkg.Official || pkg.Repository.Official {
+		score += 4
 	}
+	if pkg.Repository.VerifiedPublisher {
+		score += 2
+	}
+
+	repo := pkg.Repository
+	switch {
+	case strings.EqualFold(repo.OrganizationName, repoName) || strings.EqualFold(repo.UserAlias, repoName):
+		score += 2
+	case repo.Name != "" && (strings.Contains(repo.Name, repoName) || strings.Contains(repoName, repo.Name)):
+		score++
+	}
+	return score
+}
 
-	return nil, fmt.Errorf("chart %s not found on ArtifactHub", chartName)
+// IsHelmRepoURL reports whether repository is a classic http(s) Helm repository
+func IsHelmRepoURL(repository string) bool {
+	return strings.HasPrefix(repository, "https://") || strings.HasPrefix(repository, "http://")
 }
 
 func mapUpstreamToRepo(upstream string) string {
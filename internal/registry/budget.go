@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LookupTimeoutError is returned when a lookup, including its token exchanges
+// and follow-up requests, runs past the lookup budget. It implements net.Error
+// so it is handled like any other network timeout.
+type LookupTimeoutError struct {
+	Budget time.Duration
+}
+
+func (e *LookupTimeoutError) Error() string {
+	return fmt.Sprintf("lookup exceeded its %s budget", e.Budget)
+}
+
+// Timeout reports true, the lookup gave up waiting on the network
+func (e *LookupTimeoutError) Timeout() bool { return true }
+
+// Temporary reports true, a later run may finish in time
+func (e *LookupTimeoutError) Temporary() bool { return true }
+
+// SetLookupBudget bounds the total time of one lookup, across all of its
+// requests. 0 or less means no budget beyond the per-request HTTP timeout.
+func (c *Client) SetLookupBudget(budget time.Duration) {
+	c.lookupBudget = budget
+}
+
+// lookupContext returns the context that bounds one lookup within ctx
+func (c *Client) lookupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.lookupBudget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.lookupBudget)
+}
+
+// lookupError replaces the error of a lookup that ran out of budget. A
+// cancelled or expired parent context is reported as is.
+func (c *Client) lookupError(parent, ctx context.Context, err error) error {
+	if err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &LookupTimeoutError{Budget: c.lookupBudget}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package registry
+
+// Bump is the size of the change from one version to a newer one
+type Bump int
+
+const (
+	BumpNone    Bump = iota // Not newer
+	BumpUnknown             // Newer, but not comparable by version components (e.g. date tags)
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	case BumpUnknown:
+		return "unknown"
+	default:
+		return "none"
+	}
+}
+
+// ParseBump parses "patch", "minor", or "major"
+func ParseBump(s string) (Bump, bool) {
+	switch s {
+	case "patch":
+		return BumpPatch, true
+	case "minor":
+		return BumpMinor, true
+	case "major":
+		return BumpMajor, true
+	default:
+		return BumpNone, false
+	}
+}
+
+// BumpLevel classifies the update from current to latest by the first
+// version component that differs, e.g. 1.2.3 -> 1.4.0 is a minor bump.
+// Prefixes such as "v" are ignored and missing components count as 0.
+// Components are compared like compareSemver does, so long numbers such as
+// date stamps don't overflow.
+func BumpLevel(current, latest string) Bump {
+	if current == latest {
+		return BumpNone
+	}
+
+	a := semverRegex.FindStringSubmatch(trimVersionPrefix(current))
+	b := semverRegex.FindStringSubmatch(trimVersionPrefix(latest))
+	if a == nil || b == nil {
+		return BumpUnknown
+	}
+
+	levels := []Bump{BumpMajor, BumpMinor, BumpPatch}
+	for i, level := range levels {
+		switch compareNumeric(b[i+1], a[i+1]) {
+		case 1:
+			return level
+		case -1:
+			return BumpNone
+		}
+	}
+	return BumpNone
+}
@@ -0,0 +1,18 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+)
+
+// debugOut receives notes about lookup decisions, such as fuzzy matches
+var debugOut io.Writer = io.Discard
+
+// SetDebugOutput sets where debug notes are written (io.Discard disables them)
+func SetDebugOutput(w io.Writer) {
+	debugOut = w
+}
+
+func debugf(format string, args ...any) {
+	fmt.Fprintf(debugOut, "debug: "+format+"\n", args...)
+}
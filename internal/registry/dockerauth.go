@@ -0,0 +1,257 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoCredentials is returned when Docker Hub credentials are needed but none are configured
+var ErrNoCredentials = errors.New("no Docker Hub credentials found")
+
+// Credentials holds a registry username and password or access token
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// FindDockerHubCredentials looks up Docker Hub credentials from the
+// DOCKERHUB_USERNAME/DOCKERHUB_TOKEN environment variables, then from the
+// Docker CLI config ($DOCKER_CONFIG/config.json or ~/.docker/config.json).
+// Credential helpers (credsStore) are not supported.
+func FindDockerHubCredentials() (*Credentials, error) {
+	return findDockerHubCredentials("")
+}
+
+// DockerHubCredentials is FindDockerHubCredentials, but reads the file set
+// with LoadDockerConfig instead of the default Docker CLI config
+func (c *Client) DockerHubCredentials() (*Credentials, error) {
+	return findDockerHubCredentials(c.dockerConfig)
+}
+
+// findDockerHubCredentials looks up Docker Hub credentials from the
+// environment, then from configPath or, if empty, the default Docker CLI config
+func findDockerHubCredentials(configPath string) (*Credentials, error) {
+	if user, token := os.Getenv("DOCKERHUB_USERNAME"), os.Getenv("DOCKERHUB_TOKEN"); user != "" && token != "" {
+		return &Credentials{Username: user, Password: token}, nil
+	}
+
+	if configPath == "" {
+		dir := os.Getenv("DOCKER_CONFIG")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, ErrNoCredentials
+			}
+			dir = filepath.Join(home, ".docker")
+		}
+		configPath = filepath.Join(dir, "config.json")
+	}
+
+	return readDockerConfigCredentials(configPath)
+}
+
+// readDockerConfigCredentials reads the Docker Hub entry from a Docker CLI config file
+func readDockerConfigCredentials(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+
+	auths, err := parseDockerConfigAuths(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	creds, ok := auths["docker.io"]
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	return creds, nil
+}
+
+// LoadDockerConfig reads registry credentials from the "auths" map of a
+// Docker CLI config file (the format of ~/.docker/config.json). Lookups on
+// a registry with an entry authenticate with it instead of anonymously, and
+// the file's Docker Hub entry is used when logging in after a rate limit.
+func (c *Client) LoadDockerConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	auths, err := parseDockerConfigAuths(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	c.dockerConfig = path
+	c.auths = auths
+	return nil
+}
+
+// SetDockerHubAuth sets a Docker Hub username and access token to log in
+// with before the first Docker Hub lookup, rather than only after hitting
+// the anonymous rate limit. The token takes precedence over a Docker Hub
+// entry from LoadDockerConfig. Empty values leave lookups anonymous.
+func (c *Client) SetDockerHubAuth(username, token string) {
+	if username == "" || token == "" {
+		c.hubAuth = nil
+		return
+	}
+	c.hubAuth = &Credentials{Username: username, Password: token}
+}
+
+// credentialsFor returns the stored credentials for a registry host, or nil.
+// Credentials from LoginDockerHub or SetDockerHubAuth apply to all Docker
+// Hub hosts and to the host set with SetDockerHubRegistry.
+func (c *Client) credentialsFor(host string) *Credentials {
+	key := authKey(host)
+	if key == "docker.io" || (host != "" && host == c.dockerHubRegistry) {
+		if creds := c.hubLoginCredentials(); creds != nil {
+			return creds
+		}
+		if c.hubAuth != nil {
+			return c.hubAuth
+		}
+	}
+	return c.auths[key]
+}
+
+// parseDockerConfigAuths decodes the base64 "user:password" auth field of
+// each entry in a Docker CLI config, keyed by authKey. Entries without an
+// auth field (e.g. those kept in a credential helper) are left out.
+func parseDockerConfigAuths(data []byte) (map[string]*Credentials, error) {
+	var cfg struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	auths := make(map[string]*Credentials)
+	for key, entry := range cfg.Auths {
+		if entry.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("decoding auth for %s: %w", key, err)
+		}
+		user, password, ok := strings.Cut(string(decoded), ":")
+		if !ok || user == "" || password == "" {
+			continue
+		}
+		auths[authKey(key)] = &Credentials{Username: user, Password: password}
+	}
+	return auths, nil
+}
+
+// authKey normalizes a config.json auths key or registry host to a bare
+// host, e.g. "https://ghcr.io/v2/" -> "ghcr.io". All Docker Hub hosts,
+// including the legacy "https://index.docker.io/v1/", map to "docker.io".
+func authKey(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	key, _, _ = strings.Cut(key, "/")
+	switch key {
+	case "", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return "docker.io"
+	}
+	return key
+}
+
+// LoginDockerHub exchanges credentials for a Docker Hub token that is sent
+// with all subsequent Docker Hub requests. With SetDockerHubRegistry, the
+// credentials are kept for the registry's token endpoint instead.
+func (c *Client) LoginDockerHub(ctx context.Context, creds *Credentials) error {
+	if c.dockerHubRegistry != "" {
+		c.tokenMu.Lock()
+		c.hubCreds = creds
+		c.tokenMu.Unlock()
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"username": creds.Username,
+		"password": creds.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.dockerHubURL+"/v2/users/login", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Docker Hub login returned status %d", resp.StatusCode)
+	}
+
+	var loginResp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return err
+	}
+	if loginResp.Token == "" {
+		return fmt.Errorf("Docker Hub login returned no token")
+	}
+
+	c.tokenMu.Lock()
+	c.dockerHubToken = loginResp.Token
+	c.tokenMu.Unlock()
+	return nil
+}
+
+// loginDockerHubOnce logs in to Docker Hub once with the credentials set
+// with SetDockerHubAuth or, failing that, the Docker Hub entry of the file
+// set with LoadDockerConfig, so private repositories can be listed and the
+// authenticated rate limit applies
+func (c *Client) loginDockerHubOnce(ctx context.Context) error {
+	creds := c.credentialsFor("docker.io")
+	if creds == nil {
+		return nil
+	}
+	c.hubLogin.Do(func() {
+		c.hubLoginErr = c.LoginDockerHub(ctx, creds)
+	})
+	return c.hubLoginErr
+}
+
+// DockerHubAuthenticated reports whether Docker Hub requests are authenticated
+func (c *Client) DockerHubAuthenticated() bool {
+	return c.hubToken() != "" || c.hubLoginCredentials() != nil
+}
+
+// hubLoginCredentials returns the credentials LoginDockerHub kept for the
+// Docker Hub registry API, if any
+func (c *Client) hubLoginCredentials() *Credentials {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.hubCreds
+}
+
+// hubToken returns the Docker Hub token set by LoginDockerHub, if any
+func (c *Client) hubToken() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.dockerHubToken
+}
@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+// ErrNoAWSCredentials is returned for ECR images when the AWS default
+// credential chain (environment, shared config files, instance metadata)
+// yields no credentials
+var ErrNoAWSCredentials = errors.New("no AWS credentials found")
+
+// ecrHostPattern matches private ECR registries, e.g.
+// 123456789012.dkr.ecr.eu-central-1.amazonaws.com
+var ecrHostPattern = regexp.MustCompile(`^\d{12}\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// ecrRegion returns the AWS region of an ECR registry host, or false if the
+// host is not an ECR registry
+func ecrRegion(host string) (string, bool) {
+	m := ecrHostPattern.FindStringSubmatch(host)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// ecrToken is a cached ECR authorization token for one region
+type ecrToken struct {
+	token     string // base64 "AWS:<password>", used as Basic credentials
+	expiresAt time.Time
+}
+
+// doerFunc adapts Client.do to the AWS SDK's HTTP client interface, so SDK
+// requests are counted like all others
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ecrMaxPages bounds how many pages of tags are read per ECR repository
+const ecrMaxPages = 50
+
+func (c *Client) getECRTags(ctx context.Context, host, region, repository, currentTag string) (*TagInfo, error) {
+	token, err := c.getECRToken(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	// ECR returns at most 1000 tags per page and links to the next one
+	var tags []string
+	next := fmt.Sprintf("https://%s/v2/%s/tags/list?n=1000", host, repository)
+	for page := 0; next != ""; page++ {
+		if page == ecrMaxPages {
+			debugf("ECR tags of %s: stopped after %d pages", repository, page)
+			break
+		}
+		pageTags, link, err := c.getECRTagsPage(ctx, host, next, token)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, pageTags...)
+		next = link
+	}
+
+	latest, latestAny := SelectLatest(tags, currentTag, c.onlySemver)
+
+	return &TagInfo{
+		Name:      repository,
+		Latest:    latest,
+		LatestAny: latestAny,
+		AllTags:   tags,
+	}, nil
+}
+
+// getECRTagsPage fetches one page of tags and returns the URL of the next
+// page from the Link header, or "" on the last page
+func (c *Client) getECRTagsPage(ctx context.Context, host, pageURL, token string) (tags []string, next string, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Authorization", "Basic "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, "", rateLimitError(resp)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, "", fmt.Errorf("%s API returned status %d", host, resp.StatusCode)
+	}
+
+	var tagsResp ociTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
+		return nil, "", err
+	}
+	return tagsResp.Tags, nextPageURL(req.URL, resp.Header.Get("Link")), nil
+}
+
+// nextPageURL resolves the rel="next" target of an OCI Distribution Link
+// header, e.g. `</v2/team/app/tags/list?last=x&n=1000>; rel="next"`,
+// against the URL of the current page. It returns "" if there is none.
+func nextPageURL(current *url.URL, link string) string {
+	target, params, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(params, `rel="next"`) {
+		return ""
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(target), "<"), ">")
+	ref, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return current.ResolveReference(ref).String()
+}
+
+// getECRToken returns an ECR authorization token for region, calling
+// GetAuthorizationToken with credentials from the AWS default chain.
+// Tokens are valid for 12 hours and reused until shortly before expiry.
+func (c *Client) getECRToken(ctx context.Context, region string) (string, error) {
+	c.ecrMu.Lock()
+	defer c.ecrMu.Unlock()
+
+	if t, ok := c.ecrTokens[region]; ok && time.Until(t.expiresAt) > time.Minute {
+		return t.token, nil
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return "", err
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoAWSCredentials, err)
+	}
+
+	client := ecr.NewFromConfig(cfg, func(o *ecr.Options) {
+		o.HTTPClient = doerFunc(c.do)
+		if c.ecrEndpoint != "" {
+			o.BaseEndpoint = aws.String(c.ecrEndpoint)
+		}
+	})
+	out, err := client.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", fmt.Errorf("ECR GetAuthorizationToken: %w", err)
+	}
+	if len(out.AuthorizationData) == 0 || out.AuthorizationData[0].AuthorizationToken == nil {
+		return "", errors.New("ECR returned no authorization token")
+	}
+
+	data := out.AuthorizationData[0]
+	t := ecrToken{token: *data.AuthorizationToken, expiresAt: time.Now().Add(12 * time.Hour)}
+	if data.ExpiresAt != nil {
+		t.expiresAt = *data.ExpiresAt
+	}
+
+	if c.ecrTokens == nil {
+		c.ecrTokens = make(map[string]ecrToken)
+	}
+	c.ecrTokens[region] = t
+	return t.token, nil
+}
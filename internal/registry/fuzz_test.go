@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzFindLatestTag(f *testing.F) {
+	seeds := []struct {
+		tags    string
+		current string
+	}{
+		{"1.0.0,1.1.0,2.0.0", "1.0.0"},
+		{"v1.0.0,v2.0.0,1.5.0", "v1.0.0"},
+		{"latest,stable", "latest"},
+		{"410,411,479", "410"},
+		{"1.2.0-rc1,1.1.0", "1.0.0"},
+		{"99999999999999999999.0.0,1.0.0", "1.0.0"},
+		{"", "1.0.0"},
+		{"ü,1.0", "v"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.tags, seed.current)
+	}
+
+	f.Fuzz(func(t *testing.T, tagList, current string) {
+		var tags []string
+		if tagList != "" {
+			tags = strings.Split(tagList, ",")
+		}
+
+		latest := findLatestTag(tags, current, false)
+
+		if len(tags) == 0 {
+			if latest != "" {
+				t.Errorf("findLatestTag(%q, %q) = %q, want empty for no tags", tags, current, latest)
+			}
+			return
+		}
+
+		if latest == "" {
+			return
+		}
+		for _, tag := range tags {
+			if tag == latest {
+				return
+			}
+		}
+		t.Errorf("findLatestTag(%q, %q) = %q, not among candidates", tags, current, latest)
+	})
+}
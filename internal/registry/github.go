@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ParseGitHubRepo extracts the owner and repository from a GitHub URL such
+// as "https://github.com/trinodb/trino" or "git@github.com:org/app.git"
+func ParseGitHubRepo(source string) (owner, repo string, ok bool) {
+	source = strings.TrimPrefix(source, "git@github.com:")
+	source = strings.TrimPrefix(source, "github.com/")
+	if u, err := url.Parse(source); err == nil && u.Host != "" {
+		if !strings.EqualFold(u.Host, "github.com") && !strings.EqualFold(u.Host, "www.github.com") {
+			return "", "", false
+		}
+		source = u.Path
+	} else if strings.Contains(source, "://") {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(source, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// GetLatestRelease returns the tag of the latest published (non-draft,
+// non-pre-release) GitHub release of owner/repo. A GITHUB_TOKEN in the
+// environment raises GitHub's anonymous rate limit.
+func (c *Client) GetLatestRelease(ctx context.Context, owner, repo string) (string, error) {
+	lookupCtx, cancel := c.lookupContext(ctx)
+	defer cancel()
+
+	tag, err := c.getLatestRelease(lookupCtx, owner, repo)
+	return tag, c.lookupError(ctx, lookupCtx, err)
+}
+
+func (c *Client) getLatestRelease(ctx context.Context, owner, repo string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/latest", c.githubURL, owner, repo)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// GitHub signals exhausted rate limits with 403 as well as 429
+	if resp.StatusCode == 429 || (resp.StatusCode == 403 && resp.Header.Get("X-RateLimit-Remaining") == "0") {
+		return "", rateLimitError(resp)
+	}
+
+	if resp.StatusCode == 404 {
+		return "", fmt.Errorf("no GitHub releases found for %s/%s", owner, repo)
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+// IsNewerVersion reports whether candidate is a newer version than current.
+// Release tag prefixes such as "v" or "app-" are ignored.
+func IsNewerVersion(candidate, current string) bool {
+	return compareSemver(trimVersionPrefix(candidate), trimVersionPrefix(current)) > 0
+}
+
+// trimVersionPrefix drops everything before the first digit ("trino-410" -> "410")
+func trimVersionPrefix(version string) string {
+	if i := strings.IndexAny(version, "0123456789"); i > 0 {
+		return version[i:]
+	}
+	return version
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Helm repository index.yaml structure
+type helmIndex struct {
+	Entries map[string][]helmIndexEntry `yaml:"entries"`
+}
+
+type helmIndexEntry struct {
+	Version    string `yaml:"version"`
+	AppVersion string `yaml:"appVersion"`
+}
+
+// GetChartVersionFromIndex fetches the latest version of a chart from a
+// classic Helm repository's index.yaml
+func (c *Client) GetChartVersionFromIndex(ctx context.Context, repoURL, chartName string) (*ChartVersionInfo, error) {
+	lookupCtx, cancel := c.lookupContext(ctx)
+	defer cancel()
+
+	info, err := c.getChartVersionFromIndex(lookupCtx, repoURL, chartName)
+	return info, c.lookupError(ctx, lookupCtx, err)
+}
+
+func (c *Client) getChartVersionFromIndex(ctx context.Context, repoURL, chartName string) (*ChartVersionInfo, error) {
+	url := strings.TrimSuffix(repoURL, "/") + "/index.yaml"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, rateLimitError(resp)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Helm repository %s returned status %d", repoURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index helmIndex
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	entries := index.Entries[chartName]
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("chart %s not found in %s", chartName, repoURL)
+	}
+
+	versions := make([]string, 0, len(entries))
+	appVersions := make(map[string]string, len(entries))
+	for _, e := range entries {
+		versions = append(versions, e.Version)
+		appVersions[e.Version] = e.AppVersion
+	}
+
+	stable := filterSemverTags(versions)
+	if len(stable) == 0 {
+		return nil, fmt.Errorf("no released versions of chart %s in %s", chartName, repoURL)
+	}
+	sort.Sort(sort.Reverse(semverSlice(stable)))
+
+	_, latestAny := SelectLatest(versions, stable[0], false)
+
+	return &ChartVersionInfo{
+		Name:          chartName,
+		LatestVersion: stable[0],
+		LatestAny:     latestAny,
+		AppVersion:    appVersions[stable[0]],
+	}, nil
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IsOCIRepoURL reports whether repository is an OCI registry holding Helm
+// charts, such as "oci://registry-1.docker.io/bitnamicharts"
+func IsOCIRepoURL(repository string) bool {
+	return strings.HasPrefix(repository, "oci://")
+}
+
+// getChartVersionFromOCI lists the tags of a chart pushed to an OCI
+// registry, with an ECR token for private ECR registries. Helm stores a "+"
+// in a version as "_" in the tag.
+func (c *Client) getChartVersionFromOCI(ctx context.Context, repoURL, chartName string) (*ChartVersionInfo, error) {
+	host, path, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(repoURL, "oci://"), "/"), "/")
+	repository := chartName
+	if path != "" {
+		repository = path + "/" + chartName
+	}
+
+	var info *TagInfo
+	var err error
+	if region, ok := ecrRegion(host); ok {
+		info, err = c.getECRTags(ctx, host, region, repository, "")
+	} else {
+		info, err = c.getOCITags(ctx, host, repository, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(info.AllTags))
+	for _, tag := range info.AllTags {
+		versions = append(versions, strings.ReplaceAll(tag, "_", "+"))
+	}
+
+	stable := filterSemverTags(versions)
+	if len(stable) == 0 {
+		return nil, fmt.Errorf("no released versions of chart %s in %s", chartName, repoURL)
+	}
+	sort.Sort(sort.Reverse(semverSlice(stable)))
+
+	_, latestAny := SelectLatest(versions, stable[0], false)
+
+	return &ChartVersionInfo{
+		Name:          chartName,
+		LatestVersion: stable[0],
+		LatestAny:     latestAny,
+	}, nil
+}
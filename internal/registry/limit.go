@@ -0,0 +1,44 @@
+package registry
+
+// DefaultDockerHubConcurrency is how many Docker Hub lookups may run at once
+// by default. Its anonymous rate limit is strict, so this stays low even when
+// other registries are queried with more concurrency.
+const DefaultDockerHubConcurrency = 2
+
+// SetMaxConcurrency limits the number of concurrent lookups against one
+// registry (e.g. "docker.io"). 0 or less removes the limit.
+func (c *Client) SetMaxConcurrency(registry string, n int) {
+	c.limitsMu.Lock()
+	defer c.limitsMu.Unlock()
+
+	if c.limits == nil {
+		c.limits = make(map[string]chan struct{})
+	}
+	if n <= 0 {
+		delete(c.limits, limitKey(registry))
+		return
+	}
+	c.limits[limitKey(registry)] = make(chan struct{}, n)
+}
+
+// acquire waits for a free lookup slot on registry and returns the function
+// that releases it
+func (c *Client) acquire(registry string) func() {
+	c.limitsMu.Lock()
+	slots := c.limits[limitKey(registry)]
+	c.limitsMu.Unlock()
+
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// limitKey normalizes the registry names that refer to Docker Hub
+func limitKey(registry string) string {
+	if registry == "" {
+		return "docker.io"
+	}
+	return registry
+}
@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+)
+
+// mirrorFor returns the host and repository to look up an image of registry
+// at, if a mirror is configured for the registry. Docker Hub repositories
+// are normalized to their registry API name, e.g. "library/nginx".
+func (c *Client) mirrorFor(registry, repository string) (string, string, bool) {
+	if registry == "" {
+		registry = "docker.io"
+	}
+	mirror, ok := c.mirrors[registry]
+	if !ok || mirror == "" {
+		return "", "", false
+	}
+	if registry == "docker.io" {
+		namespace, name, _ := NormalizeDockerRepo(repository)
+		repository = namespace + "/" + name
+	}
+	return mirror, repository, true
+}
+
+// insecureHost reports whether host, which may include a port, was listed
+// in Options.InsecureRegistries, either with the same port or without one
+func (c *Client) insecureHost(host string) bool {
+	if c.insecure[host] {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return c.insecure[h]
+	}
+	return false
+}
+
+// newInsecureTransport returns a transport that doesn't verify TLS
+// certificates and falls back to plain HTTP for servers that don't speak
+// TLS. It is only used for requests to insecure registries.
+func newInsecureTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return insecureTransport{transport}
+}
+
+type insecureTransport struct {
+	base http.RoundTripper
+}
+
+func (t insecureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil || req.URL.Scheme != "https" || req.Context().Err() != nil {
+		return resp, err
+	}
+
+	debugf("retrying %s %s over plain HTTP: %v", req.Method, req.URL.Redacted(), err)
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitError is a rate limit that says when it resets. It matches
+// ErrRateLimit with errors.Is.
+type RateLimitError struct {
+	ResetAt time.Time // Zero if the registry didn't say
+	Err     error     // Why the rate limit could not be worked around, if known
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Err != nil {
+		return ErrRateLimit.Error() + ": " + e.Err.Error()
+	}
+	return ErrRateLimit.Error()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimit
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// rateLimitError returns the error for a 429 response, with the reset time
+// from its headers if it has one
+func rateLimitError(resp *http.Response) error {
+	resetAt := parseRateLimitReset(resp.Header, time.Now())
+	if resetAt.IsZero() {
+		return ErrRateLimit
+	}
+	return &RateLimitError{ResetAt: resetAt}
+}
+
+// parseRateLimitReset returns when a rate limit resets according to the
+// Retry-After header (seconds or an HTTP date) or the RateLimit-Reset and
+// X-RateLimit-Reset headers (seconds, or a Unix time as sent by Docker Hub
+// and GitHub), or the zero time if none is set
+func parseRateLimitReset(header http.Header, now time.Time) time.Time {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return at
+		}
+	}
+
+	for _, name := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		seconds, err := strconv.ParseInt(strings.TrimSpace(header.Get(name)), 10, 64)
+		if err != nil || seconds < 0 {
+			continue
+		}
+		// Values this large are points in time rather than a wait
+		if seconds > 1_000_000_000 {
+			return time.Unix(seconds, 0)
+		}
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	return time.Time{}
+}
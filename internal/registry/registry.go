@@ -1,58 +1,193 @@
 package registry
 
 import (
+	"cmp"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 var ErrRateLimit = errors.New("rate limit exceeded")
 
+// ErrUnsupportedRegistry is returned for image hosts that are neither a known
+// registry nor answer as an OCI Distribution registry
+var ErrUnsupportedRegistry = errors.New("unsupported registry")
+
 // Client is a registry client for checking image tags
 type Client struct {
-	httpClient *http.Client
+	httpClient     *http.Client
+	artifactHubURL string
+	dockerHubURL   string
+	githubURL      string
+	lookupBudget   time.Duration // Total time allowed per lookup (0 = unbounded)
+	onlySemver     bool          // Whether only clean release versions count for every image
+
+	hostTimeouts map[string]time.Duration // Request timeouts by host, overriding httpClient's
+	retries      int                      // Extra attempts after a transient failure
+	retryBackoff time.Duration            // Wait before the first retry
+
+	mirrors           map[string]string // Mirror hosts by registry
+	insecure          map[string]bool   // Hosts reached without TLS verification
+	insecureTransport http.RoundTripper // Used for requests to insecure hosts
+
+	tokenMu        sync.Mutex
+	dockerHubToken string       // Set by LoginDockerHub
+	hubCreds       *Credentials // Set by LoginDockerHub when dockerHubRegistry is used
+
+	// dockerHubRegistry is the registry API host for docker.io tag lookups,
+	// e.g. "registry-1.docker.io"; empty uses the hub.docker.com web API
+	dockerHubRegistry string
+	dockerHubMaxPages int // Pages of tags read per Docker Hub repository
+
+	dockerConfig string                  // Docker CLI config file set with LoadDockerConfig
+	auths        map[string]*Credentials // Registry credentials by host, from dockerConfig
+	hubLogin     sync.Once
+	hubAuth      *Credentials // Set by SetDockerHubAuth
+	hubLoginErr  error        // Why logging in with hubAuth or the dockerConfig Docker Hub entry failed
+
+	statsMu sync.Mutex
+	stats   map[string]*RegistryStats // Request counters by host
+
+	limitsMu sync.Mutex
+	limits   map[string]chan struct{} // Lookup slots by registry
+
+	hubReposMu sync.Mutex
+	hubRepos   map[string]string // ArtifactHub repository names by Helm repository URL
+
+	ecrEndpoint string // Overrides the ECR API endpoint (tests)
+	ecrMu       sync.Mutex
+	ecrTokens   map[string]ecrToken // ECR authorization tokens by region
 }
 
 // New creates a new registry client
-func New() *Client {
-	return &Client{
+// Docker Hub lookups are limited to DefaultDockerHubConcurrency at a time
+func New(opts Options) *Client {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	c := &Client{
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
+		hostTimeouts:      opts.RegistryTimeouts,
+		retries:           max(opts.Retries, 0),
+		retryBackoff:      opts.RetryBackoff,
+		artifactHubURL:    "https://artifacthub.io",
+		dockerHubURL:      "https://hub.docker.com",
+		githubURL:         "https://api.github.com",
+		dockerHubMaxPages: DefaultDockerHubMaxPages,
+		mirrors:           opts.Mirrors,
+		onlySemver:        opts.OnlySemver,
+	}
+	if len(opts.InsecureRegistries) > 0 {
+		c.insecure = make(map[string]bool)
+		for _, host := range opts.InsecureRegistries {
+			c.insecure[host] = true
+		}
+		c.insecureTransport = newInsecureTransport()
 	}
+	c.SetMaxConcurrency("docker.io", DefaultDockerHubConcurrency)
+	return c
+}
+
+// SetDockerHubRegistry makes docker.io tag lookups use the OCI registry API
+// on host (e.g. "registry-1.docker.io", with tokens from auth.docker.io, or a
+// pull-through mirror) instead of the hub.docker.com web API. Empty restores
+// the web API.
+func (c *Client) SetDockerHubRegistry(host string) {
+	c.dockerHubRegistry = host
 }
 
 // TagInfo holds information about an image tag
 type TagInfo struct {
 	Name      string
-	Latest    string
+	Latest    string // Latest tag matching the current tag's style (stable)
+	LatestAny string // Latest tag including pre-releases
 	AllTags   []string
-	FromCache bool
 }
 
 // GetLatestTag fetches the latest tag for an image from the appropriate registry
-func (c *Client) GetLatestTag(registry, repository, currentTag string) (*TagInfo, error) {
+func (c *Client) GetLatestTag(ctx context.Context, registry, repository, currentTag string) (*TagInfo, error) {
+	release := c.acquire(registry)
+	defer release()
+
+	lookupCtx, cancel := c.lookupContext(ctx)
+	defer cancel()
+
+	info, err := c.getLatestTag(lookupCtx, registry, repository, currentTag)
+	return info, c.lookupError(ctx, lookupCtx, err)
+}
+
+func (c *Client) getLatestTag(ctx context.Context, registry, repository, currentTag string) (*TagInfo, error) {
+	if mirror, repo, ok := c.mirrorFor(registry, repository); ok {
+		return c.getOCITags(ctx, mirror, repo, currentTag)
+	}
+
 	switch {
 	case registry == "docker.io" || registry == "":
-		return c.getDockerHubTags(repository, currentTag)
+		if c.dockerHubRegistry != "" {
+			namespace, name, _ := NormalizeDockerRepo(repository)
+			return c.getOCITags(ctx, c.dockerHubRegistry, namespace+"/"+name, currentTag)
+		}
+		return c.getDockerHubTags(ctx, repository, currentTag)
 	case strings.Contains(registry, "quay.io"):
-		return c.getQuayTags(repository, currentTag)
+		if c.credentialsFor("quay.io") != nil {
+			// The Quay API takes OAuth tokens; the registry API takes
+			// the same credentials as docker login
+			return c.getOCITags(ctx, "quay.io", repository, currentTag)
+		}
+		return c.getQuayTags(ctx, repository, currentTag)
 	case strings.Contains(registry, "ghcr.io"):
-		return c.getOCITags("ghcr.io", repository, currentTag)
+		return c.getOCITags(ctx, "ghcr.io", repository, currentTag)
 	case strings.Contains(registry, "gcr.io"):
-		return c.getOCITags("gcr.io", repository, currentTag)
+		return c.getOCITags(ctx, "gcr.io", repository, currentTag)
 	case strings.Contains(registry, "registry.k8s.io"):
-		return c.getOCITags("registry.k8s.io", repository, currentTag)
+		return c.getOCITags(ctx, "registry.k8s.io", repository, currentTag)
+	case ecrHostPattern.MatchString(registry):
+		region, _ := ecrRegion(registry)
+		return c.getECRTags(ctx, registry, region, repository, currentTag)
 	default:
-		return nil, fmt.Errorf("unsupported registry: %s", registry)
+		// Any other host is tried as a standard OCI Distribution registry,
+		// such as Harbor or Artifactory
+		info, err := c.getOCITags(ctx, registry, repository, currentTag)
+		if err == nil || errors.Is(err, ErrRateLimit) {
+			return info, err
+		}
+
+		// Timeouts, DNS and TLS failures say nothing about the host
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, err
+		}
+		if !c.isOCIRegistry(ctx, registry) {
+			return nil, fmt.Errorf("%w: %s: %w", ErrUnsupportedRegistry, registry, err)
+		}
+		return nil, err
 	}
 }
 
+// isOCIRegistry reports whether host answers the OCI Distribution version
+// check (GET /v2/) with 200 or 401, as registries do. A host that can't be
+// reached is given the benefit of the doubt.
+func (c *Client) isOCIRegistry(ctx context.Context, host string) bool {
+	resp, err := c.requestOCI(ctx, "GET", fmt.Sprintf("https://%s/v2/", host), "", "")
+	if err != nil {
+		return true
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusUnauthorized
+}
+
 // Docker Hub API response structures
 type dockerHubTagsResponse struct {
 	Results []struct {
@@ -61,36 +196,110 @@ type dockerHubTagsResponse struct {
 	Next string `json:"next"`
 }
 
-func (c *Client) getDockerHubTags(repository, currentTag string) (*TagInfo, error) {
-	// Handle official images (e.g., "postgres" -> "library/postgres")
-	if !strings.Contains(repository, "/") {
-		repository = "library/" + repository
+// NormalizeDockerRepo splits a Docker Hub repository into its namespace and
+// name. Official images ("nginx" or "library/nginx") are in the "library"
+// namespace. The API, cache keys, and links all use this so they agree.
+func NormalizeDockerRepo(repository string) (namespace, name string, official bool) {
+	namespace, name, ok := strings.Cut(repository, "/")
+	if !ok {
+		return "library", repository, true
 	}
+	return namespace, name, namespace == "library"
+}
+
+// DefaultDockerHubMaxPages is how many pages of 100 tags are read per Docker
+// Hub repository by default. Tags are requested newest first, so this is
+// enough to reach the latest release of busy repositories such as
+// library/postgres.
+const DefaultDockerHubMaxPages = 5
 
-	url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags?page_size=100", repository)
+// SetDockerHubMaxPages sets how many pages of tags are read per Docker Hub
+// repository. Values below 1 read only the first page.
+func (c *Client) SetDockerHubMaxPages(n int) {
+	c.dockerHubMaxPages = n
+}
+
+func (c *Client) getDockerHubTags(ctx context.Context, repository, currentTag string) (*TagInfo, error) {
+	namespace, name, _ := NormalizeDockerRepo(repository)
+	repository = namespace + "/" + name
 
-	req, err := http.NewRequest("GET", url, nil)
+	if err := c.loginDockerHubOnce(ctx); err != nil {
+		return nil, fmt.Errorf("Docker Hub login: %w", err)
+	}
+
+	// Recently pushed tags first, so the page cap cuts off old tags only
+	pageURL := fmt.Sprintf("%s/v2/repositories/%s/tags?page_size=100&ordering=last_updated", c.dockerHubURL, repository)
+
+	// The request timeout applies to the whole listing, not to each page
+	hub, err := url.Parse(pageURL)
 	if err != nil {
 		return nil, err
 	}
+	if timeout := c.timeoutFor(hub.Host); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var tags []string
+	maxPages := max(c.dockerHubMaxPages, 1)
+	for page := 0; pageURL != ""; page++ {
+		if page == maxPages {
+			debugf("Docker Hub tags of %s: read the first %d tags, older ones are ignored", repository, len(tags))
+			break
+		}
+		results, next, err := c.getDockerHubTagsPage(ctx, pageURL)
+		if err != nil {
+			// Later pages only hold older tags, so what was read so far is
+			// still a useful answer
+			if len(tags) > 0 && (errors.Is(err, ErrRateLimit) || ctx.Err() != nil) {
+				debugf("Docker Hub tags of %s: stopped after %d pages: %v", repository, page, err)
+				break
+			}
+			return nil, err
+		}
+		tags = append(tags, results...)
+		pageURL = next
+	}
+
+	latest, latestAny := SelectLatest(tags, currentTag, c.onlySemver)
+
+	return &TagInfo{
+		Name:      repository,
+		Latest:    latest,
+		LatestAny: latestAny,
+		AllTags:   tags,
+	}, nil
+}
+
+// getDockerHubTagsPage fetches one page of a Docker Hub tag listing and
+// returns its tag names and the URL of the next page, if any
+func (c *Client) getDockerHubTagsPage(ctx context.Context, pageURL string) ([]string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if token := c.hubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return nil, ErrRateLimit
+		return nil, "", rateLimitError(resp)
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Docker Hub API returned status %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("Docker Hub API returned status %d", resp.StatusCode)
 	}
 
 	var tagsResp dockerHubTagsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	tags := make([]string, 0, len(tagsResp.Results))
@@ -98,13 +307,20 @@ func (c *Client) getDockerHubTags(repository, currentTag string) (*TagInfo, erro
 		tags = append(tags, t.Name)
 	}
 
-	latest := findLatestTag(tags, currentTag)
+	return tags, nextDockerHubPage(req.URL, tagsResp.Next), nil
+}
 
-	return &TagInfo{
-		Name:    repository,
-		Latest:  latest,
-		AllTags: tags,
-	}, nil
+// nextDockerHubPage resolves the "next" link of a tag listing page. Links to
+// another host are not followed, so the Hub token is never sent elsewhere.
+func nextDockerHubPage(current *url.URL, next string) string {
+	if next == "" {
+		return ""
+	}
+	u, err := current.Parse(next)
+	if err != nil || u.Host != current.Host {
+		return ""
+	}
+	return u.String()
 }
 
 // Quay.io API response structures
@@ -114,22 +330,22 @@ type quayTagsResponse struct {
 	} `json:"tags"`
 }
 
-func (c *Client) getQuayTags(repository, currentTag string) (*TagInfo, error) {
+func (c *Client) getQuayTags(ctx context.Context, repository, currentTag string) (*TagInfo, error) {
 	url := fmt.Sprintf("https://quay.io/api/v1/repository/%s/tag/?limit=100", repository)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return nil, ErrRateLimit
+		return nil, rateLimitError(resp)
 	}
 
 	if resp.StatusCode != 200 {
@@ -146,51 +362,37 @@ func (c *Client) getQuayTags(repository, currentTag string) (*TagInfo, error) {
 		tags = append(tags, t.Name)
 	}
 
-	latest := findLatestTag(tags, currentTag)
+	latest, latestAny := SelectLatest(tags, currentTag, c.onlySemver)
 
 	return &TagInfo{
-		Name:    repository,
-		Latest:  latest,
-		AllTags: tags,
+		Name:      repository,
+		Latest:    latest,
+		LatestAny: latestAny,
+		AllTags:   tags,
 	}, nil
 }
 
 // OCI Registry API response structures (used by ghcr.io, gcr.io, registry.k8s.io)
 type ociTokenResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
 }
 
 type ociTagsResponse struct {
 	Tags []string `json:"tags"`
 }
 
-func (c *Client) getOCITags(registry, repository, currentTag string) (*TagInfo, error) {
-	// Step 1: Get anonymous token
-	token, err := c.getOCIToken(registry, repository)
-	if err != nil {
-		return nil, err
-	}
-
-	// Step 2: List tags using the token
+func (c *Client) getOCITags(ctx context.Context, registry, repository, currentTag string) (*TagInfo, error) {
 	url := fmt.Sprintf("https://%s/v2/%s/tags/list", registry, repository)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doOCI(ctx, "GET", url, registry, repository, "")
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return nil, ErrRateLimit
+		return nil, rateLimitError(resp)
 	}
 
 	if resp.StatusCode == 401 {
@@ -206,68 +408,298 @@ func (c *Client) getOCITags(registry, repository, currentTag string) (*TagInfo,
 		return nil, err
 	}
 
-	latest := findLatestTag(tagsResp.Tags, currentTag)
+	latest, latestAny := SelectLatest(tagsResp.Tags, currentTag, c.onlySemver)
 
 	return &TagInfo{
-		Name:    repository,
-		Latest:  latest,
-		AllTags: tagsResp.Tags,
+		Name:      repository,
+		Latest:    latest,
+		LatestAny: latestAny,
+		AllTags:   tagsResp.Tags,
 	}, nil
 }
 
-func (c *Client) getOCIToken(registry, repository string) (string, error) {
-	// Different registries have different token endpoints
-	var tokenURL string
+// doOCI performs a request against an OCI registry. It first tries
+// anonymously; registries answer 401 with an auth challenge, in which case a
+// token is fetched from the advertised realm and the request retried.
+// Stored credentials for the registry are sent to the token realm, or
+// directly for registries with a Basic challenge.
+func (c *Client) doOCI(ctx context.Context, method, url, registry, repository, accept string) (*http.Response, error) {
+	resp, err := c.requestOCI(ctx, method, url, "", accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 401 {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
 
-	switch registry {
-	case "ghcr.io":
-		tokenURL = fmt.Sprintf("https://ghcr.io/token?scope=repository:%s:pull", repository)
-	case "gcr.io":
-		tokenURL = fmt.Sprintf("https://gcr.io/v2/token?scope=repository:%s:pull", repository)
-	case "registry.k8s.io":
-		// registry.k8s.io may not require a token for public images, try without
-		return "", nil
-	default:
+	creds := c.credentialsFor(registry)
+	if scheme, _ := parseWWWAuthenticate(challenge); creds != nil && strings.EqualFold(scheme, "Basic") {
+		return c.requestOCI(ctx, method, url, basicAuth(creds), accept)
+	}
+
+	token, err := c.getOCIToken(ctx, challenge, repository, creds)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%s requires authentication", registry)
+	}
+
+	return c.requestOCI(ctx, method, url, "Bearer "+token, accept)
+}
+
+// basicAuth returns the Authorization header value for creds
+func basicAuth(creds *Credentials) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password))
+}
+
+// requestOCI performs a single registry request, with an Authorization
+// header value (e.g. "Bearer <token>") if given
+func (c *Client) requestOCI(ctx context.Context, method, url, authorization, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	return c.do(req)
+}
+
+// manifestAccept lists the manifest media types a digest lookup accepts,
+// so multi-arch images report the digest of their index
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// GetDigest returns the manifest digest of an image tag, e.g. "sha256:..."
+func (c *Client) GetDigest(ctx context.Context, registry, repository, tag string) (string, error) {
+	release := c.acquire(registry)
+	defer release()
+
+	host := registry
+	if mirror, repo, ok := c.mirrorFor(registry, repository); ok {
+		host, repository = mirror, repo
+	} else if registry == "docker.io" || registry == "" {
+		// Docker Hub serves the registry API from a separate host
+		host = "registry-1.docker.io"
+		if c.dockerHubRegistry != "" {
+			host = c.dockerHubRegistry
+		}
+		namespace, name, _ := NormalizeDockerRepo(repository)
+		repository = namespace + "/" + name
+	}
+	lookupCtx, cancel := c.lookupContext(ctx)
+	defer cancel()
+
+	digest, err := c.getManifestDigest(lookupCtx, host, repository, tag)
+	return digest, c.lookupError(ctx, lookupCtx, err)
+}
+
+// getManifestDigest reads the Docker-Content-Digest header from a HEAD on the manifest
+func (c *Client) getManifestDigest(ctx context.Context, host, repository, tag string) (string, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, tag)
+
+	resp, err := c.doOCI(ctx, "HEAD", url, host, repository, manifestAccept)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return "", rateLimitError(resp)
+	}
+
+	if resp.StatusCode == 401 {
+		return "", fmt.Errorf("%s requires authentication", host)
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("%s manifest returned status %d", host, resp.StatusCode)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("%s did not report a manifest digest", host)
+	}
+	return digest, nil
+}
+
+// getOCIToken requests a pull token from the realm advertised in a
+// WWW-Authenticate challenge, anonymously or with creds if not nil. Returns
+// an empty token if the challenge is not a usable Bearer challenge.
+func (c *Client) getOCIToken(ctx context.Context, challenge, repository string, creds *Credentials) (string, error) {
+	scheme, params := parseWWWAuthenticate(challenge)
+	if !strings.EqualFold(scheme, "Bearer") || params["realm"] == "" {
 		return "", nil
 	}
 
-	req, err := http.NewRequest("GET", tokenURL, nil)
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+	}
+	query.Set("scope", scope)
+
+	tokenURL := params["realm"]
+	if strings.Contains(tokenURL, "?") {
+		tokenURL += "&" + query.Encode()
+	} else {
+		tokenURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tokenURL, nil)
 	if err != nil {
 		return "", err
 	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return "", ErrRateLimit
+		return "", rateLimitError(resp)
 	}
 
 	if resp.StatusCode != 200 {
-		// Token endpoint failed, try without token
-		return "", nil
+		return "", nil // Token endpoint refused anonymous access
 	}
 
 	var tokenResp ociTokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return "", nil // Ignore decode errors, try without token
+		return "", nil // Ignore decode errors, caller reports auth failure
+	}
+
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+	return tokenResp.AccessToken, nil
+}
+
+// parseWWWAuthenticate parses a challenge such as
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/app:pull"`
+// into its scheme and lowercased parameter map.
+func parseWWWAuthenticate(header string) (string, map[string]string) {
+	params := make(map[string]string)
+
+	header = strings.TrimSpace(header)
+	scheme, rest, _ := strings.Cut(header, " ")
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, `"`) {
+			// Quoted value, may contain commas
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(value)
+		}
 	}
 
-	return tokenResp.Token, nil
+	return scheme, params
 }
 
 // semverRegex matches semantic version patterns
 var semverRegex = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
 
+// strictSemverRegex matches clean release versions like "1.2.3" or "v1.2.3"
+var strictSemverRegex = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// useStrictSemver reports whether only clean release versions are considered
+// when looking for a newer tag than currentTag. onlySemver applies strict
+// matching to every tag, not just clean release versions.
+func useStrictSemver(currentTag string, onlySemver bool) bool {
+	return onlySemver || strictSemverRegex.MatchString(currentTag)
+}
+
+// SelectLatest returns the latest stable tag matching the style of the
+// current tag, and the latest tag of that style including pre-releases.
+// With onlySemver, only clean release versions are considered for any tag.
+func SelectLatest(tags []string, currentTag string, onlySemver bool) (stable, latestAny string) {
+	stable = findLatestTag(tags, currentTag, onlySemver)
+	latestAny = findLatestAnyTag(tags, currentTag, onlySemver)
+
+	// A pre-release only counts if it is newer than the stable tag
+	if latestAny == "" || (stable != "" && compareSemver(latestAny, stable) <= 0) {
+		latestAny = stable
+	}
+	return stable, latestAny
+}
+
+// findLatestAnyTag finds the latest semver tag, including pre-releases,
+// that matches the v-prefix style of the current tag
+func findLatestAnyTag(tags []string, currentTag string, onlySemver bool) string {
+	if !semverRegex.MatchString(currentTag) {
+		return ""
+	}
+
+	hasVPrefix := strings.HasPrefix(currentTag, "v")
+	strict := useStrictSemver(currentTag, onlySemver)
+	variant := isVariant(currentTag)
+
+	candidates := []string{}
+	for _, tag := range tags {
+		// Strict mode still reports pre-releases, but not variants like "1.2.3-alpine"
+		if strict && !strictSemverRegex.MatchString(tag) && !isPreRelease(tag) {
+			continue
+		}
+		if variant && variantShape(tag) != variantShape(currentTag) {
+			continue
+		}
+		if semverRegex.MatchString(tag) && strings.HasPrefix(tag, "v") == hasVPrefix {
+			candidates = append(candidates, tag)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Sort(sort.Reverse(semverSlice(candidates)))
+	return candidates[0]
+}
+
 // findLatestTag finds the latest tag that matches the pattern of the current tag
-func findLatestTag(tags []string, currentTag string) string {
+func findLatestTag(tags []string, currentTag string, onlySemver bool) string {
 	if len(tags) == 0 {
 		return ""
 	}
 
+	if useStrictSemver(currentTag, onlySemver) {
+		return findLatestStrictTag(tags, currentTag)
+	}
+
 	// Determine the type of current tag
 	currentMatch := semverRegex.FindStringSubmatch(currentTag)
 
@@ -285,19 +717,41 @@ func findLatestTag(tags []string, currentTag string) string {
 	// Check if current tag has 'v' prefix
 	hasVPrefix := strings.HasPrefix(currentTag, "v")
 
-	// Filter tags that match the same pattern (v prefix or not) and exclude pre-releases
+	// Someone on a stable release is not told to move to a release
+	// candidate or a variant: suffixed tags ("1.2.0-rc1", "1.2.0-alpine")
+	// are only candidates when the current tag has a suffix too, and
+	// pre-releases only when it is a pre-release itself. Someone on a
+	// variant stays on it: only tags of the same variant shape count
+	suffixed := strings.Contains(currentTag, "-")
+	preRelease := isPreRelease(currentTag)
+	variant := isVariant(currentTag)
+
+	// Filter tags that match the same pattern (v prefix or not)
 	matchingTags := []string{}
 	for _, tag := range tags {
-		if semverRegex.MatchString(tag) && !isPreRelease(tag) {
-			tagHasV := strings.HasPrefix(tag, "v")
-			if tagHasV == hasVPrefix {
-				matchingTags = append(matchingTags, tag)
-			}
+		if !semverRegex.MatchString(tag) {
+			continue
+		}
+		if (strings.Contains(tag, "-") && !suffixed) || (isPreRelease(tag) && !preRelease) {
+			continue
+		}
+		if variant && variantShape(tag) != variantShape(currentTag) {
+			continue
+		}
+		tagHasV := strings.HasPrefix(tag, "v")
+		if tagHasV == hasVPrefix {
+			matchingTags = append(matchingTags, tag)
 		}
 	}
 
+	// Nothing comparable: only vouch for the current tag if the registry has it
 	if len(matchingTags) == 0 {
-		return currentTag
+		for _, tag := range tags {
+			if tag == currentTag {
+				return currentTag
+			}
+		}
+		return ""
 	}
 
 	// Sort by semver and return highest
@@ -305,6 +759,53 @@ func findLatestTag(tags []string, currentTag string) string {
 	return matchingTags[0]
 }
 
+// isVariant reports whether a version tag has a suffix that is not a
+// pre-release, like "1.25.0-alpine" or "7.2.0-debian-12-r0"
+func isVariant(tag string) bool {
+	return semverRegex.MatchString(tag) && strings.Contains(tag, "-") && !isPreRelease(tag)
+}
+
+// variantShape returns the suffix after a tag's version with its numbers
+// masked, so "7.2.0-debian-12-r0" and "8.0.2-debian-12-r3" share a shape
+// while "1.25.0" and "1.25.0-alpine" don't
+func variantShape(tag string) string {
+	suffix := tag[len(semverRegex.FindString(tag)):]
+	return digitsRegex.ReplaceAllString(suffix, "0")
+}
+
+var digitsRegex = regexp.MustCompile(`\d+`)
+
+// findLatestStrictTag finds the highest clean release version, ignoring
+// date, branch, and variant tags. It never falls back to an arbitrary tag.
+func findLatestStrictTag(tags []string, currentTag string) string {
+	// Match the v-prefix style of the current tag when it is a version
+	matchPrefix := semverRegex.MatchString(currentTag)
+	hasVPrefix := strings.HasPrefix(currentTag, "v")
+
+	candidates := []string{}
+	for _, tag := range tags {
+		if !strictSemverRegex.MatchString(tag) {
+			continue
+		}
+		if matchPrefix && strings.HasPrefix(tag, "v") != hasVPrefix {
+			continue
+		}
+		candidates = append(candidates, tag)
+	}
+
+	if len(candidates) == 0 {
+		for _, tag := range tags {
+			if tag == currentTag {
+				return currentTag
+			}
+		}
+		return ""
+	}
+
+	sort.Sort(sort.Reverse(semverSlice(candidates)))
+	return candidates[0]
+}
+
 // preReleaseSuffixes contains common pre-release version suffixes to filter out
 var preReleaseSuffixes = []string{
 	"-dev", "-alpha", "-beta", "-rc", "-RC",
@@ -342,31 +843,113 @@ func isSimpleVersion(tag string) bool {
 // semverSlice implements sort.Interface for semver-like strings
 type semverSlice []string
 
-func (s semverSlice) Len() int      { return len(s) }
-func (s semverSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
-func (s semverSlice) Less(i, j int) bool {
-	return compareSemver(s[i], s[j]) < 0
-}
+func (s semverSlice) Len() int           { return len(s) }
+func (s semverSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s semverSlice) Less(i, j int) bool { return compareSemver(s[i], s[j]) < 0 }
 
+// semverPartsRegex splits a version into up to three numbers, pre-release
+// identifiers, and build metadata, e.g. "v1.2.0-rc.1+build.5"
+var semverPartsRegex = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]*))?(?:\+[0-9A-Za-z.-]*)?`)
+
+// compareSemver orders versions by semver 2.0.0 precedence: numbers first,
+// missing ones counting as 0, then a pre-release before its release
+// ("1.2.0-rc1" < "1.2.0"). Build metadata is ignored. Returns a negative
+// number if a < b, 0 if equal, and a positive number if a > b. Versions that
+// don't parse are compared as strings.
 func compareSemver(a, b string) int {
-	matchA := semverRegex.FindStringSubmatch(a)
-	matchB := semverRegex.FindStringSubmatch(b)
+	matchA := semverPartsRegex.FindStringSubmatch(a)
+	matchB := semverPartsRegex.FindStringSubmatch(b)
 
 	if matchA == nil || matchB == nil {
 		return strings.Compare(a, b)
 	}
 
 	for i := 1; i <= 3; i++ {
-		var numA, numB int
-		if i < len(matchA) && matchA[i] != "" {
-			fmt.Sscanf(matchA[i], "%d", &numA)
+		if c := compareNumeric(matchA[i], matchB[i]); c != 0 {
+			return c
 		}
-		if i < len(matchB) && matchB[i] != "" {
-			fmt.Sscanf(matchB[i], "%d", &numB)
+	}
+	return comparePreRelease(matchA[4], matchB[4])
+}
+
+// compareNumeric compares two strings of digits by value, without
+// overflowing on long ones such as date stamps. Empty counts as 0.
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return cmp.Compare(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// comparePreRelease compares dot-separated pre-release identifiers. A
+// version without any has higher precedence; numeric identifiers compare
+// numerically and sort before alphanumeric ones, which compare with
+// compareIdentifier; a shorter list of otherwise equal identifiers sorts first.
+func comparePreRelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		numA, numB := isNumeric(idsA[i]), isNumeric(idsB[i])
+		switch {
+		case numA && numB:
+			if c := compareNumeric(idsA[i], idsB[i]); c != 0 {
+				return c
+			}
+		case numA:
+			return -1
+		case numB:
+			return 1
+		default:
+			if c := compareIdentifier(idsA[i], idsB[i]); c != 0 {
+				return c
+			}
 		}
-		if numA != numB {
-			return numA - numB
+	}
+	return cmp.Compare(len(idsA), len(idsB))
+}
+
+// compareIdentifier compares alphanumeric pre-release identifiers in ASCII
+// order, except that a shared prefix followed by digits compares by number,
+// so "rc9" < "rc10" as tags like "-rc9" and "-rc10" intend. Strict semver
+// would order these as strings.
+func compareIdentifier(a, b string) int {
+	prefixA, numA := splitTrailingNumber(a)
+	prefixB, numB := splitTrailingNumber(b)
+	if prefixA == prefixB && numA != "" && numB != "" {
+		return compareNumeric(numA, numB)
+	}
+	return strings.Compare(a, b)
+}
+
+// splitTrailingNumber splits "rc10" into "rc" and "10"
+func splitTrailingNumber(s string) (prefix, number string) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	return s[:i], s[i:]
+}
+
+// isNumeric reports whether s is a non-empty string of ASCII digits
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
 		}
 	}
-	return 0
+	return true
 }
@@ -1,7 +1,24 @@
 package registry
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestFindLatestTag(t *testing.T) {
@@ -47,12 +64,60 @@ func TestFindLatestTag(t *testing.T) {
 			currentTag: "1.0.0",
 			want:       "1.1.5", // Pre-release versions (rc, alpha, beta, dev) are filtered out
 		},
+		{
+			name:       "stable release preferred over its pre-release and variants",
+			tags:       []string{"1.1.0", "1.2.0-rc1", "1.2.0-alpine", "1.2.0"},
+			currentTag: "1.1.0",
+			want:       "1.2.0",
+		},
+		{
+			name:       "stable current ignores suffixed tags",
+			tags:       []string{"1.0", "1.1", "1.2-alpine", "1.3-rc1"},
+			currentTag: "1.0",
+			want:       "1.1",
+		},
+		{
+			name:       "variant current considers variants but not pre-releases",
+			tags:       []string{"1.0-alpine", "1.1-alpine", "1.2-rc1-alpine"},
+			currentTag: "1.0-alpine",
+			want:       "1.1-alpine",
+		},
+		{
+			name:       "variant current stays on its variant",
+			tags:       []string{"1.25.0-alpine", "1.26.0", "1.27.0-alpine", "1.27.0", "1.27.0-bookworm"},
+			currentTag: "1.25.0-alpine",
+			want:       "1.27.0-alpine",
+		},
+		{
+			name:       "bitnami variant current stays on its variant",
+			tags:       []string{"7.2.0-debian-12-r0", "8.0.2", "8.0.2-debian-12-r3", "8.0.2-alpine"},
+			currentTag: "7.2.0-debian-12-r0",
+			want:       "8.0.2-debian-12-r3",
+		},
+		{
+			name:       "pre-release current considers newer pre-releases",
+			tags:       []string{"1.0-rc1", "1.0-rc2", "0.9"},
+			currentTag: "1.0-rc1",
+			want:       "1.0-rc2",
+		},
+		{
+			name:       "pre-release current moves to its release",
+			tags:       []string{"1.0-rc1", "1.0-rc2", "1.0"},
+			currentTag: "1.0-rc1",
+			want:       "1.0",
+		},
 		{
 			name:       "empty tags list",
 			tags:       []string{},
 			currentTag: "1.0.0",
 			want:       "",
 		},
+		{
+			name:       "current tag not in registry",
+			tags:       []string{"latest", "stable"},
+			currentTag: "1.0.0",
+			want:       "",
+		},
 		{
 			name:       "non-semver current tag",
 			tags:       []string{"latest", "v1.0.0", "v2.0.0", "stable"},
@@ -63,7 +128,7 @@ func TestFindLatestTag(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := findLatestTag(tt.tags, tt.currentTag)
+			got := findLatestTag(tt.tags, tt.currentTag, false)
 			if got != tt.want {
 				t.Errorf("findLatestTag() = %q, want %q", got, tt.want)
 			}
@@ -71,6 +136,64 @@ func TestFindLatestTag(t *testing.T) {
 	}
 }
 
+func TestFindLatestTag_OnlySemver(t *testing.T) {
+	tests := []struct {
+		name       string
+		onlySemver bool
+		tags       []string
+		currentTag string
+		want       string
+	}{
+		{
+			name:       "semver current ignores variant and date tags",
+			tags:       []string{"1.0.0", "1.1.0", "1.2.0-alpine", "20240101", "main"},
+			currentTag: "1.0.0",
+			want:       "1.1.0",
+		},
+		{
+			name:       "semver current with only non-version tags",
+			tags:       []string{"main", "nightly-2024", "20240101"},
+			currentTag: "1.0.0",
+			want:       "",
+		},
+		{
+			name:       "opt-in never falls back to first tag",
+			onlySemver: true,
+			tags:       []string{"latest", "main", "stable"},
+			currentTag: "latest",
+			want:       "latest",
+		},
+		{
+			name:       "opt-in with unknown current tag",
+			onlySemver: true,
+			tags:       []string{"main", "stable", "20240101"},
+			currentTag: "edge",
+			want:       "",
+		},
+		{
+			name:       "opt-in picks clean release for non-semver current",
+			onlySemver: true,
+			tags:       []string{"branch-x", "1.4.0", "2.0.0-debian", "1.9.9"},
+			currentTag: "latest",
+			want:       "1.9.9",
+		},
+		{
+			name:       "default keeps first-tag fallback for non-semver current",
+			tags:       []string{"main", "stable"},
+			currentTag: "latest",
+			want:       "main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findLatestTag(tt.tags, tt.currentTag, tt.onlySemver); got != tt.want {
+				t.Errorf("findLatestTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCompareSemver(t *testing.T) {
 	tests := []struct {
 		a, b string
@@ -84,6 +207,22 @@ func TestCompareSemver(t *testing.T) {
 		{"v1.0.0", "v2.0.0", -1},
 		{"10.0.0", "9.0.0", 1},
 		{"1.10.0", "1.9.0", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.2.0-rc1", "1.2.0", -1},
+		{"1.2.0", "1.2.0-rc1", 1},
+		{"1.2.0-alpha", "1.2.0-beta", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"1.0.0-rc1+build", "1.0.0", -1},
+		{"1.2.0-rc1", "1.2.0-rc2", -1},
+		{"1.2.0-rc9", "1.2.0-rc10", -1},
+		{"1.2.0-rc10", "1.2.0-rc10", 0},
+		{"1.2.0-rc2", "1.2.0-beta10", 1},
+		{"v1.2.0-rc1", "v1.2.0", -1},
+		{"20240101000000000000.0.0", "9999.0.0", 1},
 	}
 
 	for _, tt := range tests {
@@ -102,6 +241,66 @@ func TestCompareSemver(t *testing.T) {
 	}
 }
 
+func TestCompareSemver_Precedence(t *testing.T) {
+	// Ascending precedence, from the semver 2.0.0 spec
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0",
+	}
+
+	for i := 0; i+1 < len(ordered); i++ {
+		a, b := ordered[i], ordered[i+1]
+		if got := compareSemver(a, b); got >= 0 {
+			t.Errorf("compareSemver(%q, %q) = %d, want < 0", a, b, got)
+		}
+		if got := compareSemver(b, a); got <= 0 {
+			t.Errorf("compareSemver(%q, %q) = %d, want > 0", b, a, got)
+		}
+	}
+}
+
+func TestParseBump(t *testing.T) {
+	for _, b := range []Bump{BumpPatch, BumpMinor, BumpMajor} {
+		if got, ok := ParseBump(b.String()); !ok || got != b {
+			t.Errorf("ParseBump(%q) = %v, %v", b.String(), got, ok)
+		}
+	}
+	if _, ok := ParseBump("huge"); ok {
+		t.Error("ParseBump(\"huge\") succeeded, want an error")
+	}
+}
+
+func TestBumpLevel(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            Bump
+	}{
+		{"1.2.3", "2.0.0", BumpMajor},
+		{"1.2.3", "1.4.0", BumpMinor},
+		{"1.2.3", "1.2.9", BumpPatch},
+		{"v1.2.3", "v1.3.0", BumpMinor},
+		{"1.28", "1.37.0-glibc", BumpMinor},
+		{"410", "479", BumpMajor},
+		{"trino-410", "trino-479", BumpMajor},
+		{"1.2.3", "1.2.3", BumpNone},
+		{"2.0.0", "1.9.0", BumpNone},
+		{"1.25.0", "1.25.0-alpine", BumpNone},
+		{"20240101000000000000", "20250101000000000000", BumpMajor}, // Beyond int64
+		{"1.99999999999999999999", "1.100000000000000000000", BumpMinor},
+		{"1.007", "1.10", BumpMinor},
+		{"latest", "1.0.0", BumpUnknown},
+		{"stable", "edge", BumpUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.current+"_to_"+tt.latest, func(t *testing.T) {
+			if got := BumpLevel(tt.current, tt.latest); got != tt.want {
+				t.Errorf("BumpLevel(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFilterSemverTags(t *testing.T) {
 	tests := []struct {
 		name string
@@ -159,3 +358,1871 @@ func TestMapUpstreamToRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWWWAuthenticate(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantScheme string
+		wantParams map[string]string
+	}{
+		{
+			name:       "ghcr challenge",
+			header:     `Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/app:pull"`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{
+				"realm":   "https://ghcr.io/token",
+				"service": "ghcr.io",
+				"scope":   "repository:org/app:pull",
+			},
+		},
+		{
+			name:       "missing scope",
+			header:     `Bearer realm="https://auth.example.com/token",service="registry.example.com"`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com",
+			},
+		},
+		{
+			name:       "harbor",
+			header:     `Bearer realm="https://harbor.internal.example.com/service/token",service="harbor-registry",scope="repository:team/api:pull"`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{
+				"realm":   "https://harbor.internal.example.com/service/token",
+				"service": "harbor-registry",
+				"scope":   "repository:team/api:pull",
+			},
+		},
+		{
+			name:       "quoted value with comma",
+			header:     `Bearer realm="https://auth.example.com/token",scope="repository:a:pull,push"`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{
+				"realm": "https://auth.example.com/token",
+				"scope": "repository:a:pull,push",
+			},
+		},
+		{
+			name:       "unquoted values",
+			header:     `Bearer realm=https://auth.example.com/token, service=example`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "example",
+			},
+		},
+		{
+			name:       "basic challenge",
+			header:     `Basic realm="Registry"`,
+			wantScheme: "Basic",
+			wantParams: map[string]string{"realm": "Registry"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, params := parseWWWAuthenticate(tt.header)
+			if scheme != tt.wantScheme {
+				t.Errorf("scheme = %q, want %q", scheme, tt.wantScheme)
+			}
+			if len(params) != len(tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+			for k, v := range tt.wantParams {
+				if params[k] != v {
+					t.Errorf("params[%q] = %q, want %q", k, params[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestGetOCIToken(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		fmt.Fprint(w, `{"access_token":"secret"}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		challenge string
+		wantToken string
+		wantQuery url.Values
+	}{
+		{
+			name:      "scope from challenge",
+			challenge: fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:org/app:pull"`, srv.URL),
+			wantToken: "secret",
+			wantQuery: url.Values{"service": {"registry"}, "scope": {"repository:org/app:pull"}},
+		},
+		{
+			name:      "missing scope pulls the repository",
+			challenge: fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, srv.URL),
+			wantToken: "secret",
+			wantQuery: url.Values{"service": {"registry"}, "scope": {"repository:team/api:pull"}},
+		},
+		{
+			name:      "realm with query",
+			challenge: fmt.Sprintf(`bearer Realm="%s/token?tenant=a"`, srv.URL),
+			wantToken: "secret",
+			wantQuery: url.Values{"tenant": {"a"}, "scope": {"repository:team/api:pull"}},
+		},
+		{
+			name:      "basic challenge",
+			challenge: `Basic realm="Registry"`,
+		},
+		{
+			name:      "no realm",
+			challenge: `Bearer service="registry"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery = nil
+			c := &Client{httpClient: srv.Client()}
+
+			token, err := c.getOCIToken(context.Background(), tt.challenge, "team/api", nil)
+			if err != nil {
+				t.Fatalf("getOCIToken() error = %v", err)
+			}
+			if token != tt.wantToken {
+				t.Errorf("token = %q, want %q", token, tt.wantToken)
+			}
+			if tt.wantQuery == nil {
+				if gotQuery != nil {
+					t.Errorf("token endpoint called with %v, want no request", gotQuery)
+				}
+				return
+			}
+			if gotQuery.Encode() != tt.wantQuery.Encode() {
+				t.Errorf("token query = %v, want %v", gotQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestGetOCITags_Challenge(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.URL.Query().Get("scope") != "repository:org/app:pull" {
+				t.Errorf("token scope = %q", r.URL.Query().Get("scope"))
+			}
+			if r.URL.Query().Get("service") != "test-registry" {
+				t.Errorf("token service = %q", r.URL.Query().Get("service"))
+			}
+			fmt.Fprint(w, `{"token":"secret"}`)
+		case "/v2/org/app/tags/list":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer realm="%s/token",service="test-registry",scope="repository:org/app:pull"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0","2.0.0"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client()}
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	info, err := c.getOCITags(context.Background(), host, "org/app", "1.0.0")
+	if err != nil {
+		t.Fatalf("getOCITags() error = %v", err)
+	}
+	if info.Latest != "2.0.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "2.0.0")
+	}
+}
+
+func TestGetDigest_ArtifactoryChallenge(t *testing.T) {
+	// Artifactory advertises a token endpoint without a scope
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/api/docker/docker-remote/v2/token":
+			if r.URL.Query().Get("scope") != "repository:team/api:pull" {
+				t.Errorf("token scope = %q, want a pull scope for the repository", r.URL.Query().Get("scope"))
+			}
+			fmt.Fprint(w, `{"token":"rt-token"}`)
+		case "/v2/team/api/manifests/2.1.0":
+			if r.Method != http.MethodHead {
+				t.Errorf("manifest requested with %s, want HEAD", r.Method)
+			}
+			if r.Header.Get("Authorization") != "Bearer rt-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+					`Bearer realm="%s/artifactory/api/docker/docker-remote/v2/token",service="%s"`, srv.URL, r.Host))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client()}
+	digest, err := c.GetDigest(context.Background(), host, "team/api", "2.1.0")
+	if err != nil {
+		t.Fatalf("GetDigest() error = %v", err)
+	}
+	if digest != "sha256:abc" {
+		t.Errorf("digest = %q, want sha256:abc", digest)
+	}
+}
+
+// hostRewriter sends every request to the test server at target, keeping
+// the path and query, so lookups against fixed hosts can be served locally
+type hostRewriter struct {
+	target    *url.URL
+	transport http.RoundTripper
+}
+
+func (h hostRewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = h.target.Scheme
+	req.URL.Host = h.target.Host
+	return h.transport.RoundTrip(req)
+}
+
+func TestGetLatestTag_RegistryK8sChallenge(t *testing.T) {
+	var tagRequests, tokenRequests int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if r.URL.Query().Get("scope") != "repository:ingress-nginx/controller:pull" {
+				t.Errorf("token scope = %q", r.URL.Query().Get("scope"))
+			}
+			fmt.Fprint(w, `{"token":"k8s-token"}`)
+		case "/v2/ingress-nginx/controller/tags/list":
+			tagRequests++
+			if r.Header.Get("Authorization") != "Bearer k8s-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="https://registry.k8s.io/token",service="registry.k8s.io",scope="repository:ingress-nginx/controller:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"tags":["v1.9.0","v1.10.0"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	c := New(Options{})
+	c.httpClient = &http.Client{Transport: hostRewriter{target, srv.Client().Transport}}
+
+	info, err := c.GetLatestTag(context.Background(), "registry.k8s.io", "ingress-nginx/controller", "v1.9.0")
+	if err != nil {
+		t.Fatalf("GetLatestTag() error = %v", err)
+	}
+	if info.Latest != "v1.10.0" {
+		t.Errorf("Latest = %q, want v1.10.0", info.Latest)
+	}
+	if tagRequests != 2 || tokenRequests != 1 {
+		t.Errorf("%d tags and %d token requests, want 2 and 1", tagRequests, tokenRequests)
+	}
+}
+
+func TestGetLatestTag_OnlySemverPerClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tags":["1.2.3","20240101","main"]}`)
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	newClient := func(onlySemver bool) *Client {
+		c := New(Options{OnlySemver: onlySemver})
+		c.httpClient = &http.Client{Transport: hostRewriter{target, srv.Client().Transport}}
+		return c
+	}
+	strict, loose := newClient(true), newClient(false)
+
+	// Clients with different settings don't affect each other
+	for _, tt := range []struct {
+		c    *Client
+		want string
+	}{
+		{strict, "1.2.3"},
+		{loose, "20240101"},
+		{strict, "1.2.3"},
+	} {
+		info, err := tt.c.GetLatestTag(context.Background(), "ghcr.io", "org/app", "latest")
+		if err != nil {
+			t.Fatalf("GetLatestTag() error = %v", err)
+		}
+		if info.Latest != tt.want {
+			t.Errorf("GetLatestTag() with OnlySemver %v = %q, want %q", tt.c.onlySemver, info.Latest, tt.want)
+		}
+	}
+}
+
+func TestGetLatestTag_GenericRegistry(t *testing.T) {
+	// Harbor-style token service on a path of the registry host
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/token":
+			if r.URL.Query().Get("service") != "harbor-registry" || r.URL.Query().Get("scope") != "repository:team/api:pull" {
+				t.Errorf("token request %s, want service and scope from the challenge", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"token":"harbor-token"}`)
+		case "/v2/team/api/tags/list":
+			if r.Header.Get("Authorization") != "Bearer harbor-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+					`Bearer realm="%s/service/token",service="harbor-registry",scope="repository:team/api:pull"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"name":"team/api","tags":["2.0.0","2.1.0","2.2.0-rc1"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client()}
+	info, err := c.GetLatestTag(context.Background(), host, "team/api", "2.0.0")
+	if err != nil {
+		t.Fatalf("GetLatestTag() error = %v", err)
+	}
+	if info.Latest != "2.1.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "2.1.0")
+	}
+
+	// A host that doesn't answer may just be down, not unsupported
+	srv.Close()
+	if _, err := c.GetLatestTag(context.Background(), host, "team/api", "2.0.0"); err == nil || errors.Is(err, ErrUnsupportedRegistry) {
+		t.Errorf("error for unreachable host = %v, want a network error", err)
+	}
+}
+
+func TestGetLatestTag_UnknownHostTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	client := srv.Client()
+	client.Timeout = 50 * time.Millisecond
+	c := &Client{httpClient: client}
+
+	_, err := c.GetLatestTag(context.Background(), host, "team/api", "2.0.0")
+	if err == nil {
+		t.Fatal("GetLatestTag() succeeded, want a timeout")
+	}
+	if errors.Is(err, ErrUnsupportedRegistry) {
+		t.Errorf("slow host reported as unsupported: %v", err)
+	}
+}
+
+func TestGetLatestTag_NotARegistry(t *testing.T) {
+	// A web server that answers, but not the registry API
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client()}
+	if _, err := c.GetLatestTag(context.Background(), host, "team/api", "2.0.0"); !errors.Is(err, ErrUnsupportedRegistry) {
+		t.Errorf("error for a host without /v2/ = %v, want ErrUnsupportedRegistry", err)
+	}
+}
+
+func TestGetOCITags_Anonymous(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("expected no Authorization header for anonymous registry")
+		}
+		fmt.Fprint(w, `{"tags":["v1.0.0","v1.2.0"]}`)
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client()}
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	info, err := c.getOCITags(context.Background(), host, "org/app", "v1.0.0")
+	if err != nil {
+		t.Fatalf("getOCITags() error = %v", err)
+	}
+	if info.Latest != "v1.2.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "v1.2.0")
+	}
+}
+
+const sampleHelmIndex = `apiVersion: v1
+entries:
+  postgresql:
+    - version: 16.0.0-rc1
+      appVersion: "17.0"
+    - version: 15.5.2
+      appVersion: "16.4"
+    - version: 15.10.0
+      appVersion: "16.6"
+    - version: 12.1.0
+      appVersion: "15.1"
+  redis:
+    - version: 20.0.0
+`
+
+func TestGetChartVersion_IndexFallback(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ArtifactHub doesn't know the chart
+		if strings.HasPrefix(r.URL.Path, "/api/v1/packages/search") {
+			fmt.Fprint(w, `{"packages":[]}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer hub.Close()
+
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/charts/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, sampleHelmIndex)
+	}))
+	defer repo.Close()
+
+	c := &Client{httpClient: http.DefaultClient, artifactHubURL: hub.URL}
+
+	info, err := c.GetChartVersion(context.Background(), "postgresql", "bitnami", repo.URL+"/charts/")
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "15.10.0" {
+		t.Errorf("LatestVersion = %q, want %q", info.LatestVersion, "15.10.0")
+	}
+	if info.AppVersion != "16.6" {
+		t.Errorf("AppVersion = %q, want %q", info.AppVersion, "16.6")
+	}
+
+	// Without a repository URL the ArtifactHub error is returned
+	if _, err := c.GetChartVersion(context.Background(), "postgresql", "bitnami", ""); err == nil {
+		t.Error("expected error without repository fallback")
+	}
+}
+
+func TestGetChartVersion_ArtifactHubUnreachable(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleHelmIndex)
+	}))
+	defer repo.Close()
+
+	c := &Client{httpClient: http.DefaultClient, artifactHubURL: "http://127.0.0.1:1"}
+
+	info, err := c.GetChartVersion(context.Background(), "redis", "bitnami", repo.URL)
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "20.0.0" {
+		t.Errorf("LatestVersion = %q, want %q", info.LatestVersion, "20.0.0")
+	}
+}
+
+func TestGetChartVersion_ArtifactHubRepoURL(t *testing.T) {
+	indexRequests := 0
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		indexRequests++
+		fmt.Fprint(w, sampleHelmIndex)
+	}))
+	defer repo.Close()
+
+	repoSearches := 0
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repositories/search":
+			repoSearches++
+			if r.URL.Query().Get("url") != repo.URL+"/charts" {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			fmt.Fprintf(w, `[{"name":"acme-fork","url":"https://fork.example.com/charts"},{"name":"acme","url":"%s/charts/"}]`, repo.URL)
+		case "/api/v1/packages/helm/acme/postgresql":
+			fmt.Fprint(w, `{"name":"postgresql","version":"16.0.0","app_version":"17.0"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer hub.Close()
+
+	c := &Client{httpClient: http.DefaultClient, artifactHubURL: hub.URL}
+
+	for range 2 {
+		info, err := c.GetChartVersion(context.Background(), "postgresql", "", repo.URL+"/charts/")
+		if err != nil {
+			t.Fatalf("GetChartVersion() error = %v", err)
+		}
+		if info.LatestVersion != "16.0.0" || info.AppVersion != "17.0" {
+			t.Errorf("GetChartVersion() = %s (app %s), want 16.0.0 (app 17.0) from ArtifactHub", info.LatestVersion, info.AppVersion)
+		}
+	}
+	if indexRequests != 0 {
+		t.Errorf("got %d index requests, want 0", indexRequests)
+	}
+	if repoSearches != 1 {
+		t.Errorf("got %d repository searches, want 1 (cached)", repoSearches)
+	}
+
+	// A chart the ArtifactHub repository lacks comes from the index
+	info, err := c.GetChartVersion(context.Background(), "redis", "", repo.URL+"/charts")
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "20.0.0" || indexRequests != 1 {
+		t.Errorf("GetChartVersion() = %s after %d index requests, want 20.0.0 after 1", info.LatestVersion, indexRequests)
+	}
+
+	// So does one from a repository ArtifactHub doesn't index
+	info, err = c.GetChartVersion(context.Background(), "redis", "", repo.URL)
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "20.0.0" {
+		t.Errorf("GetChartVersion() = %s, want 20.0.0", info.LatestVersion)
+	}
+}
+
+func TestGetChartVersion_OCI(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/charts/postgresql/tags/list" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"tags":["15.0.0","15.10.0","15.10.1_build.1","16.0.0-rc.1","sha256-abc.sig"]}`)
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	c := New(Options{})
+	c.artifactHubURL = "http://127.0.0.1:1"
+	c.httpClient = &http.Client{Transport: hostRewriter{target, srv.Client().Transport}}
+
+	info, err := c.GetChartVersion(context.Background(), "postgresql", "", "oci://registry.example.com/charts/")
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "15.10.1+build.1" || info.LatestAny != "16.0.0-rc.1" {
+		t.Errorf("GetChartVersion() = %s (any %s), want 15.10.1+build.1 (any 16.0.0-rc.1)", info.LatestVersion, info.LatestAny)
+	}
+
+	// Charts with an upstream fall back to the registry when ArtifactHub fails
+	info, err = c.GetChartVersion(context.Background(), "postgresql", "bitnami", "oci://registry.example.com/charts")
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "15.10.1+build.1" {
+		t.Errorf("LatestVersion = %q, want 15.10.1+build.1", info.LatestVersion)
+	}
+
+	if _, err := c.GetChartVersion(context.Background(), "missing", "", "oci://registry.example.com/charts"); err == nil {
+		t.Error("expected error for chart missing from the registry")
+	}
+}
+
+func TestGetChartVersionFromIndex(t *testing.T) {
+	requests := 0
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, sampleHelmIndex)
+	}))
+	defer repo.Close()
+
+	// An unreachable ArtifactHub leaves only the index
+	c := &Client{httpClient: http.DefaultClient, artifactHubURL: "http://127.0.0.1:1"}
+
+	info, err := c.GetChartVersion(context.Background(), "postgresql", "", repo.URL)
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "15.10.0" {
+		t.Errorf("LatestVersion = %q, want %q (highest stable)", info.LatestVersion, "15.10.0")
+	}
+	if requests != 1 {
+		t.Errorf("got %d index requests, want 1", requests)
+	}
+
+	if _, err := c.GetChartVersionFromIndex(context.Background(), repo.URL, "missing"); err == nil {
+		t.Error("expected error for chart missing from index")
+	}
+	if _, err := c.GetChartVersionFromIndex(context.Background(), repo.URL+"/nope", "postgresql"); err == nil {
+		t.Error("expected error for missing index.yaml")
+	}
+}
+
+func TestIsHelmRepoURL(t *testing.T) {
+	tests := []struct {
+		repository string
+		want       bool
+	}{
+		{"https://charts.bitnami.com/bitnami", true},
+		{"http://charts.internal", true},
+		{"oci://registry-1.docker.io/bitnamicharts", false},
+		{"file://../common", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			if got := IsHelmRepoURL(tt.repository); got != tt.want {
+				t.Errorf("IsHelmRepoURL(%q) = %v, want %v", tt.repository, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectLatest(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags       []string
+		currentTag string
+		wantStable string
+		wantAny    string
+	}{
+		{
+			name:       "newer rc available",
+			tags:       []string{"1.3.0", "1.4.0", "1.5.0-rc1"},
+			currentTag: "1.3.0",
+			wantStable: "1.4.0",
+			wantAny:    "1.5.0-rc1",
+		},
+		{
+			name:       "rc superseded by release",
+			tags:       []string{"1.4.0", "1.5.0-rc1", "1.5.0"},
+			currentTag: "1.4.0",
+			wantStable: "1.5.0",
+			wantAny:    "1.5.0",
+		},
+		{
+			name:       "older rc ignored",
+			tags:       []string{"1.4.0-beta", "1.4.0", "2.0.0"},
+			currentTag: "1.4.0",
+			wantStable: "2.0.0",
+			wantAny:    "2.0.0",
+		},
+		{
+			name:       "numeric pre-release identifiers",
+			tags:       []string{"1.0.0-rc.2", "1.0.0-rc.10", "1.0.0-rc.9"},
+			currentTag: "1.0.0-rc.2",
+			wantStable: "1.0.0-rc.10",
+			wantAny:    "1.0.0-rc.10",
+		},
+		{
+			name:       "variant current",
+			tags:       []string{"7.2.0-debian-12-r0", "8.0.2", "8.0.2-debian-12-r3", "8.1.0-rc1"},
+			currentTag: "7.2.0-debian-12-r0",
+			wantStable: "8.0.2-debian-12-r3",
+			wantAny:    "8.0.2-debian-12-r3",
+		},
+		{
+			name:       "v prefix style kept",
+			tags:       []string{"v1.0.0", "v1.1.0-alpha", "2.0.0-alpha"},
+			currentTag: "v1.0.0",
+			wantStable: "v1.0.0",
+			wantAny:    "v1.1.0-alpha",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stable, latestAny := SelectLatest(tt.tags, tt.currentTag, false)
+			if stable != tt.wantStable {
+				t.Errorf("stable = %q, want %q", stable, tt.wantStable)
+			}
+			if latestAny != tt.wantAny {
+				t.Errorf("latestAny = %q, want %q", latestAny, tt.wantAny)
+			}
+		})
+	}
+}
+
+func TestReadDockerConfigCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("me:secret"))
+	config := `{"auths": {"https://index.docker.io/v1/": {"auth": "` + auth + `"}}}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := readDockerConfigCredentials(path)
+	if err != nil {
+		t.Fatalf("readDockerConfigCredentials() error = %v", err)
+	}
+	if creds.Username != "me" || creds.Password != "secret" {
+		t.Errorf("credentials = %+v, want me/secret", creds)
+	}
+
+	if _, err := readDockerConfigCredentials(filepath.Join(dir, "missing.json")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("missing config error = %v, want ErrNoCredentials", err)
+	}
+}
+
+func TestParseDockerConfigAuths(t *testing.T) {
+	enc := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	config := `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "` + enc("hub:hubpass") + `"},
+		"ghcr.io": {"auth": "` + enc("octocat:ghp_token:with:colons") + `"},
+		"https://registry.example.com:5000/v2/": {"auth": "` + enc("bot:pw") + `"},
+		"quay.io": {},
+		"broken.example.com": {"auth": "` + enc("no-colon") + `"}
+	}, "credsStore": "desktop"}`
+
+	auths, err := parseDockerConfigAuths([]byte(config))
+	if err != nil {
+		t.Fatalf("parseDockerConfigAuths() error = %v", err)
+	}
+
+	want := map[string]Credentials{
+		"docker.io":                 {"hub", "hubpass"},
+		"ghcr.io":                   {"octocat", "ghp_token:with:colons"},
+		"registry.example.com:5000": {"bot", "pw"},
+	}
+	if len(auths) != len(want) {
+		t.Errorf("got %d entries, want %d: %v", len(auths), len(want), auths)
+	}
+	for host, w := range want {
+		if got := auths[host]; got == nil || *got != w {
+			t.Errorf("auths[%q] = %+v, want %+v", host, got, w)
+		}
+	}
+
+	if _, err := parseDockerConfigAuths([]byte(`{"auths": {"ghcr.io": {"auth": "not base64!"}}}`)); err == nil {
+		t.Error("expected an error for an invalid base64 auth field")
+	}
+}
+
+func TestGetOCITags_DockerConfig(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			// Only authenticated users get a token for the private repository
+			if user, pass, ok := r.BasicAuth(); !ok || user != "bot" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token":"private-token"}`)
+		case r.Header.Get("Authorization") != "Bearer private-token":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0"]}`)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	writeConfig := func(registry string) string {
+		path := filepath.Join(t.TempDir(), "config.json")
+		auth := base64.StdEncoding.EncodeToString([]byte("bot:secret"))
+		config := `{"auths": {"` + registry + `": {"auth": "` + auth + `"}}}`
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	c := &Client{httpClient: srv.Client()}
+	if err := c.LoadDockerConfig(writeConfig("https://" + host)); err != nil {
+		t.Fatalf("LoadDockerConfig() error = %v", err)
+	}
+	info, err := c.getOCITags(context.Background(), host, "org/private", "1.0.0")
+	if err != nil {
+		t.Fatalf("getOCITags() with credentials error = %v", err)
+	}
+	if info.Latest != "1.1.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "1.1.0")
+	}
+
+	// No entry for this host: the lookup stays anonymous and is refused
+	c = &Client{httpClient: srv.Client()}
+	if err := c.LoadDockerConfig(writeConfig("ghcr.io")); err != nil {
+		t.Fatalf("LoadDockerConfig() error = %v", err)
+	}
+	if _, err := c.getOCITags(context.Background(), host, "org/private", "1.0.0"); err == nil || !strings.Contains(err.Error(), "requires authentication") {
+		t.Errorf("getOCITags() without matching entry error = %v, want requires authentication", err)
+	}
+
+	if err := c.LoadDockerConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestGetOCITags_BasicChallenge(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot" || pass != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"tags":["2.0.0","2.1.0"]}`)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client(), auths: map[string]*Credentials{host: {"bot", "secret"}}}
+	info, err := c.getOCITags(context.Background(), host, "team/app", "2.0.0")
+	if err != nil {
+		t.Fatalf("getOCITags() error = %v", err)
+	}
+	if info.Latest != "2.1.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "2.1.0")
+	}
+}
+
+func TestGetDockerHubTags_Login(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/users/login":
+			fmt.Fprint(w, `{"token":"hub-token"}`)
+		case "/v2/repositories/library/nginx/tags":
+			if r.Header.Get("Authorization") != "Bearer hub-token" {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{"results":[{"name":"1.25.0"},{"name":"1.26.0"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), dockerHubURL: srv.URL}
+
+	if _, err := c.GetLatestTag(context.Background(), "docker.io", "nginx", "1.25.0"); !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("anonymous GetLatestTag() error = %v, want ErrRateLimit", err)
+	}
+
+	if err := c.LoginDockerHub(context.Background(), &Credentials{Username: "me", Password: "secret"}); err != nil {
+		t.Fatalf("LoginDockerHub() error = %v", err)
+	}
+	info, err := c.GetLatestTag(context.Background(), "docker.io", "nginx", "1.25.0")
+	if err != nil {
+		t.Fatalf("authenticated GetLatestTag() error = %v", err)
+	}
+	if info.Latest != "1.26.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "1.26.0")
+	}
+}
+
+func TestGetDockerHubTags_Pagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxPages   int
+		page2      int // Status of the second page
+		wantLatest string
+		wantTags   int
+	}{
+		{"follows next", 5, http.StatusOK, "17.2.0", 4},
+		{"page cap", 1, http.StatusOK, "16.4.0", 2},
+		{"rate limited keeps first page", 5, http.StatusTooManyRequests, "16.4.0", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var srv *httptest.Server
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/repositories/library/postgres/tags" {
+					http.NotFound(w, r)
+					return
+				}
+				if r.URL.Query().Get("page") == "" && r.URL.Query().Get("ordering") != "last_updated" {
+					t.Errorf("first page query %q, want newest tags first", r.URL.RawQuery)
+				}
+				switch r.URL.Query().Get("page") {
+				case "":
+					fmt.Fprintf(w, `{"results":[{"name":"16.3.0"},{"name":"16.4.0"}],"next":"%s/v2/repositories/library/postgres/tags?page=2&page_size=100"}`, srv.URL)
+				case "2":
+					w.WriteHeader(tt.page2)
+					fmt.Fprint(w, `{"results":[{"name":"17.1.0"},{"name":"17.2.0"}],"next":""}`)
+				default:
+					t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+				}
+			}))
+			defer srv.Close()
+
+			var debug strings.Builder
+			SetDebugOutput(&debug)
+			t.Cleanup(func() { SetDebugOutput(io.Discard) })
+
+			c := &Client{httpClient: srv.Client(), dockerHubURL: srv.URL}
+			c.SetDockerHubMaxPages(tt.maxPages)
+
+			info, err := c.GetLatestTag(context.Background(), "docker.io", "postgres", "16.3.0")
+			if err != nil {
+				t.Fatalf("GetLatestTag() error = %v", err)
+			}
+			if gotNote := strings.Contains(debug.String(), "older ones are ignored"); gotNote != (tt.maxPages == 1) {
+				t.Errorf("page cap note = %v, want %v (debug: %q)", gotNote, tt.maxPages == 1, debug.String())
+			}
+			if info.Latest != tt.wantLatest {
+				t.Errorf("Latest = %q, want %q", info.Latest, tt.wantLatest)
+			}
+			if len(info.AllTags) != tt.wantTags {
+				t.Errorf("AllTags = %q, want %d tags", info.AllTags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestNextDockerHubPage(t *testing.T) {
+	current, _ := url.Parse("https://hub.docker.com/v2/repositories/library/postgres/tags?page_size=100")
+
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"", ""},
+		{"https://hub.docker.com/v2/repositories/library/postgres/tags?page=2", "https://hub.docker.com/v2/repositories/library/postgres/tags?page=2"},
+		{"/v2/repositories/library/postgres/tags?page=3", "https://hub.docker.com/v2/repositories/library/postgres/tags?page=3"},
+		{"https://evil.example/tags?page=2", ""},
+	}
+
+	for _, tt := range tests {
+		if got := nextDockerHubPage(current, tt.next); got != tt.want {
+			t.Errorf("nextDockerHubPage(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestGetDockerHubTags_Auth(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		token    string
+		wantAuth string
+	}{
+		{"with token", "me", "dckr_pat_secret", "Bearer hub-token"},
+		{"anonymous", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logins int
+			var gotAuth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v2/users/login":
+					logins++
+					var body map[string]string
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["username"] != tt.username || body["password"] != tt.token {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					fmt.Fprint(w, `{"token":"hub-token"}`)
+				case "/v2/repositories/library/nginx/tags", "/v2/repositories/library/redis/tags":
+					gotAuth = r.Header.Get("Authorization")
+					fmt.Fprint(w, `{"results":[{"name":"1.25.0"},{"name":"1.26.0"}]}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			c := &Client{httpClient: srv.Client(), dockerHubURL: srv.URL}
+			c.SetDockerHubAuth(tt.username, tt.token)
+
+			for _, repo := range []string{"nginx", "redis"} {
+				if _, err := c.GetLatestTag(context.Background(), "docker.io", repo, "1.25.0"); err != nil {
+					t.Fatalf("GetLatestTag(%s) error = %v", repo, err)
+				}
+				if gotAuth != tt.wantAuth {
+					t.Errorf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+				}
+			}
+
+			wantLogins := 0
+			if tt.token != "" {
+				wantLogins = 1
+			}
+			if logins != wantLogins {
+				t.Errorf("logins = %d, want %d", logins, wantLogins)
+			}
+			if got := c.DockerHubAuthenticated(); got != (tt.token != "") {
+				t.Errorf("DockerHubAuthenticated() = %v, want %v", got, tt.token != "")
+			}
+		})
+	}
+}
+
+func TestGetLatestTag_DockerHubRegistry(t *testing.T) {
+	var tokenRequests []string
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			// Stands in for auth.docker.io
+			if r.URL.Query().Get("service") != "registry.docker.io" || r.URL.Query().Get("scope") != "repository:library/nginx:pull" {
+				t.Errorf("token request %s, want service and scope from the challenge", r.URL.RawQuery)
+			}
+			user, _, _ := r.BasicAuth()
+			tokenRequests = append(tokenRequests, user)
+			fmt.Fprint(w, `{"token":"pull-token"}`)
+		case r.Header.Get("Authorization") != "Bearer pull-token":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry.docker.io",scope="repository:library/nginx:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/library/nginx/tags/list":
+			fmt.Fprint(w, `{"name":"library/nginx","tags":["1.25.0","1.26.0","1.27.0-alpine","latest"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	// The Hub web API must not be used
+	c := &Client{httpClient: srv.Client(), dockerHubURL: "https://hub.invalid"}
+	c.SetDockerHubRegistry(host)
+
+	info, err := c.GetLatestTag(context.Background(), "docker.io", "nginx", "1.25.0")
+	if err != nil {
+		t.Fatalf("GetLatestTag() error = %v", err)
+	}
+	if info.Latest != "1.26.0" {
+		t.Errorf("Latest = %q, want %q", info.Latest, "1.26.0")
+	}
+
+	// After a login, the token exchange is authenticated
+	if err := c.LoginDockerHub(context.Background(), &Credentials{Username: "me", Password: "secret"}); err != nil {
+		t.Fatalf("LoginDockerHub() error = %v", err)
+	}
+	if !c.DockerHubAuthenticated() {
+		t.Error("DockerHubAuthenticated() = false after login")
+	}
+	if _, err := c.GetLatestTag(context.Background(), "docker.io", "library/nginx", "1.25.0"); err != nil {
+		t.Fatalf("authenticated GetLatestTag() error = %v", err)
+	}
+
+	if len(tokenRequests) != 2 || tokenRequests[0] != "" || tokenRequests[1] != "me" {
+		t.Errorf("token requests by user = %q, want anonymous then \"me\"", tokenRequests)
+	}
+}
+
+func TestSearchChart_RanksCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		chart    string
+		upstream string
+		packages string
+		want     string
+		wantNote bool
+	}{
+		{
+			name:     "exact repository wins",
+			chart:    "redis",
+			upstream: "bitnami",
+			packages: `[
+				{"name":"redis","version":"9.9.9","official":true,"repository":{"name":"other"}},
+				{"name":"redis","version":"18.0.0","repository":{"name":"bitnami"}}
+			]`,
+			want: "18.0.0",
+		},
+		{
+			name:     "official over fork",
+			chart:    "trino",
+			upstream: "trinodb",
+			packages: `[
+				{"name":"trino","version":"0.1.0","repository":{"name":"someones-fork"}},
+				{"name":"trino","version":"0.20.0","repository":{"name":"trino-official","official":true}}
+			]`,
+			want:     "0.20.0",
+			wantNote: true,
+		},
+		{
+			name:     "verified publisher over unverified",
+			chart:    "app",
+			upstream: "example",
+			packages: `[
+				{"name":"app","version":"1.0.0","repository":{"name":"mirror"}},
+				{"name":"app","version":"2.0.0","repository":{"name":"charts","verified_publisher":true}}
+			]`,
+			want:     "2.0.0",
+			wantNote: true,
+		},
+		{
+			name:     "closer repository name",
+			chart:    "app",
+			upstream: "example",
+			packages: `[
+				{"name":"app","version":"1.0.0","repository":{"name":"mirror"}},
+				{"name":"app","version":"3.0.0","repository":{"name":"example-charts"}}
+			]`,
+			want:     "3.0.0",
+			wantNote: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/packages/search" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprintf(w, `{"packages":%s}`, tt.packages)
+			}))
+			defer hub.Close()
+
+			var debug strings.Builder
+			SetDebugOutput(&debug)
+			t.Cleanup(func() { SetDebugOutput(io.Discard) })
+
+			c := &Client{httpClient: hub.Client(), artifactHubURL: hub.URL}
+			info, err := c.searchChart(context.Background(), tt.chart, tt.upstream)
+			if err != nil {
+				t.Fatalf("searchChart() error = %v", err)
+			}
+			if info.LatestVersion != tt.want {
+				t.Errorf("LatestVersion = %q, want %q", info.LatestVersion, tt.want)
+			}
+			if gotNote := strings.Contains(debug.String(), "fuzzy match"); gotNote != tt.wantNote {
+				t.Errorf("fuzzy match note = %v, want %v (debug: %q)", gotNote, tt.wantNote, debug.String())
+			}
+		})
+	}
+}
+
+func TestGetManifestDigest(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			fmt.Fprint(w, `{"token":"pull-token"}`)
+		case "/v2/org/app/manifests/1.2.0":
+			if r.Method != "HEAD" {
+				t.Errorf("method = %s, want HEAD", r.Method)
+			}
+			if r.Header.Get("Authorization") != "Bearer pull-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+				t.Errorf("Accept = %q, want OCI index media type", r.Header.Get("Accept"))
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:0123abcd")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client()}
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	digest, err := c.getManifestDigest(context.Background(), host, "org/app", "1.2.0")
+	if err != nil {
+		t.Fatalf("getManifestDigest() error = %v", err)
+	}
+	if digest != "sha256:0123abcd" {
+		t.Errorf("digest = %q, want %q", digest, "sha256:0123abcd")
+	}
+
+	if _, err := c.getManifestDigest(context.Background(), host, "org/app", "9.9.9"); err == nil {
+		t.Error("expected error for missing manifest")
+	}
+}
+
+func TestClientStats(t *testing.T) {
+	ok := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tags":["1.0.0","1.1.0"]}`)
+	}))
+	defer ok.Close()
+
+	limited := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+
+	// Both servers share the test certificate, so either client trusts both
+	c := &Client{httpClient: ok.Client()}
+	okHost := strings.TrimPrefix(ok.URL, "https://")
+	limitedHost := strings.TrimPrefix(limited.URL, "https://")
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.getOCITags(context.Background(), okHost, "org/app", "1.0.0"); err != nil {
+			t.Fatalf("getOCITags() error = %v", err)
+		}
+	}
+	if _, err := c.getOCITags(context.Background(), limitedHost, "org/app", "1.0.0"); !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("getOCITags() error = %v, want ErrRateLimit", err)
+	}
+
+	want := map[string]RegistryStats{
+		okHost:      {Host: okHost, Requests: 2},
+		limitedHost: {Host: limitedHost, Requests: 1, RateLimited: 1},
+	}
+
+	stats := c.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("Stats() = %+v, want %d hosts", stats, len(want))
+	}
+	for _, s := range stats {
+		if s != want[s.Host] {
+			t.Errorf("Stats() for %s = %+v, want %+v", s.Host, s, want[s.Host])
+		}
+	}
+}
+
+func TestParseGitHubRepo(t *testing.T) {
+	tests := []struct {
+		source    string
+		wantOwner string
+		wantRepo  string
+		wantOK    bool
+	}{
+		{"https://github.com/trinodb/trino", "trinodb", "trino", true},
+		{"https://github.com/bitnami/containers/tree/main/bitnami/redis", "bitnami", "containers", true},
+		{"git@github.com:org/app.git", "org", "app", true},
+		{"github.com/org/app", "org", "app", true},
+		{"https://gitlab.com/org/app", "", "", false},
+		{"https://github.com/org", "", "", false},
+		{"https://redis.io", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			owner, repo, ok := ParseGitHubRepo(tt.source)
+			if owner != tt.wantOwner || repo != tt.wantRepo || ok != tt.wantOK {
+				t.Errorf("ParseGitHubRepo(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.source, owner, repo, ok, tt.wantOwner, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetLatestRelease(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/app/releases/latest":
+			if r.Header.Get("Accept") != "application/vnd.github+json" {
+				t.Errorf("Accept = %q, want GitHub media type", r.Header.Get("Accept"))
+			}
+			fmt.Fprint(w, `{"tag_name":"v1.4.0","name":"App 1.4.0"}`)
+		case "/repos/org/limited/releases/latest":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("GITHUB_TOKEN", "")
+	c := &Client{httpClient: srv.Client(), githubURL: srv.URL}
+
+	tag, err := c.GetLatestRelease(context.Background(), "org", "app")
+	if err != nil {
+		t.Fatalf("GetLatestRelease() error = %v", err)
+	}
+	if tag != "v1.4.0" {
+		t.Errorf("tag = %q, want %q", tag, "v1.4.0")
+	}
+
+	if _, err := c.GetLatestRelease(context.Background(), "org", "limited"); !errors.Is(err, ErrRateLimit) {
+		t.Errorf("error = %v, want ErrRateLimit", err)
+	}
+	if _, err := c.GetLatestRelease(context.Background(), "org", "missing"); err == nil {
+		t.Error("expected error for repository without releases")
+	}
+
+	if !IsNewerVersion("v1.4.0", "1.2.0") {
+		t.Error("IsNewerVersion(v1.4.0, 1.2.0) = false, want true")
+	}
+	if IsNewerVersion("app-1.2.0", "1.2.0") {
+		t.Error("IsNewerVersion(app-1.2.0, 1.2.0) = true, want false")
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	// inFlight tracks concurrent work and records the peak
+	type inFlight struct {
+		cur, max atomic.Int32
+	}
+	enter := func(f *inFlight) {
+		n := f.cur.Add(1)
+		for {
+			m := f.max.Load()
+			if n <= m || f.max.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		f.cur.Add(-1)
+	}
+
+	var hub inFlight
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enter(&hub)
+		fmt.Fprint(w, `{"results":[{"name":"1.0.0"}]}`)
+	}))
+	defer srv.Close()
+
+	c := New(Options{})
+	c.httpClient = srv.Client()
+	c.dockerHubURL = srv.URL
+
+	var other inFlight
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetLatestTag(context.Background(), "docker.io", "org/app", "1.0.0"); err != nil {
+				t.Errorf("GetLatestTag() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			release := c.acquire("ghcr.io")
+			defer release()
+			enter(&other)
+		}()
+	}
+	wg.Wait()
+
+	if got := hub.max.Load(); got < 1 || got > DefaultDockerHubConcurrency {
+		t.Errorf("docker.io in-flight peaked at %d, want 1..%d", got, DefaultDockerHubConcurrency)
+	}
+	if got := other.max.Load(); got <= DefaultDockerHubConcurrency {
+		t.Errorf("ghcr.io in-flight peaked at %d, want more than %d", got, DefaultDockerHubConcurrency)
+	}
+}
+
+func TestNormalizeDockerRepo(t *testing.T) {
+	tests := []struct {
+		repository   string
+		wantNS       string
+		wantName     string
+		wantOfficial bool
+	}{
+		{"nginx", "library", "nginx", true},
+		{"library/nginx", "library", "nginx", true},
+		{"bitnami/postgresql", "bitnami", "postgresql", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			ns, name, official := NormalizeDockerRepo(tt.repository)
+			if ns != tt.wantNS || name != tt.wantName || official != tt.wantOfficial {
+				t.Errorf("NormalizeDockerRepo(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.repository, ns, name, official, tt.wantNS, tt.wantName, tt.wantOfficial)
+			}
+		})
+	}
+}
+
+func TestLookupBudget(t *testing.T) {
+	// Every step of the token dance is slow: challenge, token, retried request
+	const step = 100 * time.Millisecond
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(step)
+		switch {
+		case r.URL.Path == "/token":
+			fmt.Fprint(w, `{"token":"pull-token"}`)
+		case r.Header.Get("Authorization") != "Bearer pull-token":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Header().Set("Docker-Content-Digest", "sha256:0123abcd")
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client()}
+	if _, err := c.GetDigest(context.Background(), host, "org/app", "1.0.0"); err != nil {
+		t.Fatalf("GetDigest() without budget error = %v", err)
+	}
+
+	c.SetLookupBudget(step + step/2)
+	start := time.Now()
+	_, err := c.GetDigest(context.Background(), host, "org/app", "1.0.0")
+	elapsed := time.Since(start)
+
+	var timeoutErr *LookupTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("error = %v, want LookupTimeoutError", err)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("error %v is not classified as a network timeout", err)
+	}
+	if elapsed >= 3*step {
+		t.Errorf("lookup took %s, want it cut off by the %s budget", elapsed, step+step/2)
+	}
+}
+
+func TestECRRegion(t *testing.T) {
+	tests := []struct {
+		host   string
+		region string
+		ok     bool
+	}{
+		{"123456789012.dkr.ecr.eu-central-1.amazonaws.com", "eu-central-1", true},
+		{"123456789012.dkr.ecr-fips.us-east-1.amazonaws.com", "us-east-1", true},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", "cn-north-1", true},
+		{"public.ecr.aws", "", false},
+		{"dkr.ecr.eu-central-1.amazonaws.com", "", false},
+		{"ghcr.io", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			region, ok := ecrRegion(tt.host)
+			if region != tt.region || ok != tt.ok {
+				t.Errorf("ecrRegion(%q) = %q, %v, want %q, %v", tt.host, region, ok, tt.region, tt.ok)
+			}
+		})
+	}
+}
+
+// isolateAWSConfig keeps the AWS default credential chain from reading the
+// developer's config files or instance metadata
+func isolateAWSConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+}
+
+func TestGetECRTags(t *testing.T) {
+	isolateAWSConfig(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	token := base64.StdEncoding.EncodeToString([]byte("AWS:ecr-password"))
+	var tokenCalls atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.Header.Get("X-Amz-Target"), ".GetAuthorizationToken"):
+			tokenCalls.Add(1)
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			fmt.Fprintf(w, `{"authorizationData":[{"authorizationToken":%q,"expiresAt":%d}]}`,
+				token, time.Now().Add(time.Hour).Unix())
+		case r.URL.Path == "/v2/team/service/tags/list":
+			if r.Header.Get("Authorization") != "Basic "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0","latest"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client(), ecrEndpoint: srv.URL}
+	for i := 0; i < 2; i++ {
+		info, err := c.getECRTags(context.Background(), host, "eu-central-1", "team/service", "1.0.0")
+		if err != nil {
+			t.Fatalf("getECRTags() error = %v", err)
+		}
+		if info.Latest != "1.1.0" {
+			t.Errorf("Latest = %q, want %q", info.Latest, "1.1.0")
+		}
+	}
+	if n := tokenCalls.Load(); n != 1 {
+		t.Errorf("GetAuthorizationToken called %d times, want 1 (token reused)", n)
+	}
+}
+
+func TestGetChartVersion_OCIOnECR(t *testing.T) {
+	isolateAWSConfig(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	token := base64.StdEncoding.EncodeToString([]byte("AWS:ecr-password"))
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.Header.Get("X-Amz-Target"), ".GetAuthorizationToken"):
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			fmt.Fprintf(w, `{"authorizationData":[{"authorizationToken":%q,"expiresAt":%d}]}`,
+				token, time.Now().Add(time.Hour).Unix())
+		case r.URL.Path == "/v2/charts/service/tags/list":
+			if r.Header.Get("Authorization") != "Basic "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	c := New(Options{})
+	c.artifactHubURL = "http://127.0.0.1:1"
+	c.ecrEndpoint = srv.URL
+	c.httpClient = &http.Client{Transport: hostRewriter{target, srv.Client().Transport}}
+
+	info, err := c.GetChartVersion(context.Background(), "service", "", "oci://123456789012.dkr.ecr.eu-central-1.amazonaws.com/charts")
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "1.1.0" {
+		t.Errorf("LatestVersion = %q, want %q", info.LatestVersion, "1.1.0")
+	}
+}
+
+func TestGetECRTags_Pagination(t *testing.T) {
+	isolateAWSConfig(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.Header.Get("X-Amz-Target"), ".GetAuthorizationToken"):
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			fmt.Fprintf(w, `{"authorizationData":[{"authorizationToken":"dG9rZW4=","expiresAt":%d}]}`,
+				time.Now().Add(time.Hour).Unix())
+		case r.URL.Path == "/v2/team/service/tags/list" && r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/team/service/tags/list?last=1.1.0&n=1000>; rel="next"`)
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0"]}`)
+		case r.URL.Path == "/v2/team/service/tags/list" && r.URL.Query().Get("last") == "1.1.0":
+			fmt.Fprint(w, `{"tags":["1.2.0","latest"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := &Client{httpClient: srv.Client(), ecrEndpoint: srv.URL}
+	info, err := c.getECRTags(context.Background(), host, "eu-central-1", "team/service", "1.0.0")
+	if err != nil {
+		t.Fatalf("getECRTags() error = %v", err)
+	}
+	if info.Latest != "1.2.0" || len(info.AllTags) != 4 {
+		t.Errorf("Latest = %q from %d tags, want 1.2.0 from 4", info.Latest, len(info.AllTags))
+	}
+}
+
+func TestGetLatestTag_ECRNoCredentials(t *testing.T) {
+	isolateAWSConfig(t)
+
+	c := New(Options{})
+	c.ecrEndpoint = "https://127.0.0.1:1"
+	_, err := c.GetLatestTag(context.Background(), "123456789012.dkr.ecr.eu-central-1.amazonaws.com", "team/service", "1.0.0")
+	if !errors.Is(err, ErrNoAWSCredentials) {
+		t.Fatalf("error = %v, want ErrNoAWSCredentials", err)
+	}
+	if errors.Is(err, ErrUnsupportedRegistry) {
+		t.Error("ECR host reported as unsupported registry")
+	}
+}
+
+func TestParseRateLimitReset(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Time
+	}{
+		{"none", http.Header{}, time.Time{}},
+		{"retry-after seconds", http.Header{"Retry-After": {"120"}}, now.Add(2 * time.Minute)},
+		{"retry-after HTTP date", http.Header{"Retry-After": {"Sun, 01 Mar 2026 12:30:00 GMT"}}, now.Add(30 * time.Minute)},
+		{"reset seconds", http.Header{"Ratelimit-Reset": {"600"}}, now.Add(10 * time.Minute)},
+		{"reset unix time", http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}}, now.Add(time.Hour)},
+		{"retry-after wins", http.Header{"Retry-After": {"60"}, "X-Ratelimit-Reset": {"600"}}, now.Add(time.Minute)},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRateLimitReset(tt.header, now); !got.Equal(tt.want) {
+				t.Errorf("parseRateLimitReset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLatestTag_RateLimitReset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "300")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := New(Options{})
+	c.httpClient = srv.Client()
+	c.dockerHubURL = srv.URL
+
+	_, err := c.GetLatestTag(context.Background(), "docker.io", "org/app", "1.0.0")
+	if !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("error = %v, want ErrRateLimit", err)
+	}
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("error = %T, want *RateLimitError", err)
+	}
+	if wait := time.Until(rateErr.ResetAt); wait < 4*time.Minute || wait > 5*time.Minute {
+		t.Errorf("ResetAt in %s, want about 5m", wait)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int // Status of each attempt, the last one repeating
+		retries    int
+		wantCalls  int
+		wantLatest string // Empty if the lookup fails
+		wantErr    error
+	}{
+		{"503 then 200", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, 2, "1.1.0", nil},
+		{"fails twice then succeeds", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, 3, 3, "1.1.0", nil},
+		{"gives up", []int{http.StatusBadGateway}, 2, 3, "", nil},
+		{"4xx not retried", []int{http.StatusNotFound, http.StatusOK}, 2, 1, "", nil},
+		{"no retries", []int{http.StatusServiceUnavailable, http.StatusOK}, 0, 1, "", nil},
+		{"rate limit not retried", []int{http.StatusTooManyRequests, http.StatusOK}, 2, 1, "", ErrRateLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(calls, len(tt.statuses)-1)]
+				calls++
+				w.WriteHeader(status)
+				fmt.Fprint(w, `{"results":[{"name":"1.0.0"},{"name":"1.1.0"}]}`)
+			}))
+			defer srv.Close()
+
+			c := New(Options{Retries: tt.retries, RetryBackoff: time.Millisecond})
+			c.httpClient = srv.Client()
+			c.dockerHubURL = srv.URL
+
+			info, err := c.GetLatestTag(context.Background(), "docker.io", "org/app", "1.0.0")
+			if calls != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantLatest == "" {
+				if err == nil {
+					t.Error("error = nil, want the last attempt's failure")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLatestTag() error = %v", err)
+			}
+			if info.Latest != tt.wantLatest {
+				t.Errorf("Latest = %q, want %q", info.Latest, tt.wantLatest)
+			}
+		})
+	}
+}
+
+func TestRetry_DroppedConnection(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"name":"1.0.0"},{"name":"1.1.0"}]}`)
+	}))
+	defer srv.Close()
+
+	c := New(Options{Retries: 3, RetryBackoff: time.Millisecond})
+	c.httpClient = srv.Client()
+	c.dockerHubURL = srv.URL
+
+	info, err := c.GetLatestTag(context.Background(), "docker.io", "org/app", "1.0.0")
+	if err != nil {
+		t.Fatalf("GetLatestTag() error = %v", err)
+	}
+	if info.Latest != "1.1.0" || calls != 2 {
+		t.Errorf("Latest = %q after %d requests, want 1.1.0 after 2", info.Latest, calls)
+	}
+}
+
+func TestRetry_ArtifactHub(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"name":"redis","version":"19.0.0"}`)
+	}))
+	defer srv.Close()
+
+	c := New(Options{Retries: 2, RetryBackoff: time.Millisecond})
+	c.httpClient = srv.Client()
+	c.artifactHubURL = srv.URL
+
+	info, err := c.GetChartVersion(context.Background(), "redis", "bitnami", "")
+	if err != nil {
+		t.Fatalf("GetChartVersion() error = %v", err)
+	}
+	if info.LatestVersion != "19.0.0" || calls != 2 {
+		t.Errorf("LatestVersion = %q after %d requests, want 19.0.0 after 2", info.LatestVersion, calls)
+	}
+}
+
+func TestGetLatestTag_Mirror(t *testing.T) {
+	var paths []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v2/library/nginx/tags/list", "/v2/org/app/tags/list":
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0"]}`)
+		case "/v2/library/nginx/manifests/1.1.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	mirror := strings.TrimPrefix(srv.URL, "https://")
+
+	c := New(Options{Mirrors: map[string]string{"docker.io": mirror, "ghcr.io": mirror}})
+	c.httpClient = srv.Client()
+
+	for _, ref := range [][2]string{{"docker.io", "nginx"}, {"ghcr.io", "org/app"}} {
+		info, err := c.GetLatestTag(context.Background(), ref[0], ref[1], "1.0.0")
+		if err != nil {
+			t.Fatalf("GetLatestTag(%s/%s) error = %v", ref[0], ref[1], err)
+		}
+		if info.Latest != "1.1.0" {
+			t.Errorf("GetLatestTag(%s/%s) = %q, want 1.1.0 from the mirror", ref[0], ref[1], info.Latest)
+		}
+	}
+	if digest, err := c.GetDigest(context.Background(), "docker.io", "nginx", "1.1.0"); err != nil || digest != "sha256:abc" {
+		t.Errorf("GetDigest() = %q, %v, want sha256:abc from the mirror", digest, err)
+	}
+
+	want := []string{"/v2/library/nginx/tags/list", "/v2/org/app/tags/list", "/v2/library/nginx/manifests/1.1.0"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("mirror requests = %v, want %v", paths, want)
+	}
+}
+
+func TestInsecureRegistries(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tags":["1.0.0","1.1.0"]}`)
+	})
+	selfSigned := httptest.NewTLSServer(handler)
+	defer selfSigned.Close()
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	selfSignedHost := strings.TrimPrefix(selfSigned.URL, "https://")
+	plainHost := strings.TrimPrefix(plain.URL, "http://")
+
+	c := New(Options{InsecureRegistries: []string{selfSignedHost, plainHost}})
+	for _, host := range []string{selfSignedHost, plainHost} {
+		info, err := c.GetLatestTag(context.Background(), host, "team/api", "1.0.0")
+		if err != nil {
+			t.Fatalf("GetLatestTag(%s) error = %v", host, err)
+		}
+		if info.Latest != "1.1.0" {
+			t.Errorf("GetLatestTag(%s) = %q, want 1.1.0", host, info.Latest)
+		}
+	}
+
+	// Hosts that are not listed keep TLS verification
+	c = New(Options{InsecureRegistries: []string{"other.example.com"}})
+	if _, err := c.GetLatestTag(context.Background(), selfSignedHost, "team/api", "1.0.0"); err == nil {
+		t.Error("GetLatestTag() on an unlisted self-signed host succeeded, want a certificate error")
+	}
+}
+
+func TestInsecureHost(t *testing.T) {
+	c := New(Options{InsecureRegistries: []string{"mirror.corp.net", "registry.local:5000"}})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"mirror.corp.net", true},
+		{"mirror.corp.net:8443", true},
+		{"registry.local:5000", true},
+		{"registry.local", false},
+		{"registry.local:5001", false},
+		{"ghcr.io", false},
+	}
+	for _, tt := range tests {
+		if got := c.insecureHost(tt.host); got != tt.want {
+			t.Errorf("insecureHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestNew_Timeout(t *testing.T) {
+	if got := New(Options{Timeout: 3 * time.Second}).httpClient.Timeout; got != 3*time.Second {
+		t.Errorf("client timeout = %s, want 3s", got)
+	}
+	if got := New(Options{}).httpClient.Timeout; got != DefaultTimeout {
+		t.Errorf("default client timeout = %s, want %s", got, DefaultTimeout)
+	}
+}
+
+func TestTimeoutFor(t *testing.T) {
+	c := New(Options{
+		Timeout:          5 * time.Second,
+		RegistryTimeouts: map[string]time.Duration{"docker.io": time.Minute, "ghcr.io": 30 * time.Second},
+	})
+
+	tests := []struct {
+		host string
+		want time.Duration
+	}{
+		{"ghcr.io", 30 * time.Second},
+		{"ghcr.io:443", 30 * time.Second},
+		{"registry-1.docker.io", time.Minute},
+		{"auth.docker.io", time.Minute},
+		{"hub.docker.com", 5 * time.Second},
+		{"quay.io", 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := c.timeoutFor(tt.host); got != tt.want {
+			t.Errorf("timeoutFor(%q) = %s, want %s", tt.host, got, tt.want)
+		}
+	}
+}
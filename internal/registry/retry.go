@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options configures a Client
+type Options struct {
+	// Timeout bounds each HTTP request (0 = DefaultTimeout)
+	Timeout time.Duration
+
+	// RegistryTimeouts overrides Timeout for requests to a host, e.g.
+	// "ghcr.io". A host also covers its subdomains, so "docker.io" applies
+	// to registry-1.docker.io and auth.docker.io.
+	RegistryTimeouts map[string]time.Duration
+
+	// Retries is how many times a request that failed with a 5xx status or
+	// a connection error is sent again (0 = never). Rate limits are not
+	// retried.
+	Retries int
+
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// further one and jittered (0 = DefaultRetryBackoff)
+	RetryBackoff time.Duration
+
+	// Mirrors maps registries to a mirror host their images are looked up
+	// on instead, e.g. "docker.io": "mirror.corp.net". Mirrors are queried
+	// with the OCI Distribution API.
+	Mirrors map[string]string
+
+	// InsecureRegistries lists hosts, optionally with a port, whose TLS
+	// certificates are not verified and that may be reached over plain
+	// HTTP. All other hosts keep full TLS verification.
+	InsecureRegistries []string
+
+	// OnlySemver considers only clean release versions (1.2.3) as newer
+	// tags of every image, not just of images already on one
+	OnlySemver bool
+}
+
+// Defaults for Options
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultRetries      = 3
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// DefaultOptions returns the options New is used with by default
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      DefaultTimeout,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+}
+
+// do sends a request, retrying transient failures of idempotent requests
+// with exponential backoff
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(req)
+		if attempt >= c.retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		if err != nil {
+			debugf("retrying %s %s: %v", req.Method, req.URL.Redacted(), err)
+		} else {
+			debugf("retrying %s %s: status %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+			resp.Body.Close()
+		}
+
+		if err := sleep(req.Context(), c.backoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a request may succeed if sent again: it has no
+// body to resend, and it failed to connect or the server had a problem.
+// A 429 is not retried, the caller reports it as a rate limit.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	if err != nil {
+		// A cancelled or expired lookup is not going to recover
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff returns the wait before retry number attempt+1: RetryBackoff
+// doubled for each earlier retry, between half and the full value
+func (c *Client) backoff(attempt int) time.Duration {
+	base := c.retryBackoff
+	if base <= 0 {
+		base = DefaultRetryBackoff
+	}
+	d := base << attempt
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleep waits for d, or returns early with the error of ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timeoutFor returns the request timeout for host, which may include a port
+func (c *Client) timeoutFor(host string) time.Duration {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for {
+		if timeout, ok := c.hostTimeouts[host]; ok {
+			return timeout
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			return c.httpClient.Timeout
+		}
+		host = parent
+	}
+}
+
+// clientFor returns the HTTP client for requests to host
+func (c *Client) clientFor(host string) *http.Client {
+	timeout := c.timeoutFor(host)
+	insecure := c.insecureHost(host)
+	if timeout == c.httpClient.Timeout && !insecure {
+		return c.httpClient
+	}
+	client := *c.httpClient
+	client.Timeout = timeout
+	if insecure {
+		client.Transport = c.insecureTransport
+	}
+	return &client
+}
@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"net/http"
+	"sort"
+)
+
+// RegistryStats counts the requests made to one host during a run
+type RegistryStats struct {
+	Host        string
+	Requests    int
+	RateLimited int // Responses with status 429
+	Failed      int // Requests that got no response
+}
+
+// send sends a request once and records it in the per-host counters
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.clientFor(req.URL.Host).Do(req)
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if c.stats == nil {
+		c.stats = make(map[string]*RegistryStats)
+	}
+	host := req.URL.Host
+	s, ok := c.stats[host]
+	if !ok {
+		s = &RegistryStats{Host: host}
+		c.stats[host] = s
+	}
+
+	s.Requests++
+	switch {
+	case err != nil:
+		s.Failed++
+	case resp.StatusCode == 429:
+		s.RateLimited++
+	}
+
+	return resp, err
+}
+
+// Stats returns the request counters of every host contacted, sorted by host
+func (c *Client) Stats() []RegistryStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := make([]RegistryStats, 0, len(c.stats))
+	for _, s := range c.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Host < stats[j].Host
+	})
+	return stats
+}
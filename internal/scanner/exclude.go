@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is read from the scan root for further exclude patterns, one
+// per line. Blank lines and lines starting with "#" are skipped.
+const IgnoreFile = ".chartupignore"
+
+// readIgnoreFile returns the patterns in the ignore file at path, or none
+// if there is no such file
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return patterns, nil
+}
+
+// excluded reports whether a path relative to the scan root matches any of
+// the exclude patterns
+func (o Options) excluded(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range o.Exclude {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether a path relative to the scan root passes the Only
+// patterns. For a directory it reports whether a file below it could match.
+func (o Options) included(rel string, dir bool) bool {
+	if len(o.Only) == 0 {
+		return true
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range o.Only {
+		if dir && matchGlobPrefix(pattern, rel) || !dir && matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipImage reports whether an image reference matches any of the
+// SkipImages patterns: globs are matched like exclude patterns, with "/" as
+// the separator, and other patterns as substrings
+func (o Options) skipImage(ref string) bool {
+	for _, pattern := range o.SkipImages {
+		switch {
+		case pattern == "":
+			continue
+		case strings.ContainsAny(pattern, "*?["):
+			if matchGlob(pattern, ref) {
+				return true
+			}
+		case strings.Contains(ref, pattern):
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated path against a glob pattern. Each
+// segment is matched with path.Match, and a "**" segment matches any
+// number of segments, including none, so "**/charts/**" matches both
+// "charts" and "a/charts/b/values.yaml".
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchGlobPrefix reports whether some path below the directory name could
+// match the glob pattern
+func matchGlobPrefix(pattern, name string) bool {
+	return matchSegmentsPrefix(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegmentsPrefix(pattern, name []string) bool {
+	for len(name) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseImageString(f *testing.F) {
+	seeds := []string{
+		"nginx:1.21",
+		"bitnami/postgresql:11.14.0",
+		"quay.io/minio/minio:latest",
+		"registry.k8s.io/ingress-nginx/controller:v1.0.0",
+		"localhost:5000/app:1.0",
+		"ghcr.io/org/app",
+		"nginx@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"ghcr.io/org/app:v1@sha256:0123456789abcdef0123456789abcdef",
+		"a:",
+		":",
+		"/",
+		"a/",
+		"x.io/",
+		"ünïcode/ïmage:1.0",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		img := parseImageString(input, "values.yaml", 1)
+		if img == nil {
+			return
+		}
+
+		if strings.Contains(img.Registry, "/") {
+			t.Errorf("parseImageString(%q): registry %q contains a slash", input, img.Registry)
+		}
+		if img.Registry == "" {
+			t.Errorf("parseImageString(%q): empty registry", input)
+		}
+		if img.Repository == "" || strings.HasPrefix(img.Repository, "/") || strings.HasSuffix(img.Repository, "/") {
+			t.Errorf("parseImageString(%q): invalid repository %q", input, img.Repository)
+		}
+		if strings.ContainsAny(img.Repository, ": \t\r\n") {
+			t.Errorf("parseImageString(%q): repository %q contains a colon or whitespace", input, img.Repository)
+		}
+		if img.Tag == "" && img.Digest == "" {
+			t.Errorf("parseImageString(%q): empty tag and digest", input)
+		}
+	})
+}
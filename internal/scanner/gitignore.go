@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitignoreFile is read from the scan root when Options.Gitignore is set
+const GitignoreFile = ".gitignore"
+
+// gitignoreRule is one pattern of a .gitignore file
+type gitignoreRule struct {
+	pattern  string
+	negate   bool // "!pattern" re-includes what earlier rules ignored
+	dirOnly  bool // "pattern/" only matches directories
+	anchored bool // Patterns with a "/" before the end match from the root
+}
+
+// parseGitignore turns the lines of a .gitignore file into rules. Comments
+// and blank lines are expected to be removed already.
+func parseGitignore(lines []string) []gitignoreRule {
+	var rules []gitignoreRule
+	for _, line := range lines {
+		var rule gitignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`) // Escaped leading "#" or "!"
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// gitignored reports whether a path relative to the scan root is ignored by
+// rules, the last matching rule winning. Patterns without a "/" match the
+// name at any depth, the others the whole path.
+func gitignored(rules []gitignoreRule, rel string, dir bool) bool {
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !dir {
+			continue
+		}
+		var match bool
+		if rule.anchored {
+			match = matchGlob(rule.pattern, rel)
+		} else {
+			match, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
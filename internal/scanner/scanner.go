@@ -2,6 +2,10 @@ package scanner
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -16,25 +20,146 @@ type ChartInfo struct {
 	Version    string
 	AppVersion string
 	Path       string
-	Line       int    // Line number in file
-	Upstream   string // Known upstream source (e.g., "bitnami", "trinodb")
+	Line       int      // Line number in file
+	Upstream   string   // Known upstream source, the ArtifactHub repository (e.g., "bitnami", "grafana") or "trinodb"
+	Repository string   // Helm repository URL (https:// or oci://) from Chart.yaml dependencies
+	Sources    []string // Source URLs of the chart itself (not set for dependencies)
+
+	DependencyOf string // Name of the chart listing this one as a dependency, empty for a chart itself
 }
 
 // ImageInfo holds information about a Docker image
 type ImageInfo struct {
-	Registry   string // e.g., "docker.io", "quay.io"
-	Repository string // e.g., "trinodb/trino"
-	Tag        string // e.g., "410"
-	FullImage  string // Original full image string
-	Path       string // File where it was found
-	Line       int    // Line number in file
-	Skipped    bool   // True for images we don't check (e.g., thinkportgmbh)
+	Registry      string     // e.g., "docker.io", "quay.io"
+	Repository    string     // e.g., "trinodb/trino"
+	Tag           string     // e.g., "410"
+	RawTag        string     // Tag exactly as written in the file (e.g., "01"), empty if inherited
+	TagStyle      yaml.Style // YAML quoting of the tag's scalar (e.g., yaml.DoubleQuotedStyle), 0 if plain
+	Digest        string     // Pinned manifest digest (e.g., "sha256:abcd..."), empty if none
+	ContainerName string     // Name of the container in a containers/initContainers list, or compose service, if any
+	FullImage     string     // Original full image string
+	Path          string     // File where it was found
+	Line          int        // Line number in file
+	ByDigest      bool       // True if pinned by digest alone, with no tag to compare
+	Skipped       bool       // True for images we don't check (e.g., matching Options.SkipImages)
 }
 
 // ScanResults holds all discovered charts and images
 type ScanResults struct {
-	Charts []ChartInfo
-	Images []ImageInfo
+	Charts   []ChartInfo
+	Images   []ImageInfo
+	Warnings []string // Files that were skipped
+}
+
+// Options controls optional scanning behavior
+type Options struct {
+	// ScanSchemas enables extracting images from values.schema.json defaults
+	ScanSchemas bool
+
+	// ScanManifests enables extracting container images from Kubernetes
+	// manifests (any other .yaml/.yml file, including templates/). The
+	// CLI turns it on by default through config.Default.
+	ScanManifests bool
+
+	// Files restricts scanning to these files and the Chart.yaml and
+	// values.yaml of the chart they belong to, found by walking up to the
+	// nearest Chart.yaml. nil scans everything, an empty slice scans nothing.
+	Files []string
+
+	// CheckMainChart checks every chart's own version against its detected
+	// upstream, not only charts that look vendored
+	CheckMainChart bool
+
+	// MaxFileSize skips files larger than this many bytes (0 = no limit)
+	MaxFileSize int64
+
+	// Exclude skips paths relative to the scan root that match any of these
+	// glob patterns ("**" matches any number of directories). Matching
+	// directories are not descended into. Exclusion wins over Files.
+	// Patterns in the scan root's IgnoreFile are added to these.
+	Exclude []string
+
+	// Gitignore skips paths ignored by the .gitignore file in the scan
+	// root. Nested .gitignore files are not read. .git directories are
+	// skipped either way.
+	Gitignore bool
+
+	// Only restricts scanning to files whose path relative to the scan root
+	// matches one of these glob patterns. Directories no pattern could match
+	// below are not descended into. Empty scans everything.
+	Only []string
+
+	// Upstreams maps chart names to their upstream, the ArtifactHub
+	// repository their versions are looked up in. They take precedence over
+	// the built-in detection; an empty upstream turns it off for a chart.
+	// A main chart with a configured upstream is checked even if it doesn't
+	// look vendored.
+	Upstreams map[string]string
+
+	// SkipImages marks images as skipped whose reference, as written,
+	// contains one of these strings or, for patterns with glob characters,
+	// matches it (e.g. "thinkportgmbh/*" or "**/internal-*")
+	SkipImages []string
+}
+
+// DefaultMaxFileSize is the default for Options.MaxFileSize
+const DefaultMaxFileSize = 5 << 20
+
+// allowedFiles returns the set of absolute paths to scan, or nil for all
+func (o Options) allowedFiles() map[string]bool {
+	if o.Files == nil {
+		return nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, file := range o.Files {
+		path, err := resolvePath(file)
+		if err != nil {
+			continue
+		}
+		allowed[path] = true
+
+		// A change anywhere in a chart, e.g. under templates/, pulls in its
+		// Chart.yaml and values.yaml; outside a chart, the siblings
+		dir := filepath.Dir(path)
+		if chart, ok := chartDir(dir); ok {
+			dir = chart
+		}
+		allowed[filepath.Join(dir, "Chart.yaml")] = true
+		allowed[filepath.Join(dir, "values.yaml")] = true
+	}
+	return allowed
+}
+
+// resolvePath returns the absolute path of file with symlinks resolved, so
+// paths reported by git match those found while walking a symlinked root.
+// Files that no longer exist, e.g. deleted ones, resolve their directory.
+func resolvePath(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(dir, filepath.Base(abs)), nil
+	}
+	return abs, nil
+}
+
+// chartDir returns the nearest directory at or above dir with a Chart.yaml
+func chartDir(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
 }
 
 // Chart.yaml structure
@@ -42,6 +167,8 @@ type chartYAML struct {
 	Name         string            `yaml:"name"`
 	Version      string            `yaml:"version"`
 	AppVersion   string            `yaml:"appVersion"`
+	Home         string            `yaml:"home"`
+	Sources      []string          `yaml:"sources"`
 	Dependencies []chartDependency `yaml:"dependencies"`
 }
 
@@ -52,7 +179,7 @@ type chartDependency struct {
 }
 
 // Scan recursively scans a directory for Helm charts and Docker images
-func Scan(root string) (*ScanResults, error) {
+func Scan(root string, opts Options) (*ScanResults, error) {
 	results := &ScanResults{
 		Charts: []ChartInfo{},
 		Images: []ImageInfo{},
@@ -60,21 +187,80 @@ func Scan(root string) (*ScanResults, error) {
 
 	seenImages := make(map[string]bool)
 	seenCharts := make(map[string]bool)
+	allowed := opts.allowedFiles()
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	ignored, err := readIgnoreFile(filepath.Join(root, IgnoreFile))
+	if err != nil {
+		results.Warnings = append(results.Warnings, fmt.Sprintf("skipping %s: %v", IgnoreFile, err))
+	}
+	opts.Exclude = append(opts.Exclude[:len(opts.Exclude):len(opts.Exclude)], ignored...)
+
+	var gitignore []gitignoreRule
+	if opts.Gitignore {
+		lines, err := readIgnoreFile(filepath.Join(root, GitignoreFile))
+		if err != nil {
+			results.Warnings = append(results.Warnings, fmt.Sprintf("skipping %s: %v", GitignoreFile, err))
+		}
+		gitignore = parseGitignore(lines)
+	}
+
+	addImages := func(images []ImageInfo) {
+		for _, img := range images {
+			if seenImages[img.FullImage] {
+				continue
+			}
+			seenImages[img.FullImage] = true
+			if opts.skipImage(img.FullImage) {
+				img.Skipped = true
+			}
+			results.Images = append(results.Images, img)
+		}
+	}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip files we can't access
 		}
 
+		// Repository metadata never holds anything to check
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." {
+			if opts.excluded(rel) || !opts.included(rel, info.IsDir()) || gitignored(gitignore, rel, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
 		if info.IsDir() {
 			return nil
 		}
 
+		if allowed != nil {
+			if resolved, err := resolvePath(path); err != nil || !allowed[resolved] {
+				return nil
+			}
+		}
+
 		filename := info.Name()
+		if !isScanTarget(filename, opts) {
+			return nil
+		}
+
+		// Symlinked directories are not followed by filepath.Walk, so only
+		// the file itself needs checking before it is read
+		if warning := checkFile(path, opts.MaxFileSize); warning != "" {
+			results.Warnings = append(results.Warnings, warning)
+			return nil
+		}
 
 		// Parse Chart.yaml files
 		if filename == "Chart.yaml" {
-			charts, err := parseChartYAML(path)
+			charts, err := parseChartYAML(path, opts)
 			if err == nil {
 				for _, c := range charts {
 					key := c.Name + "@" + c.Version
@@ -90,12 +276,32 @@ func Scan(root string) (*ScanResults, error) {
 		if filename == "values.yaml" {
 			images, err := parseValuesYAML(path)
 			if err == nil {
-				for _, img := range images {
-					if !seenImages[img.FullImage] {
-						seenImages[img.FullImage] = true
-						results.Images = append(results.Images, img)
-					}
-				}
+				addImages(images)
+			}
+		}
+
+		// Parse values.schema.json defaults for images (opt-in)
+		if opts.ScanSchemas && filename == "values.schema.json" {
+			images, err := parseValuesSchema(path)
+			if err == nil {
+				addImages(images)
+			}
+		}
+
+		// Parse docker compose files for service images
+		if isComposeFile(filename) {
+			images, err := parseComposeFile(path)
+			if err == nil {
+				addImages(images)
+			}
+		}
+
+		// Parse Kubernetes manifests for container images (on by default,
+		// --scan-manifests=false turns it off)
+		if opts.ScanManifests && isManifest(filename) {
+			images, err := parseManifest(path)
+			if err == nil {
+				addImages(images)
 			}
 		}
 
@@ -103,12 +309,7 @@ func Scan(root string) (*ScanResults, error) {
 		if isDockerfile(filename) {
 			images, err := parseDockerfile(path)
 			if err == nil {
-				for _, img := range images {
-					if !seenImages[img.FullImage] {
-						seenImages[img.FullImage] = true
-						results.Images = append(results.Images, img)
-					}
-				}
+				addImages(images)
 			}
 		}
 
@@ -118,7 +319,66 @@ func Scan(root string) (*ScanResults, error) {
 	return results, err
 }
 
-func parseChartYAML(path string) ([]ChartInfo, error) {
+// isScanTarget reports whether a file with this name is parsed
+func isScanTarget(filename string, opts Options) bool {
+	switch {
+	case filename == "Chart.yaml", filename == "values.yaml":
+		return true
+	case filename == "values.schema.json":
+		return opts.ScanSchemas
+	case isComposeFile(filename):
+		return true
+	case isManifest(filename):
+		return opts.ScanManifests
+	default:
+		return isDockerfile(filename)
+	}
+}
+
+// checkFile returns a warning if the file at path should not be read: it is
+// larger than maxSize, or (through a symlink) not a regular file, such as a
+// device that never ends. Files that can't be stat'ed are left to the parser.
+func checkFile(path string, maxSize int64) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Sprintf("skipping %s: not a regular file", path)
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		return fmt.Sprintf("skipping %s: %d bytes exceeds the maximum file size of %d", path, info.Size(), maxSize)
+	}
+	return ""
+}
+
+// FilterRegistries keeps only images hosted on one of the given registries.
+// Charts are dropped, as they are not hosted on an image registry.
+// An empty list leaves the results untouched.
+func (r *ScanResults) FilterRegistries(registries []string) {
+	if len(registries) == 0 {
+		return
+	}
+
+	images := []ImageInfo{}
+	for _, img := range r.Images {
+		for _, reg := range registries {
+			if strings.EqualFold(img.Registry, reg) {
+				images = append(images, img)
+				break
+			}
+		}
+	}
+
+	r.Images = images
+	r.Charts = []ChartInfo{}
+}
+
+// parseChartYAML returns the chart itself and its dependencies. The chart's
+// own upstream is only kept (so its version gets checked) when it looks like
+// a vendored upstream copy, is configured in opts.Upstreams, or when
+// opts.CheckMainChart is set.
+func parseChartYAML(path string, opts Options) ([]ChartInfo, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -131,35 +391,74 @@ func parseChartYAML(path string) ([]ChartInfo, error) {
 
 	charts := []ChartInfo{}
 
-	// Add main chart with upstream detection
+	// Add main chart with upstream detection; locally developed charts
+	// have no upstream version to compare against
+	upstream := detectUpstream(chart.Name, path, opts.Upstreams)
+	_, configured := opts.Upstreams[chart.Name]
+	if !opts.CheckMainChart && !configured && !isVendoredChart(path, chart, upstream) {
+		upstream = ""
+	}
 	mainChart := ChartInfo{
 		Name:       chart.Name,
 		Version:    chart.Version,
 		AppVersion: chart.AppVersion,
 		Path:       path,
-		Upstream:   detectUpstream(chart.Name, path),
+		Upstream:   upstream,
+		Sources:    chart.Sources,
 	}
 	charts = append(charts, mainChart)
 
 	// Add dependencies with their upstreams
 	for _, dep := range chart.Dependencies {
 		upstream := ""
-		if strings.Contains(dep.Repository, "bitnami") {
-			upstream = "bitnami"
+		if configured, ok := opts.Upstreams[dep.Name]; ok {
+			upstream = configured
+		} else {
+			upstream = repoUpstream(dep.Repository)
 		}
 		charts = append(charts, ChartInfo{
-			Name:     dep.Name,
-			Version:  dep.Version,
-			Path:     path,
-			Upstream: upstream,
+			Name:       dep.Name,
+			Version:    dep.Version,
+			Path:       path,
+			Upstream:   upstream,
+			Repository: dep.Repository,
+
+			DependencyOf: chart.Name,
 		})
 	}
 
 	return charts, nil
 }
 
-// detectUpstream tries to identify known upstream sources for a chart
-func detectUpstream(name, path string) string {
+// isVendoredChart reports whether the chart at path is a copy of an upstream
+// chart rather than one developed locally: it has a known upstream and is
+// either unpacked into a parent chart's charts/ directory, or its home or
+// sources point at the upstream project.
+func isVendoredChart(path string, chart chartYAML, upstream string) bool {
+	if upstream == "" {
+		return false
+	}
+
+	chartDir := filepath.Dir(path)
+	if filepath.Base(filepath.Dir(chartDir)) == "charts" {
+		return true
+	}
+
+	for _, url := range append([]string{chart.Home}, chart.Sources...) {
+		if strings.Contains(strings.ToLower(url), upstream) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectUpstream tries to identify known upstream sources for a chart.
+// Upstreams configured by chart name take precedence.
+func detectUpstream(name, path string, upstreams map[string]string) string {
+	if upstream, ok := upstreams[name]; ok {
+		return upstream
+	}
+
 	nameLower := strings.ToLower(name)
 	pathLower := strings.ToLower(path)
 
@@ -180,6 +479,38 @@ func detectUpstream(name, path string) string {
 	return "" // Local/custom chart
 }
 
+// knownRepos maps well-known Helm repository URLs, without the scheme, to
+// the ArtifactHub repository indexing them
+var knownRepos = []struct{ url, upstream string }{
+	{"charts.bitnami.com/bitnami", "bitnami"},
+	{"registry-1.docker.io/bitnamicharts", "bitnami"},
+	{"trinodb.github.io/charts", "trinodb"},
+	{"prometheus-community.github.io/helm-charts", "prometheus-community"},
+	{"kubernetes.github.io/ingress-nginx", "ingress-nginx"},
+	{"grafana.github.io/helm-charts", "grafana"},
+	{"charts.jetstack.io", "cert-manager"},
+}
+
+// repoUpstream returns the upstream of a dependency from its repository URL,
+// or "" if the repository isn't a known one
+func repoUpstream(repository string) string {
+	repo := strings.ToLower(repository)
+	if i := strings.Index(repo, "://"); i >= 0 {
+		repo = repo[i+len("://"):]
+	}
+	repo = strings.TrimSuffix(repo, "/")
+
+	for _, known := range knownRepos {
+		if repo == known.url {
+			return known.upstream
+		}
+	}
+	if strings.Contains(repo, "bitnami") {
+		return "bitnami"
+	}
+	return ""
+}
+
 func parseValuesYAML(path string) ([]ImageInfo, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -196,69 +527,487 @@ func parseValuesYAML(path string) ([]ImageInfo, error) {
 
 	// Extract images from YAML nodes (preserves line numbers)
 	if len(root.Content) > 0 {
-		extractImagesFromNode(root.Content[0], path, &images)
+		ctx := globalImageContext(root.Content[0])
+		extractImagesFromNode(root.Content[0], path, ctx, &images)
 	}
 
 	return images, nil
 }
 
+// imageContext holds chart-wide image defaults inherited by every image
+// in a values file (Bitnami-style global.imageRegistry / global.imageTag),
+// and the name of the container list item being walked, if any
+type imageContext struct {
+	registry  string
+	tag       string
+	container string
+}
+
+// globalImageContext reads global.imageRegistry and global.imageTag from
+// the top-level mapping of a values file
+func globalImageContext(root *yaml.Node) imageContext {
+	ctx := imageContext{}
+
+	global := mappingValue(root, "global")
+	if global == nil || global.Kind != yaml.MappingNode {
+		return ctx
+	}
+
+	if node := mappingValue(global, "imageRegistry"); node != nil && node.Kind == yaml.ScalarNode {
+		ctx.registry = strings.TrimSuffix(strings.TrimSpace(node.Value), "/")
+	}
+	if node := mappingValue(global, "imageTag"); node != nil && node.Kind == yaml.ScalarNode {
+		ctx.tag = strings.TrimSpace(node.Value)
+	}
+
+	return ctx
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// apply composes an image reference with the inherited registry and tag.
+// The registry is only prepended when the reference has no host of its own,
+// and the tag only used when the reference has none.
+func (ctx imageContext) apply(ref string) string {
+	if ctx.registry != "" && !hasRegistryHost(ref) {
+		ref = ctx.registry + "/" + ref
+	}
+	if ctx.tag != "" && !hasTag(ref) {
+		ref = ref + ":" + ctx.tag
+	}
+	return ref
+}
+
+// hasRegistryHost reports whether the first path component of ref is a registry host
+func hasRegistryHost(ref string) bool {
+	first, _, ok := strings.Cut(ref, "/")
+	if !ok {
+		return false
+	}
+	return strings.Contains(first, ".") || strings.Contains(first, ":") || first == "localhost"
+}
+
+// hasTag reports whether ref carries a tag or digest after its last path component
+func hasTag(ref string) bool {
+	name := ref
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		name = ref[i+1:]
+	}
+	return strings.Contains(name, ":")
+}
+
+// isImageKey reports whether a key holds an image, e.g. "image" or "initImage"
+func isImageKey(key string) bool {
+	return strings.HasSuffix(key, "image") || strings.HasSuffix(key, "Image")
+}
+
+// repositoryImage returns the image of a mapping whose repository is given
+// by the repo pair, completed by its sibling registry, tag, and digest keys.
+// The image is reported at the line of repo.
+func repositoryImage(pairs []mappingPair, repo mappingPair, path string, ctx imageContext) *ImageInfo {
+	ref := repo.value.Value
+
+	// Charts like Bitnami's keep the registry in a sibling "registry" key.
+	// global.imageRegistry still takes precedence, as it does in their
+	// templates.
+	if n := siblingScalar(pairs, "registry"); n != nil && ctx.registry == "" && !hasRegistryHost(ref) {
+		if registry := strings.TrimSuffix(strings.TrimSpace(n.Value), "/"); registry != "" {
+			ref = registry + "/" + ref
+		}
+	}
+
+	// The tag field may carry a digest ("sha256:..." or "1.2.3@sha256:...")
+	tag := ""
+	tagNode := siblingScalar(pairs, "tag")
+	if tagNode != nil {
+		tag = tagNode.Value
+	}
+	tag, digest := splitTagDigest(tag)
+
+	// Charts like Bitnami's keep it in a sibling "digest" key instead
+	if n := siblingScalar(pairs, "digest"); digest == "" && n != nil && digestPattern.MatchString(n.Value) {
+		digest = n.Value
+	}
+	if tag != "" {
+		ref += ":" + tag
+	}
+	if digest != "" {
+		ref += "@" + digest
+	}
+	ref = ctx.apply(ref)
+	if !hasTag(ref) {
+		ref += ":latest"
+	}
+
+	img := parseImageString(ref, path, repo.line())
+	if img == nil {
+		return nil
+	}
+	if tagNode != nil {
+		img.RawTag = tagNode.Value
+		img.TagStyle = tagNode.Style
+	}
+	img.ContainerName = ctx.container
+	return img
+}
+
 // extractImagesFromNode extracts images from yaml.Node tree, preserving line numbers
-func extractImagesFromNode(node *yaml.Node, path string, images *[]ImageInfo) {
+func extractImagesFromNode(node *yaml.Node, path string, ctx imageContext, images *[]ImageInfo) {
 	if node == nil {
 		return
 	}
 
 	switch node.Kind {
 	case yaml.MappingNode:
-		// Process key-value pairs
-		for i := 0; i < len(node.Content)-1; i += 2 {
-			keyNode := node.Content[i]
-			valueNode := node.Content[i+1]
+		// Process key-value pairs, including those merged in with `<<`
+		pairs := mappingPairs(node)
+		for _, pair := range pairs {
+			keyNode := pair.key
+			valueNode := pair.value
 
 			// Check for repository/tag pattern
 			if keyNode.Value == "repository" && valueNode.Kind == yaml.ScalarNode {
-				repo := valueNode.Value
-				tag := "latest"
-				line := valueNode.Line
-
-				// Look for sibling "tag" key
-				for j := 0; j < len(node.Content)-1; j += 2 {
-					if node.Content[j].Value == "tag" {
-						tagNode := node.Content[j+1]
-						if tagNode.Kind == yaml.ScalarNode && tagNode.Value != "" {
-							tag = tagNode.Value
+				if img := repositoryImage(pairs, pair, path, ctx); img != nil {
+					*images = append(*images, *img)
+				}
+			}
+
+			// Mappings under an image key may call the repository "name"
+			// (image.name/image.tag)
+			if isImageKey(keyNode.Value) && valueNode.Kind == yaml.MappingNode && mappingValue(valueNode, "repository") == nil {
+				childPairs := mappingPairs(valueNode)
+				for _, child := range childPairs {
+					if child.key.Value == "name" && child.value.Kind == yaml.ScalarNode {
+						if img := repositoryImage(childPairs, child, path, ctx); img != nil {
+							*images = append(*images, *img)
 						}
 						break
 					}
 				}
+			}
 
-				img := parseImageString(repo+":"+tag, path, line)
-				if img != nil {
-					*images = append(*images, *img)
+			// Check for "image"/"images" key with string value, or any key
+			// ending in "image"/"Image" such as "initImage"
+			if (isImageKey(keyNode.Value) || keyNode.Value == "images") && valueNode.Kind == yaml.ScalarNode {
+				for _, ref := range splitImageList(valueNode.Value) {
+					img := parseImageString(ctx.apply(ref), path, pair.line())
+					if img != nil {
+						if hasTag(ref) {
+							img.RawTag = img.Tag
+							img.TagStyle = valueNode.Style
+						}
+						img.ContainerName = ctx.container
+						*images = append(*images, *img)
+					}
 				}
 			}
 
-			// Check for "image" key with string value
-			if keyNode.Value == "image" && valueNode.Kind == yaml.ScalarNode {
-				img := parseImageString(valueNode.Value, path, valueNode.Line)
-				if img != nil {
-					*images = append(*images, *img)
+			// Recurse into value nodes. Merged values are walked where
+			// their anchor is defined. The container name only applies
+			// to the container's own image.
+			if pair.mergeLine == 0 {
+				childCtx := ctx
+				if keyNode.Value != "image" {
+					childCtx.container = ""
 				}
+				extractImagesFromNode(valueNode, path, childCtx, images)
 			}
-
-			// Recurse into value nodes
-			extractImagesFromNode(valueNode, path, images)
 		}
 
 	case yaml.SequenceNode:
 		for _, item := range node.Content {
-			extractImagesFromNode(item, path, images)
+			itemCtx := ctx
+			itemCtx.container = containerName(item)
+			extractImagesFromNode(item, path, itemCtx, images)
 		}
 
 	case yaml.DocumentNode:
 		for _, item := range node.Content {
-			extractImagesFromNode(item, path, images)
+			extractImagesFromNode(item, path, ctx, images)
+		}
+	}
+}
+
+// containerName returns the name of a container list item, a mapping with
+// both "name" and "image" keys as in containers and initContainers, or ""
+func containerName(item *yaml.Node) string {
+	name := mappingValue(item, "name")
+	if name == nil || name.Kind != yaml.ScalarNode || mappingValue(item, "image") == nil {
+		return ""
+	}
+	return name.Value
+}
+
+// mappingPair is a key/value pair of a YAML mapping
+type mappingPair struct {
+	key, value *yaml.Node
+	mergeLine  int // Line of the `<<` merge key the pair came from, 0 if local
+}
+
+// line returns the line an image in this pair is reported on. Merged pairs
+// are reported where they are merged in, not where their anchor is.
+func (p mappingPair) line() int {
+	if p.mergeLine != 0 {
+		return p.mergeLine
+	}
+	return valueLine(p.value)
+}
+
+// siblingScalar returns the value of key among pairs if it is a non-empty
+// scalar, or nil
+func siblingScalar(pairs []mappingPair, key string) *yaml.Node {
+	for _, pair := range pairs {
+		if pair.key.Value == key {
+			if pair.value.Kind == yaml.ScalarNode && pair.value.Value != "" {
+				return pair.value
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// mappingPairs returns the pairs of a mapping with YAML merge keys
+// (`<<: *defaults` or `<<: [*a, *b]`) expanded. As in YAML, local keys
+// override merged ones, and earlier merged mappings override later ones.
+func mappingPairs(node *yaml.Node) []mappingPair {
+	var local, merged []mappingPair
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag != "!!merge" {
+			local = append(local, mappingPair{key: key, value: value})
+			seen[key.Value] = true
+		}
+	}
+
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag != "!!merge" {
+			continue
+		}
+
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			if source.Kind == yaml.AliasNode {
+				source = source.Alias
+			}
+			if source == nil || source.Kind != yaml.MappingNode {
+				continue
+			}
+			for _, pair := range mappingPairs(source) {
+				if seen[pair.key.Value] {
+					continue
+				}
+				seen[pair.key.Value] = true
+				merged = append(merged, mappingPair{key: pair.key, value: pair.value, mergeLine: key.Line})
+			}
+		}
+	}
+
+	return append(local, merged...)
+}
+
+// valueLine returns the line an image reference is written on. Block
+// scalars (`image: >-`) start on the line after their indicator.
+func valueLine(node *yaml.Node) int {
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return node.Line + 1
+	}
+	return node.Line
+}
+
+// splitImageList splits a scalar holding several image references separated
+// by commas or whitespace (e.g. "nginx:1.21, redis:7.0").
+// The value is only split when every part looks like an image reference,
+// otherwise it is returned unchanged as a single reference.
+func splitImageList(value string) []string {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(parts) < 2 {
+		return []string{value}
+	}
+
+	for _, part := range parts {
+		if parseImageString(part, "", 0) == nil {
+			return []string{value}
+		}
+	}
+
+	return parts
+}
+
+// digestPattern matches a content digest such as "sha256:<hex>"
+var digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+// splitTagDigest splits the value of a tag field into the human-readable
+// tag and the digest it is pinned to, either of which may be empty
+func splitTagDigest(value string) (tag, digest string) {
+	if tag, digest, ok := strings.Cut(value, "@"); ok {
+		return tag, digest
+	}
+	if digestPattern.MatchString(value) {
+		return "", value
+	}
+	return value, ""
+}
+
+// imageRefPattern matches strings shaped like an image reference
+// ([registry[:port]/]name[/name...][:tag]), used where arbitrary strings
+// must be told apart from images
+var imageRefPattern = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*(?::[\w][\w.-]{0,127})?$`)
+
+// parseValuesSchema extracts images from "default" values in a values.schema.json
+// JSON is parsed as YAML so line numbers are preserved
+func parseValuesSchema(path string) ([]ImageInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	images := []ImageInfo{}
+	extractSchemaDefaults(&root, path, &images)
+	return images, nil
+}
+
+// extractSchemaDefaults collects image-like "default" scalars from a schema tree
+func extractSchemaDefaults(node *yaml.Node, path string, images *[]ImageInfo) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+
+			if keyNode.Value == "default" && valueNode.Kind == yaml.ScalarNode &&
+				valueNode.Tag == "!!str" && imageRefPattern.MatchString(valueNode.Value) {
+				img := parseImageString(valueNode.Value, path, valueNode.Line)
+				if img != nil {
+					*images = append(*images, *img)
+				}
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		extractSchemaDefaults(child, path, images)
+	}
+}
+
+// isManifest reports whether a file may be a Kubernetes manifest: any YAML
+// file other than the Chart.yaml, values.yaml and compose files parsed on
+// their own
+func isManifest(filename string) bool {
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+		return filename != "Chart.yaml" && filename != "values.yaml" && !isComposeFile(filename)
+	default:
+		return false
+	}
+}
+
+// parseManifest extracts container images from a Kubernetes manifest,
+// which may hold several documents. Helm templates that are not valid YAML
+// are retried with their template directive lines blanked out, and skipped
+// with an error if they still fail; image values with template expressions
+// are ignored by parseImageString.
+func parseManifest(path string) ([]ImageInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := parseManifestDocuments(data, path)
+	if err != nil {
+		return parseManifestDocuments(stripTemplateDirectives(data), path)
+	}
+	return images, nil
+}
+
+// parseManifestDocuments extracts container images from every document
+func parseManifestDocuments(data []byte, path string) ([]ImageInfo, error) {
+	images := []ImageInfo{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return images, nil
+			}
+			return nil, err
 		}
+		extractContainerImages(&doc, path, &images)
+	}
+}
+
+// stripTemplateDirectives blanks lines consisting only of template
+// actions, such as "{{- if .Values.enabled }}" or "{{- end }}", keeping
+// line numbers intact
+func stripTemplateDirectives(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("{{")) && bytes.HasSuffix(trimmed, []byte("}}")) {
+			lines[i] = nil
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// extractContainerImages collects the image of every item in containers
+// and initContainers lists, such as a Deployment's pod template spec
+func extractContainerImages(node *yaml.Node, path string, images *[]ImageInfo) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+
+			if (keyNode.Value == "containers" || keyNode.Value == "initContainers") &&
+				valueNode.Kind == yaml.SequenceNode {
+				for _, item := range valueNode.Content {
+					imageNode := mappingValue(item, "image")
+					if imageNode == nil || imageNode.Kind != yaml.ScalarNode {
+						continue
+					}
+					img := parseImageString(imageNode.Value, path, imageNode.Line)
+					if img != nil {
+						img.ContainerName = containerName(item)
+						*images = append(*images, *img)
+					}
+				}
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		extractContainerImages(child, path, images)
 	}
 }
 
@@ -272,6 +1021,14 @@ func parseImageString(imageStr, path string, line int) *ImageInfo {
 	if strings.HasPrefix(imageStr, "/") || strings.HasPrefix(imageStr, ".") {
 		return nil
 	}
+	if strings.ContainsAny(imageStr, " \t\r\n") {
+		return nil
+	}
+	// Template expressions (e.g. "{{.Values.image.repository}}:{{.Values.image.tag}}"
+	// rendered with tpl) refer to the concrete values reported elsewhere
+	if strings.Contains(imageStr, "{{") {
+		return nil
+	}
 	if !strings.Contains(imageStr, "/") && !strings.Contains(imageStr, ":") {
 		return nil
 	}
@@ -283,6 +1040,15 @@ func parseImageString(imageStr, path string, line int) *ImageInfo {
 		Registry:  "docker.io",
 	}
 
+	// Split off a pinned digest ("name:tag@sha256:..." or "name@sha256:...")
+	if name, digest, ok := strings.Cut(imageStr, "@"); ok {
+		if !digestPattern.MatchString(digest) {
+			return nil
+		}
+		img.Digest = digest
+		imageStr = name
+	}
+
 	// Parse registry
 	parts := strings.SplitN(imageStr, "/", 2)
 	if len(parts) == 2 && (strings.Contains(parts[0], ".") || strings.Contains(parts[0], ":")) {
@@ -295,19 +1061,82 @@ func parseImageString(imageStr, path string, line int) *ImageInfo {
 		tagParts := strings.SplitN(imageStr, ":", 2)
 		img.Repository = tagParts[0]
 		img.Tag = tagParts[1]
-	} else {
+	} else if img.Digest == "" {
 		img.Repository = imageStr
 		img.Tag = "latest"
+	} else {
+		img.Repository = imageStr
 	}
 
-	// Mark skipped images
-	if strings.Contains(img.Repository, "thinkportgmbh") {
+	// Reject malformed references (empty components, stray separators)
+	if img.Repository == "" || (img.Tag == "" && img.Digest == "") || strings.Contains(img.Repository, ":") {
+		return nil
+	}
+	for _, segment := range strings.Split(img.Repository, "/") {
+		if segment == "" {
+			return nil
+		}
+	}
+
+	// Images pinned by digest alone have no tag to compare
+	if img.Tag == "" {
+		img.ByDigest = true
 		img.Skipped = true
 	}
 
 	return img
 }
 
+// isComposeFile checks if a filename is a docker compose file
+// Matches: compose.yaml, compose.yml, docker-compose.yaml, docker-compose.yml
+func isComposeFile(filename string) bool {
+	switch filename {
+	case "compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseComposeFile extracts the image of each service in a docker compose
+// file. Images using ${VAR} interpolation are skipped, as their value is
+// only known when compose runs.
+func parseComposeFile(path string) ([]ImageInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use yaml.Node to preserve line numbers
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	images := []ImageInfo{}
+	if len(root.Content) == 0 {
+		return images, nil
+	}
+
+	services := mappingValue(root.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return images, nil
+	}
+	for i := 0; i < len(services.Content)-1; i += 2 {
+		imageNode := mappingValue(services.Content[i+1], "image")
+		if imageNode == nil || imageNode.Kind != yaml.ScalarNode || strings.Contains(imageNode.Value, "$") {
+			continue
+		}
+		img := parseImageString(imageNode.Value, path, imageNode.Line)
+		if img != nil {
+			img.ContainerName = services.Content[i].Value
+			images = append(images, *img)
+		}
+	}
+
+	return images, nil
+}
+
 // isDockerfile checks if a filename is a Dockerfile
 // Matches: Dockerfile, *.dockerfile, Dockerfile.*
 func isDockerfile(filename string) bool {
@@ -340,8 +1169,8 @@ func parseDockerfile(path string) ([]ImageInfo, error) {
 	defer file.Close()
 
 	var images []ImageInfo
-	args := make(map[string]string)    // ARG name -> default value
-	aliases := make(map[string]bool)   // Stage aliases (FROM ... AS name)
+	args := make(map[string]string)  // ARG name -> default value
+	aliases := make(map[string]bool) // Stage aliases (FROM ... AS name)
 
 	// Regex patterns
 	argPattern := regexp.MustCompile(`^\s*ARG\s+(\w+)(?:=(.*))?$`)
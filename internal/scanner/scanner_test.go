@@ -3,7 +3,12 @@ package scanner
 import (
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestParseImageString(t *testing.T) {
@@ -57,12 +62,11 @@ func TestParseImageString(t *testing.T) {
 			wantNil: true, // Bare names without / or : are rejected
 		},
 		{
-			name:     "skipped thinkportgmbh image",
+			name:     "skip rules are applied by Scan",
 			input:    "thinkportgmbh/workshops:jupyter",
 			wantRepo: "thinkportgmbh/workshops",
 			wantTag:  "jupyter",
 			wantReg:  "docker.io",
-			wantSkip: true,
 		},
 		{
 			name:    "empty string",
@@ -79,6 +83,26 @@ func TestParseImageString(t *testing.T) {
 			input:   "/var/log/app",
 			wantNil: true,
 		},
+		{
+			name:    "empty tag",
+			input:   "nginx:",
+			wantNil: true,
+		},
+		{
+			name:    "registry without repository",
+			input:   "quay.io/",
+			wantNil: true,
+		},
+		{
+			name:    "trailing slash",
+			input:   "org/",
+			wantNil: true,
+		},
+		{
+			name:    "contains whitespace",
+			input:   "org/app :1.0",
+			wantNil: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -112,6 +136,76 @@ func TestParseImageString(t *testing.T) {
 	}
 }
 
+func TestParseImageStringDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		input        string
+		wantReg      string
+		wantRepo     string
+		wantTag      string
+		wantByDigest bool
+	}{
+		{"nginx@" + digest, "docker.io", "nginx", "", true},
+		{"ghcr.io/org/app@" + digest, "ghcr.io", "org/app", "", true},
+		{"ghcr.io/org/app:1.0@" + digest, "ghcr.io", "org/app", "1.0", false},
+		{"nginx:1.25.3-alpine@" + digest, "docker.io", "nginx", "1.25.3-alpine", false},
+		{"localhost:5000/app:2.1@" + digest, "localhost:5000", "app", "2.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := parseImageString(tt.input, "/test/path", 1)
+			if got == nil {
+				t.Fatal("expected non-nil result")
+			}
+			if got.Registry != tt.wantReg || got.Repository != tt.wantRepo || got.Tag != tt.wantTag {
+				t.Errorf("got %s/%s:%s, want %s/%s:%s", got.Registry, got.Repository, got.Tag, tt.wantReg, tt.wantRepo, tt.wantTag)
+			}
+			if got.Digest != digest {
+				t.Errorf("Digest = %q, want %q", got.Digest, digest)
+			}
+			if got.ByDigest != tt.wantByDigest || got.Skipped != tt.wantByDigest {
+				t.Errorf("ByDigest, Skipped = %v, %v, want %v", got.ByDigest, got.Skipped, tt.wantByDigest)
+			}
+		})
+	}
+
+	sha512 := "sha512:" + strings.Repeat("ab", 64)
+	if got := parseImageString("quay.io/org/app@"+sha512, "/test/path", 1); got == nil || got.Digest != sha512 || !got.ByDigest {
+		t.Errorf("sha512 digest parsed as %+v", got)
+	}
+	if got := parseImageString("nginx@sha256:abcd", "/test/path", 1); got != nil {
+		t.Errorf("truncated digest parsed as %+v, want nil", got)
+	}
+}
+
+func TestRepoUpstream(t *testing.T) {
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{"https://charts.bitnami.com/bitnami", "bitnami"},
+		{"oci://registry-1.docker.io/bitnamicharts", "bitnami"},
+		{"https://trinodb.github.io/charts/", "trinodb"},
+		{"https://prometheus-community.github.io/helm-charts", "prometheus-community"},
+		{"https://kubernetes.github.io/ingress-nginx", "ingress-nginx"},
+		{"https://grafana.github.io/helm-charts", "grafana"},
+		{"https://charts.jetstack.io", "cert-manager"},
+		{"https://charts.example.com", ""},
+		{"file://../common", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			if got := repoUpstream(tt.repository); got != tt.want {
+				t.Errorf("repoUpstream(%q) = %q, want %q", tt.repository, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDetectUpstream(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -147,7 +241,7 @@ func TestDetectUpstream(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := detectUpstream(tt.chart, tt.path)
+			result := detectUpstream(tt.chart, tt.path, nil)
 			if result != tt.expected {
 				t.Errorf("detectUpstream(%q, %q) = %q, want %q", tt.chart, tt.path, result, tt.expected)
 			}
@@ -190,7 +284,7 @@ sidecar:
 	}
 
 	// Run scan
-	results, err := Scan(tmpDir)
+	results, err := Scan(tmpDir, Options{})
 	if err != nil {
 		t.Fatalf("Scan() error = %v", err)
 	}
@@ -502,7 +596,7 @@ FROM alpine:3.19
 	}
 
 	// Run scan
-	results, err := Scan(tmpDir)
+	results, err := Scan(tmpDir, Options{})
 	if err != nil {
 		t.Fatalf("Scan() error = %v", err)
 	}
@@ -528,3 +622,1516 @@ FROM alpine:3.19
 		}
 	}
 }
+
+func TestParseValuesYAMLImageList(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `images: "nginx:1.21, redis:7.0"
+sidecar:
+  image: busybox:1.35
+other:
+  image: "not an image"
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+
+	want := []struct {
+		repo string
+		tag  string
+		line int
+	}{
+		{"nginx", "1.21", 1},
+		{"redis", "7.0", 1},
+		{"busybox", "1.35", 3},
+	}
+
+	if len(images) != len(want) {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(want), images)
+	}
+
+	for i, w := range want {
+		got := images[i]
+		if got.Repository != w.repo || got.Tag != w.tag || got.Line != w.line {
+			t.Errorf("image[%d] = %s:%s (line %d), want %s:%s (line %d)",
+				i, got.Repository, got.Tag, got.Line, w.repo, w.tag, w.line)
+		}
+	}
+}
+
+func TestSplitImageList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"nginx:1.21", 1},
+		{"nginx:1.21, redis:7.0", 2},
+		{"nginx:1.21 redis:7.0", 2},
+		{"quay.io/minio/minio:latest,ghcr.io/owner/repo:v1", 2},
+		{"not an image", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := splitImageList(tt.input)
+			if len(got) != tt.want {
+				t.Errorf("splitImageList(%q) = %v, want %d parts", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterRegistries(t *testing.T) {
+	results := &ScanResults{
+		Images: []ImageInfo{
+			{Registry: "registry.k8s.io", Repository: "pause"},
+			{Registry: "docker.io", Repository: "nginx"},
+			{Registry: "quay.io", Repository: "minio/minio"},
+		},
+		Charts: []ChartInfo{{Name: "app"}},
+	}
+
+	results.FilterRegistries(nil)
+	if len(results.Images) != 3 || len(results.Charts) != 1 {
+		t.Fatalf("empty filter changed results: %+v", results)
+	}
+
+	results.FilterRegistries([]string{"Registry.k8s.io", "quay.io"})
+	if len(results.Images) != 2 {
+		t.Errorf("got %d images, want 2", len(results.Images))
+	}
+	for _, img := range results.Images {
+		if img.Registry == "docker.io" {
+			t.Errorf("docker.io image %s not filtered out", img.Repository)
+		}
+	}
+	if len(results.Charts) != 0 {
+		t.Errorf("got %d charts, want 0", len(results.Charts))
+	}
+}
+
+func TestParseValuesSchema(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-schema-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	schema := `{
+  "type": "object",
+  "properties": {
+    "image": {
+      "type": "string",
+      "default": "ghcr.io/org/app:v1.2.0"
+    },
+    "logLevel": {
+      "type": "string",
+      "default": "info"
+    },
+    "url": {
+      "type": "string",
+      "default": "https://example.com/path"
+    },
+    "replicas": {
+      "type": "integer",
+      "default": 1
+    }
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabled by default
+	results, err := Scan(tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Images) != 0 {
+		t.Errorf("expected no images without ScanSchemas, got %d", len(results.Images))
+	}
+
+	results, err = Scan(tmpDir, Options{ScanSchemas: true})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Images) != 1 {
+		t.Fatalf("got %d images, want 1: %+v", len(results.Images), results.Images)
+	}
+
+	img := results.Images[0]
+	if img.Registry != "ghcr.io" || img.Repository != "org/app" || img.Tag != "v1.2.0" {
+		t.Errorf("image = %s/%s:%s, want ghcr.io/org/app:v1.2.0", img.Registry, img.Repository, img.Tag)
+	}
+	if img.Line != 6 {
+		t.Errorf("Line = %d, want 6", img.Line)
+	}
+}
+
+func TestParseValuesSchema_NoImages(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "values.schema-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(`{"type": "object", "properties": {}}`); err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+
+	images, err := parseValuesSchema(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("parseValuesSchema() error = %v", err)
+	}
+	if len(images) != 0 {
+		t.Errorf("got %d images, want 0", len(images))
+	}
+}
+
+func TestParseValuesYAMLGlobalImageDefaults(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `global:
+  imageRegistry: registry.example.com
+  imageTag: "2.4.1"
+app:
+  image:
+    repository: org/app
+worker:
+  image:
+    repository: quay.io/org/worker
+    tag: "1.0.0"
+metrics:
+  image: org/exporter:0.15.0
+  initImage: busybox:1.36
+operator:
+  image:
+    name: org/operator
+    tag: v0.5.0
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+	if len(images) != 5 {
+		t.Fatalf("got %d images, want 5: %+v", len(images), images)
+	}
+
+	// Registry-less, tag-less image inherits both globals
+	app := images[0]
+	if app.Registry != "registry.example.com" || app.Repository != "org/app" || app.Tag != "2.4.1" {
+		t.Errorf("app = %s/%s:%s, want registry.example.com/org/app:2.4.1", app.Registry, app.Repository, app.Tag)
+	}
+	if app.Line != 6 {
+		t.Errorf("app.Line = %d, want 6", app.Line)
+	}
+
+	// Explicit host and tag win over globals
+	worker := images[1]
+	if worker.Registry != "quay.io" || worker.Repository != "org/worker" || worker.Tag != "1.0.0" {
+		t.Errorf("worker = %s/%s:%s, want quay.io/org/worker:1.0.0", worker.Registry, worker.Repository, worker.Tag)
+	}
+
+	// Every other way of writing an image without a host gets the global
+	// registry too
+	for i, want := range []string{
+		"registry.example.com/org/exporter:0.15.0",
+		"registry.example.com/busybox:1.36",
+		"registry.example.com/org/operator:v0.5.0",
+	} {
+		img := images[i+2]
+		if got := img.Registry + "/" + img.Repository + ":" + img.Tag; got != want {
+			t.Errorf("image[%d] = %s, want %s", i+2, got, want)
+		}
+	}
+}
+
+func TestScanOnlyFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-files-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, dir := range []string{"changed", "untouched"} {
+		chartDir := filepath.Join(tmpDir, dir)
+		if err := os.MkdirAll(chartDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		chart := "name: " + dir + "\nversion: 1.0.0\n"
+		if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chart), 0644); err != nil {
+			t.Fatal(err)
+		}
+		values := "image: org/" + dir + ":1.0\n"
+		if err := os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(values), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(chartDir, "NOTES.txt"), []byte("notes"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A change to any file pulls in its sibling Chart.yaml and values.yaml
+	results, err := Scan(tmpDir, Options{Files: []string{filepath.Join(tmpDir, "changed", "NOTES.txt")}})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Charts) != 1 || results.Charts[0].Name != "changed" {
+		t.Errorf("charts = %+v, want only the changed chart", results.Charts)
+	}
+	if len(results.Images) != 1 || results.Images[0].Repository != "org/changed" {
+		t.Errorf("images = %+v, want only org/changed", results.Images)
+	}
+
+	// A change under templates/ pulls in the Chart.yaml and values.yaml above it
+	templates := filepath.Join(tmpDir, "changed", "templates")
+	if err := os.MkdirAll(templates, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templates, "deployment.yaml"), []byte("kind: Deployment\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err = Scan(tmpDir, Options{Files: []string{filepath.Join(templates, "deployment.yaml")}})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Charts) != 1 || results.Charts[0].Name != "changed" {
+		t.Errorf("charts = %+v, want the chart above templates/", results.Charts)
+	}
+	if len(results.Images) != 1 || results.Images[0].Repository != "org/changed" {
+		t.Errorf("images = %+v, want org/changed from the chart's values.yaml", results.Images)
+	}
+
+	// Scanning below a symlinked directory matches files reported by their
+	// real path, as git does
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(tmpDir, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	results, err = Scan(filepath.Join(link, "changed"), Options{Files: []string{filepath.Join(tmpDir, "changed", "NOTES.txt")}})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Charts) != 1 || results.Charts[0].Name != "changed" {
+		t.Errorf("charts via symlink = %+v, want only the changed chart", results.Charts)
+	}
+
+	// No changed files scans nothing
+	results, err = Scan(tmpDir, Options{Files: []string{}})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Charts) != 0 || len(results.Images) != 0 {
+		t.Errorf("expected empty results for no changed files, got %+v", results)
+	}
+}
+
+func TestParseValuesYAMLTagStyle(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `app:
+  image:
+    repository: org/app
+    tag: "01"
+worker:
+  image:
+    repository: org/worker
+    tag: 1.0
+sidecar:
+  image: 'busybox:1.35'
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+	if len(images) != 3 {
+		t.Fatalf("got %d images, want 3", len(images))
+	}
+
+	tests := []struct {
+		repo      string
+		wantRaw   string
+		wantStyle yaml.Style
+	}{
+		{"org/app", "01", yaml.DoubleQuotedStyle},
+		{"org/worker", "1.0", 0},
+		{"busybox", "1.35", yaml.SingleQuotedStyle},
+	}
+	for i, tt := range tests {
+		got := images[i]
+		if got.Repository != tt.repo {
+			t.Fatalf("image[%d].Repository = %q, want %q", i, got.Repository, tt.repo)
+		}
+		if got.RawTag != tt.wantRaw {
+			t.Errorf("%s RawTag = %q, want %q", tt.repo, got.RawTag, tt.wantRaw)
+		}
+		if got.TagStyle != tt.wantStyle {
+			t.Errorf("%s TagStyle = %v, want %v", tt.repo, got.TagStyle, tt.wantStyle)
+		}
+	}
+}
+
+func TestParseValuesYAMLListLines(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `extraContainers:
+  - name: sidecar
+    image: busybox:1.36
+  - name: exporter
+    image:
+      repository: prometheus/node-exporter
+      tag: v1.7.0
+  - {name: inline, image: "redis:7.2"}
+initContainers:
+  -
+    image: alpine:3.19
+  - image: >-
+      nginx:1.25
+jobs:
+  - containers:
+      - image:
+          repository: org/worker
+          tag: "2.0"
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+
+	want := []struct {
+		repo string
+		tag  string
+		line int
+	}{
+		{"busybox", "1.36", 3},
+		{"prometheus/node-exporter", "v1.7.0", 6},
+		{"redis", "7.2", 8},
+		{"alpine", "3.19", 11},
+		{"nginx", "1.25", 13},
+		{"org/worker", "2.0", 17},
+	}
+
+	if len(images) != len(want) {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(want), images)
+	}
+
+	for i, w := range want {
+		got := images[i]
+		if got.Repository != w.repo || got.Tag != w.tag || got.Line != w.line {
+			t.Errorf("image[%d] = %s:%s (line %d), want %s:%s (line %d)",
+				i, got.Repository, got.Tag, got.Line, w.repo, w.tag, w.line)
+		}
+	}
+}
+
+func TestParseChartYAMLMainChart(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-chart-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	write := func(rel, content string) string {
+		path := filepath.Join(tmpDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// A chart named like an upstream, but developed in this repository
+	local := write("trino/Chart.yaml", "name: trino\nversion: 0.1.0\nhome: https://example.com/our-platform\n")
+	// A copy of the upstream chart, recognizable by its sources
+	vendoredBySources := write("vendor/trino/Chart.yaml", "name: trino\nversion: 0.20.0\nsources:\n  - https://github.com/trinodb/charts\n")
+	// An upstream subchart unpacked into a parent chart
+	vendoredSubchart := write("app/charts/postgresql/Chart.yaml", "name: postgresql\nversion: 12.1.0\n")
+
+	tests := []struct {
+		name         string
+		path         string
+		checkMain    bool
+		wantUpstream string
+	}{
+		{"local chart skipped", local, false, ""},
+		{"local chart with --check-main-chart", local, true, "trinodb"},
+		{"vendored by sources", vendoredBySources, false, "trinodb"},
+		{"vendored subchart", vendoredSubchart, false, "bitnami"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charts, err := parseChartYAML(tt.path, Options{CheckMainChart: tt.checkMain})
+			if err != nil {
+				t.Fatalf("parseChartYAML() error = %v", err)
+			}
+			if got := charts[0].Upstream; got != tt.wantUpstream {
+				t.Errorf("main chart Upstream = %q, want %q", got, tt.wantUpstream)
+			}
+		})
+	}
+}
+
+func TestScanUpstreamsAndSkipImages(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(tmpDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("platform/Chart.yaml", `name: platform
+version: 1.0.0
+dependencies:
+  - name: keycloak
+    version: 18.0.0
+    repository: oci://registry.example.com/charts
+  - name: postgresql
+    version: 12.0.0
+    repository: https://charts.bitnami.com/bitnami
+`)
+	write("trino/Chart.yaml", "name: trino\nversion: 0.20.0\nsources:\n  - https://github.com/trinodb/charts\n")
+	write("platform/values.yaml", `workshop:
+  image: thinkportgmbh/workshops:jupyter
+app:
+  image: internal/app:1.0
+cache:
+  image: redis/redis:7.2
+`)
+
+	opts := Options{
+		Upstreams: map[string]string{
+			"platform":   "our-charts", // Main chart checked because it is configured
+			"keycloak":   "codecentric",
+			"postgresql": "bitnamilegacy", // Overrides the built-in rule
+			"trino":      "",              // Turns off the built-in rule
+		},
+		SkipImages: []string{"thinkportgmbh", "internal/"},
+	}
+	results, err := Scan(tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	upstreams := map[string]string{}
+	for _, chart := range results.Charts {
+		upstreams[chart.Name] = chart.Upstream
+	}
+	want := map[string]string{"platform": "our-charts", "keycloak": "codecentric", "postgresql": "bitnamilegacy", "trino": ""}
+	for name, upstream := range want {
+		if upstreams[name] != upstream {
+			t.Errorf("%s upstream = %q, want %q", name, upstreams[name], upstream)
+		}
+	}
+
+	skipped := map[string]bool{}
+	for _, img := range results.Images {
+		skipped[img.Repository] = img.Skipped
+	}
+	wantSkipped := map[string]bool{"thinkportgmbh/workshops": true, "internal/app": true, "redis/redis": false}
+	for repo, skip := range wantSkipped {
+		if skipped[repo] != skip {
+			t.Errorf("%s skipped = %v, want %v", repo, skipped[repo], skip)
+		}
+	}
+}
+
+func TestScanMaxFileSize(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-size-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for dir, values := range map[string]string{
+		"small": "image: org/small:1.0\n",
+		"large": "image: org/large:1.0\n" + strings.Repeat("# padding\n", 200),
+	} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, dir, "values.yaml"), []byte(values), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Scan(tmpDir, Options{MaxFileSize: 1024})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Images) != 1 || results.Images[0].Repository != "org/small" {
+		t.Errorf("images = %+v, want only org/small", results.Images)
+	}
+	if len(results.Warnings) != 1 || !strings.Contains(results.Warnings[0], filepath.Join("large", "values.yaml")) {
+		t.Errorf("warnings = %q, want one for large/values.yaml", results.Warnings)
+	}
+}
+
+func TestScanSymlinkCycle(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-symlink-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	chartDir := filepath.Join(tmpDir, "chart")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("image: org/app:1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory link back to its ancestor, and a values file linking to itself
+	if err := os.Symlink(tmpDir, filepath.Join(chartDir, "loop")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	loopDir := filepath.Join(tmpDir, "self")
+	if err := os.MkdirAll(loopDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("values.yaml", filepath.Join(loopDir, "values.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan *ScanResults, 1)
+	go func() {
+		results, err := Scan(tmpDir, Options{MaxFileSize: DefaultMaxFileSize})
+		if err != nil {
+			t.Errorf("Scan() error = %v", err)
+		}
+		done <- results
+	}()
+
+	select {
+	case results := <-done:
+		if results != nil && len(results.Images) != 1 {
+			t.Errorf("images = %+v, want org/app once", results.Images)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scan() did not finish with a symlink cycle")
+	}
+}
+
+func TestParseValuesYAMLTagDigest(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	valuesYAML := `app:
+  image:
+    repository: org/app
+    tag: "1.2.3@` + digest + `"
+worker:
+  image:
+    repository: ghcr.io/org/worker
+    tag: ` + digest + `
+sidecar:
+  image: busybox:1.35@` + digest + `
+proxy:
+  image:
+    registry: docker.io
+    repository: bitnami/nginx
+    tag: 1.25.3
+    digest: "` + digest + `"
+exporter:
+  image:
+    repository: bitnami/nginx-exporter
+    digest: ` + digest + `
+unpinned:
+  image:
+    repository: bitnami/redis
+    tag: 7.2.4
+    digest: ""
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+
+	tests := []struct {
+		wantReg  string
+		wantRepo string
+		wantTag  string
+		wantSkip bool
+	}{
+		{"docker.io", "org/app", "1.2.3", false},
+		{"ghcr.io", "org/worker", "", true}, // Nothing to compare without a tag
+		{"docker.io", "busybox", "1.35", false},
+		{"docker.io", "bitnami/nginx", "1.25.3", false},
+		{"docker.io", "bitnami/nginx-exporter", "", true},
+	}
+	if len(images) != len(tests)+1 {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(tests)+1, images)
+	}
+	if got := images[len(tests)]; got.Tag != "7.2.4" || got.Digest != "" {
+		t.Errorf("empty digest key gave tag %q digest %q, want 7.2.4 and no digest", got.Tag, got.Digest)
+	}
+	for i, tt := range tests {
+		got := images[i]
+		if got.Registry != tt.wantReg || got.Repository != tt.wantRepo || got.Tag != tt.wantTag {
+			t.Errorf("image[%d] = %s/%s:%s, want %s/%s:%s", i, got.Registry, got.Repository, got.Tag, tt.wantReg, tt.wantRepo, tt.wantTag)
+		}
+		if got.Digest != digest {
+			t.Errorf("image[%d].Digest = %q, want %q", i, got.Digest, digest)
+		}
+		if got.Skipped != tt.wantSkip {
+			t.Errorf("image[%d].Skipped = %v, want %v", i, got.Skipped, tt.wantSkip)
+		}
+	}
+}
+
+func TestParseValuesYAMLImageKeys(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `initImage: busybox:1.36
+kubectlImage: bitnami/kubectl:1.29.0
+sidecars:
+  - name: logger
+    image: fluent/fluent-bit:2.2.0
+operator:
+  image:
+    name: ghcr.io/org/operator
+    tag: v0.5.0
+volumePermissions:
+  image:
+    registry: docker.io
+    repository: bitnami/os-shell
+    tag: 12-debian-12-r16
+defaultBackendImage:
+  repository: registry.k8s.io/defaultbackend-amd64
+  tag: "1.5"
+pullPolicyImage: IfNotPresent
+backgroundImage: /static/bg.png
+tagline:
+  name: not-an-image
+  tag: v1
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+
+	want := []struct {
+		ref       string
+		line      int
+		container string
+	}{
+		{"docker.io/busybox:1.36", 1, ""},
+		{"docker.io/bitnami/kubectl:1.29.0", 2, ""},
+		{"docker.io/fluent/fluent-bit:2.2.0", 5, "logger"},
+		{"ghcr.io/org/operator:v0.5.0", 8, ""},
+		{"docker.io/bitnami/os-shell:12-debian-12-r16", 13, ""},
+		{"registry.k8s.io/defaultbackend-amd64:1.5", 16, ""},
+	}
+	if len(images) != len(want) {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(want), images)
+	}
+	for i, w := range want {
+		got := images[i]
+		if ref := got.Registry + "/" + got.Repository + ":" + got.Tag; ref != w.ref || got.Line != w.line || got.ContainerName != w.container {
+			t.Errorf("image[%d] = %s line %d container %q, want %s line %d container %q", i, ref, got.Line, got.ContainerName, w.ref, w.line, w.container)
+		}
+	}
+}
+
+func TestParseValuesYAMLRegistryKey(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name   string
+		values string
+		want   []string
+	}{
+		{
+			name: "bitnami layout",
+			values: `image:
+  registry: quay.io
+  repository: bitnami/postgresql
+  tag: 16.1.0
+metrics:
+  image:
+    tag: 11
+    repository: prometheuscommunity/postgres-exporter
+    registry: registry.example.com:5000/
+mirrored:
+  image:
+    registry: docker.io
+    repository: ghcr.io/org/app
+    tag: 1.0.0
+`,
+			want: []string{
+				"quay.io/bitnami/postgresql:16.1.0",
+				"registry.example.com:5000/prometheuscommunity/postgres-exporter:11",
+				"ghcr.io/org/app:1.0.0", // The repository's own host wins
+			},
+		},
+		{
+			name: "global registry takes precedence",
+			values: `global:
+  imageRegistry: mirror.example.com
+image:
+  registry: quay.io
+  repository: bitnami/postgresql
+  tag: 16.1.0
+`,
+			want: []string{"mirror.example.com/bitnami/postgresql:16.1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valuesPath := filepath.Join(tmpDir, "values.yaml")
+			if err := os.WriteFile(valuesPath, []byte(tt.values), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			images, err := parseValuesYAML(valuesPath)
+			if err != nil {
+				t.Fatalf("parseValuesYAML() error = %v", err)
+			}
+
+			var got []string
+			for _, img := range images {
+				got = append(got, img.Registry+"/"+img.Repository+":"+img.Tag)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("images = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValuesYAMLMergeKeys(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `defaults: &defaults
+  pullPolicy: IfNotPresent
+  repository: org/app
+  tag: "1.0.0"
+api:
+  image:
+    <<: *defaults
+    tag: "1.1.0"
+worker:
+  image:
+    <<: [*defaults]
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+
+	tests := []struct {
+		wantTag  string
+		wantLine int
+	}{
+		{"1.0.0", 3}, // The anchored block itself
+		{"1.1.0", 7}, // Local tag overrides the merged one
+		{"1.0.0", 11},
+	}
+	if len(images) != len(tests) {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(tests), images)
+	}
+	for i, tt := range tests {
+		got := images[i]
+		if got.Repository != "org/app" || got.Tag != tt.wantTag || got.Line != tt.wantLine {
+			t.Errorf("image[%d] = %s:%s line %d, want org/app:%s line %d",
+				i, got.Repository, got.Tag, got.Line, tt.wantTag, tt.wantLine)
+		}
+	}
+}
+
+func TestParseChartYAMLDependencyOf(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-chart-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	chartYAML := `apiVersion: v2
+name: mychart
+version: 0.1.0
+dependencies:
+  - name: postgresql
+    version: 12.0.0
+    repository: https://charts.bitnami.com/bitnami
+`
+	chartPath := filepath.Join(tmpDir, "Chart.yaml")
+	if err := os.WriteFile(chartPath, []byte(chartYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	charts, err := parseChartYAML(chartPath, Options{})
+	if err != nil {
+		t.Fatalf("parseChartYAML() error = %v", err)
+	}
+	if len(charts) != 2 {
+		t.Fatalf("got %d charts, want 2", len(charts))
+	}
+	if charts[0].DependencyOf != "" {
+		t.Errorf("mychart DependencyOf = %q, want empty", charts[0].DependencyOf)
+	}
+	if charts[1].Name != "postgresql" || charts[1].DependencyOf != "mychart" {
+		t.Errorf("dependency %s DependencyOf = %q, want postgresql of mychart", charts[1].Name, charts[1].DependencyOf)
+	}
+}
+
+func TestScanTemplatedImageNotDuplicated(t *testing.T) {
+	tmpDir := t.TempDir()
+	chartDir := filepath.Join(tmpDir, "app")
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"Chart.yaml": `name: app
+version: 1.0.0
+appVersion: "1.25.0"
+`,
+		// The concrete image, plus a tpl-rendered reference to it
+		"values.yaml": `image:
+  repository: nginx
+  tag: "1.25.0"
+sidecar:
+  image: "{{.Values.image.repository}}:{{.Values.image.tag}}"
+`,
+		"templates/deployment.yaml": `spec:
+  containers:
+    - name: app
+      image: "{{ .Values.image.repository }}:{{ .Values.image.tag | default .Chart.AppVersion }}"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Scan(tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(results.Images) != 1 {
+		t.Fatalf("got %d images, want only the concrete one: %+v", len(results.Images), results.Images)
+	}
+	img := results.Images[0]
+	if img.Repository != "nginx" || img.Tag != "1.25.0" || filepath.Base(img.Path) != "values.yaml" {
+		t.Errorf("image = %s:%s in %s, want nginx:1.25.0 from values.yaml", img.Repository, img.Tag, img.Path)
+	}
+}
+
+func TestParseValuesYAMLContainerNames(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-values-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	valuesYAML := `initContainers:
+  - name: init-db
+    image: busybox:1.35
+containers:
+  - name: app
+    image:
+      repository: org/app
+      tag: "1.2.3"
+  - name: proxy
+    image: nginx:1.25
+    sidecar:
+      image: envoyproxy/envoy:v1.28.0
+worker:
+  image: org/worker:2.0.0
+`
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte(valuesYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseValuesYAML(valuesPath)
+	if err != nil {
+		t.Fatalf("parseValuesYAML() error = %v", err)
+	}
+
+	tests := []struct {
+		wantRepo      string
+		wantContainer string
+	}{
+		{"busybox", "init-db"},
+		{"org/app", "app"},
+		{"nginx", "proxy"},
+		{"envoyproxy/envoy", ""}, // Nested image, not the container's own
+		{"org/worker", ""},
+	}
+	if len(images) != len(tests) {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(tests), images)
+	}
+	for i, tt := range tests {
+		got := images[i]
+		if got.Repository != tt.wantRepo || got.ContainerName != tt.wantContainer {
+			t.Errorf("image[%d] = %s (container %q), want %s (container %q)", i, got.Repository, got.ContainerName, tt.wantRepo, tt.wantContainer)
+		}
+	}
+}
+
+func TestScanManifests(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-manifest-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	deployment := `apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init-db
+          image: busybox:1.35
+      containers:
+        - name: app
+          image: ghcr.io/org/app:v1.2.0
+          env:
+            - name: MODE
+              value: prod
+        - name: proxy
+          image: "{{ .Values.proxy.image }}"
+`
+	// Helm templates are often not valid YAML and are skipped
+	template := `apiVersion: apps/v1
+kind: StatefulSet
+spec:
+  {{- if .Values.enabled }}
+  replicas: 1
+  {{- end }}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "deployment.yaml"), []byte(deployment), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "templates", "statefulset.yml"), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabled by default
+	results, err := Scan(tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(results.Images) != 0 {
+		t.Errorf("expected no images without ScanManifests, got %d", len(results.Images))
+	}
+
+	results, err = Scan(tmpDir, Options{ScanManifests: true})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	tests := []struct {
+		wantImage     string
+		wantLine      int
+		wantContainer string
+	}{
+		{"busybox:1.35", 15, "init-db"},
+		{"ghcr.io/org/app:v1.2.0", 18, "app"},
+	}
+	if len(results.Images) != len(tests) {
+		t.Fatalf("got %d images, want %d: %+v", len(results.Images), len(tests), results.Images)
+	}
+	for i, tt := range tests {
+		got := results.Images[i]
+		if got.FullImage != tt.wantImage || got.Line != tt.wantLine || got.ContainerName != tt.wantContainer {
+			t.Errorf("image[%d] = %s at line %d (container %q), want %s at line %d (container %q)",
+				i, got.FullImage, got.Line, got.ContainerName, tt.wantImage, tt.wantLine, tt.wantContainer)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"**/charts/**", "charts", true},
+		{"**/charts/**", "app/charts/redis/values.yaml", true},
+		{"**/charts/**", "app/values.yaml", false},
+		{"testdata/**", "testdata", true},
+		{"testdata/**", "testdata/a/values.yaml", true},
+		{"testdata/**", "app/testdata/values.yaml", false},
+		{"*/values.yaml", "app/values.yaml", true},
+		{"*/values.yaml", "app/sub/values.yaml", false},
+		{"**/Dockerfile.*", "build/Dockerfile.prod", true},
+		{"vendor", "vendor", true},
+		{"vendor", "vendor/values.yaml", false},
+		{"[", "[", false}, // Malformed pattern matches nothing
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipImage(t *testing.T) {
+	opts := Options{SkipImages: []string{"thinkportgmbh", "quay.io/internal/*", "**/*-dev:*"}}
+
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"thinkportgmbh/workshops:jupyter", true},
+		{"docker.io/thinkportgmbh/app:1.0", true},
+		{"quay.io/internal/api:2.0", true},
+		{"quay.io/internal/team/api:2.0", false}, // "*" doesn't cross "/"
+		{"quay.io/public/api:2.0", false},
+		{"org/app-dev:1.0", true},
+		{"ghcr.io/org/app-dev:1.0", true},
+		{"org/app:1.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := opts.skipImage(tt.ref); got != tt.want {
+			t.Errorf("skipImage(%q) = %v, want %v", tt.ref, got, tt.want)
+		}
+	}
+
+	if (Options{}).skipImage("thinkportgmbh/workshops:jupyter") {
+		t.Error("images are skipped without any SkipImages")
+	}
+}
+
+func TestScanExclude(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-exclude-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"app/values.yaml":        "image: org/app:1.0.0\n",
+		"app/Dockerfile.test":    "FROM golang:1.22\n",
+		"vendor/values.yaml":     "image: org/vendored:1.0.0\n",
+		"testdata/a/values.yaml": "image: org/fixture:1.0.0\n",
+		"vendor/dep/Chart.yaml":  "name: dep\nversion: 1.0.0\n",
+		"app/Chart.yaml":         "name: app\nversion: 1.0.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		opts       Options
+		want       []string
+		wantCharts []string
+	}{
+		{"no patterns", Options{}, []string{"golang", "org/app", "org/fixture", "org/vendored"}, []string{"app", "dep"}},
+		// "vendor" only matches the directory itself, so its files are
+		// skipped because the directory is not descended into
+		{"directory pruned", Options{Exclude: []string{"vendor", "testdata/**"}}, []string{"golang", "org/app"}, []string{"app"}},
+		{"single file", Options{Exclude: []string{"**/Dockerfile.*"}}, []string{"org/app", "org/fixture", "org/vendored"}, []string{"app", "dep"}},
+		{"only", Options{Only: []string{"app/**"}}, []string{"golang", "org/app"}, []string{"app"}},
+		{"only with exclude", Options{Only: []string{"**/values.yaml"}, Exclude: []string{"vendor/**"}}, []string{"org/app", "org/fixture"}, nil},
+		{"only no match", Options{Only: []string{"deploy/prod/**"}}, nil, nil},
+		{"exclude wins over files", Options{
+			Exclude: []string{"app/values.yaml"},
+			Files:   []string{filepath.Join(tmpDir, "app", "values.yaml")},
+		}, nil, []string{"app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Scan(tmpDir, tt.opts)
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			var got []string
+			for _, img := range results.Images {
+				got = append(got, img.Repository)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("images = %v, want %v", got, tt.want)
+			}
+			var charts []string
+			for _, c := range results.Charts {
+				charts = append(charts, c.Name)
+			}
+			if strings.Join(charts, ",") != strings.Join(tt.wantCharts, ",") {
+				t.Errorf("charts = %v, want %v", charts, tt.wantCharts)
+			}
+		})
+	}
+}
+
+func TestScanIgnoreFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-ignore-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		IgnoreFile: `# Vendored and test charts
+
+vendor
+  **/fixtures/**
+`,
+		"app/Chart.yaml":                  "name: app\nversion: 1.0.0\n",
+		"app/values.yaml":                 "image: org/app:1.0.0\n",
+		"vendor/dep/Chart.yaml":           "name: dep\nversion: 1.0.0\n",
+		"app/fixtures/broken/values.yaml": "image: org/fixture:1.0.0\n",
+		"testdata/values.yaml":            "image: org/testdata:1.0.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Scan(tmpDir, Options{Exclude: []string{"testdata/**"}})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var charts, images []string
+	for _, c := range results.Charts {
+		charts = append(charts, c.Name)
+	}
+	for _, img := range results.Images {
+		images = append(images, img.Repository)
+	}
+	if strings.Join(charts, ",") != "app" {
+		t.Errorf("charts = %v, want only app", charts)
+	}
+	if strings.Join(images, ",") != "org/app" {
+		t.Errorf("images = %v, want only org/app", images)
+	}
+}
+
+func TestScanGitignore(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-gitignore-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		GitignoreFile: `# Build output
+dist/
+/tmp
+legacy-*
+!legacy-keep
+`,
+		".git/Chart.yaml":             "name: planted\nversion: 1.0.0\n",
+		".git/refs/values.yaml":       "image: org/git:1.0.0\n",
+		"app/Chart.yaml":              "name: app\nversion: 1.0.0\n",
+		"app/values.yaml":             "image: org/app:1.0.0\n",
+		"app/dist/Chart.yaml":         "name: dist\nversion: 1.0.0\n",
+		"tmp/values.yaml":             "image: org/tmp:1.0.0\n",
+		"app/tmp/values.yaml":         "image: org/nested-tmp:1.0.0\n",
+		"app/legacy-v1/values.yaml":   "image: org/legacy:1.0.0\n",
+		"app/legacy-keep/values.yaml": "image: org/kept:1.0.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(opts Options) (charts, images []string) {
+		t.Helper()
+		results, err := Scan(tmpDir, opts)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		for _, c := range results.Charts {
+			charts = append(charts, c.Name)
+		}
+		for _, img := range results.Images {
+			images = append(images, img.Repository)
+		}
+		sort.Strings(charts)
+		sort.Strings(images)
+		return charts, images
+	}
+
+	// .git is skipped even without the option
+	charts, images := scan(Options{})
+	if strings.Join(charts, ",") != "app,dist" {
+		t.Errorf("charts = %v, want app,dist", charts)
+	}
+	if strings.Contains(strings.Join(images, ","), "org/git") {
+		t.Errorf("images = %v, want nothing from .git", images)
+	}
+
+	charts, images = scan(Options{Gitignore: true})
+	if strings.Join(charts, ",") != "app" {
+		t.Errorf("charts = %v, want only app", charts)
+	}
+	if got, want := strings.Join(images, ","), "org/app,org/kept,org/nested-tmp"; got != want {
+		t.Errorf("images = %v, want %v", got, want)
+	}
+}
+
+func TestGitignored(t *testing.T) {
+	rules := parseGitignore([]string{"*.log", "!important.log", "build/", "/root-only", "docs/**/*.md", `\!bang`})
+	tests := []struct {
+		rel  string
+		dir  bool
+		want bool
+	}{
+		{"debug.log", false, true},
+		{"a/b/debug.log", false, true},
+		{"a/important.log", false, false},
+		{"build", true, true},
+		{"a/build", true, true},
+		{"build", false, false},
+		{"root-only", false, true},
+		{"a/root-only", false, false},
+		{"docs/x/y.md", false, true},
+		{"other/docs/y.md", false, false},
+		{"!bang", false, true},
+		{"values.yaml", false, false},
+	}
+	for _, tt := range tests {
+		if got := gitignored(rules, tt.rel, tt.dir); got != tt.want {
+			t.Errorf("gitignored(%q, dir=%v) = %v, want %v", tt.rel, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlobPrefix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		want    bool
+	}{
+		{"deploy/prod/**", "deploy", true},
+		{"deploy/prod/**", "deploy/prod", true},
+		{"deploy/prod/**", "deploy/prod/a/b", true},
+		{"deploy/prod/**", "deploy/dev", false},
+		{"deploy/prod/**", "vendor", false},
+		{"*/values.yaml", "app", true},
+		{"*/values.yaml", "app/sub", false},
+		{"**/values.yaml", "a/b/c", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlobPrefix(tt.pattern, tt.dir); got != tt.want {
+			t.Errorf("matchGlobPrefix(%q, %q) = %v, want %v", tt.pattern, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestScanComposeFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-compose-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	compose := `services:
+  web:
+    image: nginx:1.25
+    ports:
+      - "8080:80"
+  db:
+    environment:
+      POSTGRES_DB: app
+    image: ghcr.io/org/postgres:16.1
+  app:
+    build: .
+  worker:
+    image: org/worker:${WORKER_TAG:-1.0.0}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Scan(tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	tests := []struct {
+		wantReg     string
+		wantRepo    string
+		wantTag     string
+		wantLine    int
+		wantService string
+	}{
+		{"docker.io", "nginx", "1.25", 3, "web"},
+		{"ghcr.io", "org/postgres", "16.1", 9, "db"},
+	}
+	if len(results.Images) != len(tests) {
+		t.Fatalf("got %d images, want %d: %+v", len(results.Images), len(tests), results.Images)
+	}
+	for i, tt := range tests {
+		got := results.Images[i]
+		if got.Registry != tt.wantReg || got.Repository != tt.wantRepo || got.Tag != tt.wantTag {
+			t.Errorf("image[%d] = %s/%s:%s, want %s/%s:%s", i, got.Registry, got.Repository, got.Tag, tt.wantReg, tt.wantRepo, tt.wantTag)
+		}
+		if got.Line != tt.wantLine {
+			t.Errorf("image[%d].Line = %d, want %d", i, got.Line, tt.wantLine)
+		}
+		if got.ContainerName != tt.wantService {
+			t.Errorf("image[%d].ContainerName = %q, want %q", i, got.ContainerName, tt.wantService)
+		}
+	}
+}
+
+func TestParseManifestHelmTemplate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "chartup-manifest-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	template := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "app.fullname" . }}
+spec:
+  template:
+    spec:
+      {{- if .Values.migrations.enabled }}
+      initContainers:
+        - name: migrate
+          image: migrate/migrate:v4.17.0
+      {{- end }}
+      containers:
+        - name: app
+          image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+        - name: metrics
+          image: prom/statsd-exporter:v0.26.0
+`
+	path := filepath.Join(tmpDir, "deployment.yaml")
+	if err := os.WriteFile(path, []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := parseManifest(path)
+	if err != nil {
+		t.Fatalf("parseManifest() error = %v", err)
+	}
+
+	tests := []struct {
+		wantImage     string
+		wantLine      int
+		wantContainer string
+	}{
+		{"migrate/migrate:v4.17.0", 11, "migrate"},
+		{"prom/statsd-exporter:v0.26.0", 17, "metrics"},
+	}
+	if len(images) != len(tests) {
+		t.Fatalf("got %d images, want %d: %+v", len(images), len(tests), images)
+	}
+	for i, tt := range tests {
+		got := images[i]
+		if got.FullImage != tt.wantImage || got.Line != tt.wantLine || got.ContainerName != tt.wantContainer {
+			t.Errorf("image[%d] = %s at line %d (container %q), want %s at line %d (container %q)",
+				i, got.FullImage, got.Line, got.ContainerName, tt.wantImage, tt.wantLine, tt.wantContainer)
+		}
+	}
+}
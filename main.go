@@ -1,20 +1,40 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/nogo/chartup/internal/cache"
 	"github.com/nogo/chartup/internal/checker"
+	"github.com/nogo/chartup/internal/config"
+	"github.com/nogo/chartup/internal/gitdiff"
+	"github.com/nogo/chartup/internal/lock"
 	"github.com/nogo/chartup/internal/output"
-	"github.com/nogo/chartup/internal/scanner"
+	"github.com/nogo/chartup/internal/registry"
+	"github.com/nogo/chartup/pkg/chartup"
 )
 
 var version = "dev"
 
+// stringList is a repeatable string flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `chartup - Check Helm charts and Docker images for updates
 
@@ -23,9 +43,90 @@ Usage:
 
 Options:
   --verbose           Show all items (default: only updates)
+  --level <level>     Largest update to report: patch, minor, or major
+                      (default: major, all updates). Larger updates count
+                      as up to date; --verbose lists them as OK
+  --count-only        Print only the number of available updates
+  --format <fmt>      Output format: table, line, json, jsonl, yaml, sarif,
+                      markdown, delta (default: table). line prints one update
+                      per line; sarif reports updates for code scanning;
+                      markdown prints tables for PR comments; delta shows
+                      only items whose latest changed since the last cached run
+  --output <fmt>      Alias for --format
+  --triage            Group errors and updates by severity: errors, then
+                      major, minor, and patch updates
+  --exit-code         Exit non-zero when updates are available or checks
+                      failed (see Exit codes)
+  --fail-on-updates   Exit 1 when updates are available
+  --fail-on-error     Exit 1 when some checks failed
   --refresh           Refresh cache with fresh lookups
+  --cache-file <path> Cache file (default: $XDG_CACHE_HOME/chartup/cache.json,
+                      or ~/.cache/chartup/cache.json)
+  --cache-dir <dir>   Keep the cache as cache.json in this directory
+                      (--cache-file wins if both are set)
+  --offline           Only use the cache, however old; never query registries
+  --require-cache     With --offline, fail if a lookup is missing from the cache
+  --cache-clear       Remove the cache file and exit
+  --cache-ttl <d>     Use cached lookups younger than this, e.g. 24h
+                      (default: 1h, 0 = always look up)
+  --cache-max-age <d> Prune cache entries older than this (default: 720h, 0 = never)
   --editor <name>     Editor for clickable links (default: auto-detect)
-                      Options: vscode, cursor, idea, sublime, zed, none
+                      Options: vscode, cursor, idea, gateway, sublime, zed, none
+  --open-first-update Open the first update in the editor's command line tool
+  --registry <host>   Only check images on this registry (repeatable)
+  --changed           Only scan files changed in git (and their chart's Chart.yaml)
+  --changed-base <ref> Base for --changed (default: merge-base with default branch)
+  --scan-schemas      Also check image defaults in values.schema.json
+  --scan-manifests    Check container images in Kubernetes manifests
+                      (any other .yaml/.yml file, including templates/).
+                      On by default; --scan-manifests=false to skip
+  --check-main-chart  Check each chart's own version, not only vendored copies
+  --check-app-releases Compare each chart's appVersion with the latest GitHub
+                      release of its sources (set GITHUB_TOKEN for higher limits)
+  --max-file-size <n> Skip files larger than n bytes (default: 5242880, 0 = no limit)
+  --exclude <glob>    Skip paths matching this glob, relative to the scanned
+                      directory, e.g. '**/charts/**' (repeatable)
+  --ignore <glob>     Same as --exclude
+  --only <glob>       Only scan paths matching this glob, relative to the
+                      scanned directory, e.g. 'deploy/prod/**' (repeatable)
+  --gitignore         Skip paths ignored by the scanned directory's .gitignore
+  --skip-image <pattern> Don't check images whose reference contains this
+                      string, or matches it as a glob (repeatable)
+  --registry-map-repo <old=new> Look up updates for a renamed image at
+                      its new repository (repeatable)
+  --registry-prefer-digest Show the digest of each latest tag for pinning
+                      (one extra request per image)
+  --registry-only-semver Ignore non-version tags for all images
+                      (always on for images tagged X.Y.Z)
+  --concurrency <n>   Image and chart lookups to run at once (default: 8)
+  --max-concurrency-docker-hub <n> Concurrent docker.io lookups (default: 2,
+                      0 = no limit)
+  --docker-hub-max-pages <n> Pages of 100 tags to read per Docker Hub
+                      repository (default: 5)
+  --timeout <d>       Timeout of each registry request (default: 10s)
+  --registry-timeout <host=d> Request timeout for a registry host and its
+                      subdomains, e.g. ghcr.io=30s (repeatable, default: --timeout)
+  --retries <n>       Retry registry requests that failed with a 5xx status
+                      or connection error n times, with backoff (default: 3)
+  --lookup-budget <d> Give up on a lookup after this long in total, across
+                      token exchanges and follow-up requests (0 = no limit)
+  --docker-config <path> Authenticate registry lookups with the "auths"
+                      entries of a Docker config.json
+  --registry-endpoint-override <host> List docker.io tags through the registry
+                      API on this host, e.g. registry-1.docker.io or a mirror
+  --registry-mirror <registry=mirror> Look up images of a registry on a
+                      mirror, e.g. docker.io=mirror.corp.net (repeatable)
+  --insecure-registry <host> Skip TLS verification and allow plain HTTP for
+                      this registry host only (repeatable)
+  --write-lock <file> Record resolved latest versions to a lock file
+  --baseline <file>   Only report changes since a --write-lock file
+  --lock <file>       Report differences from a --write-lock file instead of results
+  --no-color          Print no colors or hyperlinks. Also set by NO_COLOR, and
+                      when stdout is not a terminal
+  --debug-links       Log generated editor and registry URLs to stderr
+  --debug             Log registry lookup decisions (e.g. fuzzy chart matches) to stderr
+  --config <path>     Config file (default: <directory>/.chartup.yaml)
+  --print-config      Print the effective configuration as YAML and exit
   --version           Show version
   --help              Show this help
 
@@ -34,9 +135,28 @@ Examples:
   chartup /path/to/charts        Scan specific directory
   chartup --refresh .            Force fresh lookups and update cache
   chartup --editor idea .        Use IntelliJ IDEA for links
+  chartup --count-only .         Print the update count, e.g. for $(...)
+  chartup --changed .            Only check charts touched on this branch
+  chartup --baseline chartup.lock .
+                                 Show only what changed since the lock
+  chartup --registry registry.k8s.io --verbose .
+                                 Audit all registry.k8s.io images
+
+Exit codes:
+  0  Success; with --exit-code, everything is up to date
+  1  Scanning or another fatal error; with --fail-on-updates or
+     --fail-on-error, updates are available or checks failed
+  2  With --exit-code, updates are available
+  3  With --exit-code, some checks failed (takes precedence over 2)
+  130 Interrupted with Ctrl-C; partial results were printed
+
+Configuration precedence (lowest to highest):
+  built-in defaults, config file, CHARTUP_* environment variables, flags
+  Environment: CHARTUP_CACHE_FILE, CHARTUP_CACHE_TTL, CHARTUP_EDITOR
 
 Supported registries:
-  Docker Hub, Quay.io, ghcr.io, gcr.io, registry.k8s.io
+  Docker Hub, Quay.io, ghcr.io, gcr.io, registry.k8s.io, Amazon ECR, and
+  other OCI Distribution registries (e.g. Harbor, Artifactory)
 
 `)
 }
@@ -45,8 +165,67 @@ func main() {
 	flag.Usage = printUsage
 
 	verbose := flag.Bool("verbose", false, "")
+	level := flag.String("level", "", "")
+	countOnly := flag.Bool("count-only", false, "")
+	triage := flag.Bool("triage", false, "")
+	exitCode := flag.Bool("exit-code", false, "")
+	failOnUpdates := flag.Bool("fail-on-updates", false, "")
+	failOnError := flag.Bool("fail-on-error", false, "")
+	format := flag.String("format", "table", "")
+	flag.StringVar(format, "output", "table", "")
 	refresh := flag.Bool("refresh", false, "")
+	offline := flag.Bool("offline", false, "")
+	requireCache := flag.Bool("require-cache", false, "")
+	cacheFile := flag.String("cache-file", "", "")
+	cacheDir := flag.String("cache-dir", "", "")
+	cacheClear := flag.Bool("cache-clear", false, "")
+	cacheMaxAge := flag.Duration("cache-max-age", 0, "")
+	cacheTTL := flag.Duration("cache-ttl", 0, "")
 	editor := flag.String("editor", "", "")
+	scanSchemas := flag.Bool("scan-schemas", false, "")
+	scanManifests := flag.Bool("scan-manifests", true, "")
+	gitignore := flag.Bool("gitignore", false, "")
+	onlySemver := flag.Bool("registry-only-semver", false, "")
+	preferDigest := flag.Bool("registry-prefer-digest", false, "")
+	checkMainChart := flag.Bool("check-main-chart", false, "")
+	checkAppReleases := flag.Bool("check-app-releases", false, "")
+	maxFileSize := flag.Int64("max-file-size", 0, "")
+	concurrency := flag.Int("concurrency", 0, "")
+	maxConcurrencyDockerHub := flag.Int("max-concurrency-docker-hub", 0, "")
+	dockerHubMaxPages := flag.Int("docker-hub-max-pages", 0, "")
+	retries := flag.Int("retries", 0, "")
+	timeout := flag.Duration("timeout", 0, "")
+	var registryTimeouts stringList
+	flag.Var(&registryTimeouts, "registry-timeout", "")
+	lookupBudget := flag.Duration("lookup-budget", 0, "")
+	dockerConfig := flag.String("docker-config", "", "")
+	endpointOverride := flag.String("registry-endpoint-override", "", "")
+	var registryMirrors stringList
+	flag.Var(&registryMirrors, "registry-mirror", "")
+	var insecureRegistries stringList
+	flag.Var(&insecureRegistries, "insecure-registry", "")
+	var registries stringList
+	flag.Var(&registries, "registry", "")
+	var excludes stringList
+	flag.Var(&excludes, "exclude", "")
+	flag.Var(&excludes, "ignore", "")
+	var only stringList
+	flag.Var(&only, "only", "")
+	var skipImages stringList
+	flag.Var(&skipImages, "skip-image", "")
+	var repoRewrites stringList
+	flag.Var(&repoRewrites, "registry-map-repo", "")
+	noColor := flag.Bool("no-color", false, "")
+	debugLinks := flag.Bool("debug-links", false, "")
+	debug := flag.Bool("debug", false, "")
+	openFirst := flag.Bool("open-first-update", false, "")
+	changed := flag.Bool("changed", false, "")
+	changedBase := flag.String("changed-base", "", "")
+	writeLock := flag.String("write-lock", "", "")
+	baseline := flag.String("baseline", "", "")
+	lockFile := flag.String("lock", "", "")
+	configFile := flag.String("config", "", "")
+	printConfig := flag.Bool("print-config", false, "")
 	showVersion := flag.Bool("version", false, "")
 	showHelp := flag.Bool("help", false, "")
 	flag.Parse()
@@ -61,6 +240,25 @@ func main() {
 		os.Exit(0)
 	}
 
+	switch *format {
+	case "table", "line", "json", "jsonl", "yaml", "sarif", "markdown", "delta":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (use table, line, json, jsonl, yaml, sarif, markdown, or delta)\n", *format)
+		os.Exit(1)
+	}
+	if *triage && *format != "table" {
+		fmt.Fprintln(os.Stderr, "Error: --triage only applies to table output")
+		os.Exit(1)
+	}
+	// Machine-readable output must not be mixed with progress messages, so
+	// they go to stderr for structured formats and line output, which is
+	// read by scripts and grep, and are left out for a count
+	structured := *format == "json" || *format == "jsonl" || *format == "yaml" || *format == "sarif" || *format == "markdown" || *format == "line"
+	progress := os.Stdout
+	if structured {
+		progress = os.Stderr
+	}
+
 	// Get directory to scan
 	dir := "."
 	if flag.NArg() > 0 {
@@ -78,43 +276,273 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Initialize cache (1 hour TTL)
-	c := cache.New(".chartup-cache.json", 1*time.Hour, *refresh)
-	if err := c.Load(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not load cache: %v\n", err)
+	// Build effective configuration: defaults < config file < env < flags
+	cfgPath := *configFile
+	if cfgPath == "" {
+		cfgPath = config.Find(dir)
 	}
-
-	// Scan directory for charts and images
-	fmt.Printf("Scanning %s for Helm charts and Docker images...\n\n", dir)
-	results, err := scanner.Scan(dir)
+	cfg, err := config.Load(cfgPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error scanning directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in environment: %v\n", err)
+		os.Exit(1)
+	}
+	// Visit goes in lexical order, so --cache-file overrides --cache-dir
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "cache-dir":
+			cfg.CacheFile = filepath.Join(*cacheDir, config.CacheFilename)
+		case "cache-file":
+			cfg.CacheFile = *cacheFile
+		case "level":
+			cfg.Level = *level
+		case "verbose":
+			cfg.Verbose = *verbose
+		case "refresh":
+			cfg.Refresh = *refresh
+		case "offline":
+			cfg.Offline = *offline
+		case "require-cache":
+			cfg.RequireCache = *requireCache
+		case "cache-max-age":
+			cfg.CacheMaxAge = *cacheMaxAge
+		case "cache-ttl":
+			cfg.CacheTTL = *cacheTTL
+		case "editor":
+			cfg.Editor = *editor
+		case "registry":
+			cfg.Registries = registries
+		case "scan-schemas":
+			cfg.ScanSchemas = *scanSchemas
+		case "scan-manifests":
+			cfg.ScanManifests = *scanManifests
+		case "exclude", "ignore":
+			cfg.Exclude = excludes
+		case "only":
+			cfg.Only = only
+		case "gitignore":
+			cfg.Gitignore = *gitignore
+		case "skip-image":
+			cfg.SkipImages = skipImages
+		case "registry-only-semver":
+			cfg.OnlySemver = *onlySemver
+		case "registry-prefer-digest":
+			cfg.PreferDigest = *preferDigest
+		case "check-main-chart":
+			cfg.CheckMainChart = *checkMainChart
+		case "check-app-releases":
+			cfg.CheckAppReleases = *checkAppReleases
+		case "max-file-size":
+			cfg.MaxFileSize = *maxFileSize
+		case "concurrency":
+			cfg.Concurrency = *concurrency
+		case "max-concurrency-docker-hub":
+			cfg.MaxConcurrencyDockerHub = *maxConcurrencyDockerHub
+		case "docker-hub-max-pages":
+			cfg.DockerHubMaxPages = *dockerHubMaxPages
+		case "retries":
+			cfg.Retries = *retries
+		case "timeout":
+			cfg.Timeout = *timeout
+		case "registry-timeout":
+			if cfg.RegistryTimeouts == nil {
+				cfg.RegistryTimeouts = map[string]time.Duration{}
+			}
+			for _, override := range registryTimeouts {
+				host, value, ok := strings.Cut(override, "=")
+				timeout, err := time.ParseDuration(value)
+				if !ok || host == "" || err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid --registry-timeout %q (want host=duration)\n", override)
+					os.Exit(1)
+				}
+				cfg.RegistryTimeouts[host] = timeout
+			}
+		case "lookup-budget":
+			cfg.LookupBudget = *lookupBudget
+		case "docker-config":
+			cfg.DockerConfig = *dockerConfig
+		case "registry-endpoint-override":
+			cfg.RegistryEndpointOverride = *endpointOverride
+		case "registry-mirror":
+			if cfg.RegistryMirrors == nil {
+				cfg.RegistryMirrors = map[string]string{}
+			}
+			for _, mapping := range registryMirrors {
+				host, mirror, ok := strings.Cut(mapping, "=")
+				if !ok || host == "" || mirror == "" {
+					fmt.Fprintf(os.Stderr, "Error: invalid --registry-mirror %q (want registry=mirror)\n", mapping)
+					os.Exit(1)
+				}
+				cfg.RegistryMirrors[host] = mirror
+			}
+		case "insecure-registry":
+			cfg.InsecureRegistries = insecureRegistries
+		case "registry-map-repo":
+			if cfg.RepoRewrites == nil {
+				cfg.RepoRewrites = map[string]string{}
+			}
+			for _, rewrite := range repoRewrites {
+				from, to, ok := strings.Cut(rewrite, "=")
+				if !ok || from == "" || to == "" {
+					fmt.Fprintf(os.Stderr, "Error: invalid --registry-map-repo %q (want old=new)\n", rewrite)
+					os.Exit(1)
+				}
+				cfg.RepoRewrites[from] = to
+			}
+		}
+	})
+
+	if *printConfig {
+		data, err := cfg.YAML()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+		os.Exit(0)
+	}
+
+	if cfg.RequireCache && !cfg.Offline {
+		fmt.Fprintln(os.Stderr, "Error: --require-cache requires --offline")
+		os.Exit(1)
+	}
+	if cfg.Offline && cfg.Refresh {
+		fmt.Fprintln(os.Stderr, "Error: --refresh cannot be used with --offline")
+		os.Exit(1)
+	}
+	if _, ok := registry.ParseBump(cfg.Level); !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown level %q (use patch, minor, or major)\n", cfg.Level)
 		os.Exit(1)
 	}
 
-	if len(results.Charts) == 0 && len(results.Images) == 0 {
-		fmt.Println("No Helm charts or Docker images found.")
+	if *cacheClear {
+		if err := cache.New(cfg.CacheFile, cfg.CacheTTL, false).Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing cache: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cache cleared: %s\n", cfg.CacheFile)
 		os.Exit(0)
 	}
 
-	// Check for updates
-	chk := checker.New(c)
-	updateResults, err := chk.CheckAll(results)
+	var files []string
+	if *changed {
+		files, err = gitdiff.ChangedFiles(dir, *changedBase, gitdiff.ExecRunner)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Scan directory for charts and images, and check them for updates
+	if !*countOnly {
+		fmt.Fprintf(progress, "Scanning %s for Helm charts and Docker images...\n\n", dir)
+	}
+	if *debug {
+		registry.SetDebugOutput(os.Stderr)
+	}
+	// Ctrl-C stops the lookups and prints what was checked so far. Once the
+	// run has stopped, it exits right away again.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	updateResults, err := chartup.Run(ctx, chartup.Options{
+		Dir:               dir,
+		Config:            cfg,
+		Files:             files,
+		DeferLevel:        true,
+		DockerHubUsername: os.Getenv("DOCKERHUB_USERNAME"),
+		DockerHubToken:    os.Getenv("DOCKERHUB_TOKEN"),
+		Warn: func(warning string) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		},
+	})
+	interrupted := ctx.Err() != nil
+	stop()
 	if err != nil {
-		if checker.IsRateLimitError(err) {
+		if interrupted {
+			fmt.Fprintf(os.Stderr, "\nInterrupted. Partial results shown below.\n\n")
+		} else if checker.IsRateLimitError(err) {
+			var rateErr *registry.RateLimitError
+			errors.As(err, &rateErr)
 			fmt.Fprintf(os.Stderr, "\nError: Rate limit hit. Partial results shown below.\n")
-			fmt.Fprintf(os.Stderr, "Try again later. Cached results will be used for 1 hour.\n\n")
+			if checker.NeedsLogin(err) {
+				fmt.Fprintf(os.Stderr, "Docker Hub limits anonymous requests. Run 'docker login' or set\n")
+				fmt.Fprintf(os.Stderr, "DOCKERHUB_USERNAME and DOCKERHUB_TOKEN to authenticate.\n")
+			} else if rateErr != nil && rateErr.Err != nil {
+				fmt.Fprintf(os.Stderr, "Authenticated retry failed: %v\n", rateErr.Err)
+			}
+			when := "later"
+			if rateErr != nil && !rateErr.ResetAt.IsZero() {
+				when = "in " + waitFor(time.Until(rateErr.ResetAt))
+			}
+			fmt.Fprintf(os.Stderr, "Try again %s. Cached results will be used for %s.\n\n", when, cfg.CacheTTL)
+		} else if errors.Is(err, checker.ErrCacheMiss) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Run without --offline to fill the cache.\n")
+			os.Exit(1)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(updateResults.Charts) == 0 && len(updateResults.Images) == 0 {
+		if *countOnly {
+			fmt.Println(0)
+		} else if structured {
+			printResults(*format, &checker.Results{})
 		} else {
-			fmt.Fprintf(os.Stderr, "Error checking updates: %v\n", err)
+			fmt.Println("No Helm charts or Docker images found.")
+		}
+		os.Exit(0)
+	}
+
+	// Record resolved versions for later comparison. A run cut short by
+	// Ctrl-C or a rate limit would record failed lookups as the baseline.
+	if *writeLock != "" && err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not writing %s, the check did not complete\n", *writeLock)
+	} else if *writeLock != "" {
+		if err := lock.FromResults(updateResults, dir).Write(*writeLock); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing lock file: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	// Save cache
-	if err := c.Save(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not save cache: %v\n", err)
+	// Report drift against a committed lock instead of the results
+	if *lockFile != "" {
+		locked, err := lock.Read(*lockFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading lock file: %v\n", err)
+			os.Exit(1)
+		}
+		changes := lock.Diff(locked, lock.FromResults(updateResults, dir))
+		if len(changes) == 0 {
+			fmt.Printf("No drift from %s.\n", *lockFile)
+			return
+		}
+		fmt.Printf("Drift from %s:\n", *lockFile)
+		for _, change := range changes {
+			fmt.Printf("  %s\n", change)
+		}
+		return
 	}
 
+	// Only report what changed since the baseline
+	if *baseline != "" {
+		base, err := lock.Read(*baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading baseline: %v\n", err)
+			os.Exit(1)
+		}
+		updateResults = lock.FilterChanged(updateResults, base, dir)
+	}
+
+	// Report updates larger than --level as up to date; the lock above
+	// records them as found
+	maxBump, _ := registry.ParseBump(cfg.Level)
+	updateResults.ApplyLevel(maxBump)
+
 	// Set base directory for relative path display
 	absDir, err := filepath.Abs(dir)
 	if err == nil {
@@ -122,13 +550,118 @@ func main() {
 	}
 
 	// Set editor for file links
-	if *editor != "" {
-		output.SetEditor(*editor)
+	if cfg.Editor != "" {
+		output.SetEditor(cfg.Editor)
 	}
 
 	// Set verbose mode
-	output.SetVerbose(*verbose)
+	output.SetVerbose(cfg.Verbose)
+	output.SetColor(!*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout))
+	output.SetDebugLinks(*debugLinks)
 
 	// Output results
-	output.PrintTable(updateResults)
+	if *countOnly {
+		output.PrintCount(updateResults)
+	} else if *triage {
+		output.PrintTriage(updateResults)
+	} else {
+		printResults(*format, updateResults)
+	}
+
+	if *openFirst {
+		if _, err := output.OpenFirstUpdate(updateResults, output.ExecCommandRunner); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening editor: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if interrupted {
+		os.Exit(exitInterrupted)
+	}
+
+	policy := exitPolicy{ExitCode: *exitCode, FailOnUpdates: *failOnUpdates, FailOnError: *failOnError}
+	if status := policy.status(updateResults); status != 0 {
+		os.Exit(status)
+	}
+}
+
+// Exit codes for --exit-code, and for an interrupted run
+const (
+	exitUpdates = 2 // Updates are available
+	exitErrors  = 3 // Some checks failed
+
+	exitInterrupted = 130 // Ctrl-C; partial results were printed
+)
+
+// exitPolicy holds the flags that make results end the run with a
+// non-zero exit status
+type exitPolicy struct {
+	ExitCode      bool // --exit-code: distinct statuses for updates and errors
+	FailOnUpdates bool // --fail-on-updates: 1 when updates are available
+	FailOnError   bool // --fail-on-error: 1 when some checks failed
+}
+
+// status returns the exit status for results. With --exit-code, failed
+// checks win over updates, as they may hide further updates.
+func (p exitPolicy) status(results *checker.Results) int {
+	summary := results.Summary()
+	switch {
+	case p.ExitCode && summary.Errors > 0:
+		return exitErrors
+	case p.ExitCode && summary.Updates > 0:
+		return exitUpdates
+	case p.FailOnError && summary.Errors > 0:
+		return 1
+	case p.FailOnUpdates && summary.Updates > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// printResults writes results in the given output format
+func printResults(format string, results *checker.Results) {
+	var err error
+	switch format {
+	case "json":
+		err = output.PrintJSON(results)
+	case "jsonl":
+		err = output.PrintJSONL(results)
+	case "yaml":
+		err = output.PrintYAML(results)
+	case "sarif":
+		err = output.PrintSARIF(results)
+	case "markdown":
+		output.PrintMarkdown(results)
+	case "line":
+		output.PrintLines(results)
+	case "delta":
+		output.PrintDelta(results)
+	default:
+		output.PrintTable(results)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// waitFor describes how long until a rate limit resets, in whole minutes
+// rounded up, e.g. "12 minutes"
+func waitFor(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	switch {
+	case minutes <= 0:
+		return "a moment"
+	case minutes == 1:
+		return "1 minute"
+	default:
+		return fmt.Sprintf("%d minutes", minutes)
+	}
 }
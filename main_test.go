@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nogo/chartup/internal/checker"
+)
+
+func TestExitPolicyStatus(t *testing.T) {
+	updates := &checker.Results{Images: []checker.ImageResult{
+		{Status: checker.StatusUpToDate},
+		{Status: checker.StatusUpdateAvailable},
+	}}
+	errors := &checker.Results{Charts: []checker.ChartResult{
+		{Status: checker.StatusUpdateAvailable},
+		{Status: checker.StatusError},
+	}}
+	clean := &checker.Results{Images: []checker.ImageResult{
+		{Status: checker.StatusUpToDate},
+		{Status: checker.StatusSkipped},
+	}}
+
+	tests := []struct {
+		name    string
+		policy  exitPolicy
+		results *checker.Results
+		want    int
+	}{
+		{"no flags", exitPolicy{}, errors, 0},
+		{"fail on updates", exitPolicy{FailOnUpdates: true}, updates, 1},
+		{"fail on updates, up to date", exitPolicy{FailOnUpdates: true}, clean, 0},
+		{"fail on error ignores updates", exitPolicy{FailOnError: true}, updates, 0},
+		{"fail on error", exitPolicy{FailOnError: true}, errors, 1},
+		{"exit code updates", exitPolicy{ExitCode: true}, updates, exitUpdates},
+		{"exit code errors win", exitPolicy{ExitCode: true}, errors, exitErrors},
+		{"exit code wins over fail on updates", exitPolicy{ExitCode: true, FailOnUpdates: true}, updates, exitUpdates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.status(tt.results); got != tt.want {
+				t.Errorf("status() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-time.Second, "a moment"},
+		{20 * time.Second, "1 minute"},
+		{11*time.Minute + time.Second, "12 minutes"},
+	}
+	for _, tt := range tests {
+		if got := waitFor(tt.d); got != tt.want {
+			t.Errorf("waitFor(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
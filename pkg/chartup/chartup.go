@@ -0,0 +1,213 @@
+// Package chartup scans a directory for Helm charts and Docker images and
+// checks them for updates, for programs that embed chartup instead of
+// running the CLI.
+package chartup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/nogo/chartup/internal/cache"
+	"github.com/nogo/chartup/internal/checker"
+	"github.com/nogo/chartup/internal/config"
+	"github.com/nogo/chartup/internal/registry"
+	"github.com/nogo/chartup/internal/scanner"
+)
+
+type (
+	// Config holds the settings the CLI reads from .chartup.yaml,
+	// CHARTUP_* environment variables, and flags
+	Config = config.Config
+
+	// Results are the checked images and charts of a run
+	Results = checker.Results
+
+	// ImageResult is the check result of one image
+	ImageResult = checker.ImageResult
+
+	// ChartResult is the check result of one chart
+	ChartResult = checker.ChartResult
+
+	// Bump is the size of an update, as in ImageResult.Bump and taken by
+	// Results.ApplyLevel
+	Bump = registry.Bump
+
+	// Status is the outcome of checking an image or chart
+	Status = checker.Status
+)
+
+// Statuses of an ImageResult or ChartResult
+const (
+	StatusUnknown              = checker.StatusUnknown
+	StatusUpToDate             = checker.StatusUpToDate
+	StatusUpdateAvailable      = checker.StatusUpdateAvailable
+	StatusMajorUpdateAvailable = checker.StatusMajorUpdateAvailable
+	StatusSkipped              = checker.StatusSkipped
+	StatusError                = checker.StatusError
+)
+
+// DefaultConfig returns the built-in configuration
+func DefaultConfig() *Config {
+	return config.Default()
+}
+
+// LoadConfig reads the .chartup.yaml in dir on top of the defaults, then
+// applies CHARTUP_* environment variables, as the CLI does
+func LoadConfig(dir string) (*Config, error) {
+	cfg, err := config.Load(config.Find(dir))
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ParseLevel parses a Config.Level: "patch", "minor", or "major"
+func ParseLevel(s string) (Bump, bool) {
+	return registry.ParseBump(s)
+}
+
+// Options configures a Run
+type Options struct {
+	// Dir is the directory to scan
+	Dir string
+
+	// Config holds the settings of the run. Nil uses DefaultConfig().
+	// Editor and Verbose only affect printing and are not used by Run.
+	Config *Config
+
+	// DeferLevel returns updates larger than Config.Level with their real
+	// status, e.g. to record them in a lock file first. Apply the level
+	// afterwards with ParseLevel and Results.ApplyLevel.
+	DeferLevel bool
+
+	// Files limits the scan to these files, e.g. those changed in git
+	Files []string
+
+	// DockerHubUsername and DockerHubToken log in to Docker Hub
+	DockerHubUsername string
+	DockerHubToken    string
+
+	// Warn receives warnings as they occur, e.g. a cache that could not be
+	// loaded or saved. Scan warnings are also returned in Results.Warnings.
+	Warn func(string)
+}
+
+// warn passes a warning to the Warn hook, if any
+func (o Options) warn(format string, args ...any) {
+	if o.Warn != nil {
+		o.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// Run scans opts.Dir and checks the images and charts found for updates,
+// reading and updating the cache at Config.CacheFile. Nothing is printed.
+//
+// When a registry rate limit is hit or ctx is done during the check, Run
+// returns the partial results along with the error, matching
+// registry.ErrRateLimit or ctx.Err(). Lookups that were not sent are
+// reported as errors. Other errors return nil results.
+func Run(ctx context.Context, opts Options) (*Results, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	level := registry.BumpNone
+	if cfg.Level != "" {
+		var ok bool
+		if level, ok = ParseLevel(cfg.Level); !ok {
+			return nil, fmt.Errorf("unknown level %q (use patch, minor, or major)", cfg.Level)
+		}
+	}
+	info, err := os.Stat(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", opts.Dir)
+	}
+
+	// Offline runs use entries however old they are
+	ttl := cfg.CacheTTL
+	if cfg.Offline {
+		ttl = time.Duration(math.MaxInt64)
+	}
+	c := cache.New(cfg.CacheFile, ttl, cfg.Refresh)
+	c.SetMaxAge(cfg.CacheMaxAge)
+	if err := c.Load(); err != nil {
+		opts.warn("could not load cache: %v", err)
+	}
+
+	scan, err := scanner.Scan(opts.Dir, scanner.Options{
+		ScanSchemas:    cfg.ScanSchemas,
+		ScanManifests:  cfg.ScanManifests,
+		Exclude:        cfg.Exclude,
+		Only:           cfg.Only,
+		Gitignore:      cfg.Gitignore,
+		Files:          opts.Files,
+		CheckMainChart: cfg.CheckMainChart,
+		MaxFileSize:    cfg.MaxFileSize,
+		Upstreams:      cfg.Upstreams,
+		SkipImages:     cfg.SkipImages,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning directory: %w", err)
+	}
+	for _, warning := range scan.Warnings {
+		opts.warn("%s", warning)
+	}
+
+	scan.FilterRegistries(cfg.Registries)
+	if len(scan.Charts) == 0 && len(scan.Images) == 0 {
+		return &Results{Warnings: scan.Warnings}, nil
+	}
+
+	reg := registry.New(registry.Options{
+		Timeout:            cfg.Timeout,
+		RegistryTimeouts:   cfg.RegistryTimeouts,
+		Retries:            cfg.Retries,
+		Mirrors:            cfg.RegistryMirrors,
+		InsecureRegistries: cfg.InsecureRegistries,
+		OnlySemver:         cfg.OnlySemver,
+	})
+	reg.SetMaxConcurrency("docker.io", cfg.MaxConcurrencyDockerHub)
+	reg.SetDockerHubMaxPages(cfg.DockerHubMaxPages)
+	reg.SetLookupBudget(cfg.LookupBudget)
+	reg.SetDockerHubRegistry(cfg.RegistryEndpointOverride)
+	reg.SetDockerHubAuth(opts.DockerHubUsername, opts.DockerHubToken)
+	if cfg.DockerConfig != "" {
+		if err := reg.LoadDockerConfig(cfg.DockerConfig); err != nil {
+			return nil, fmt.Errorf("reading Docker config: %w", err)
+		}
+	}
+
+	chk := checker.NewWithRegistry(c, reg)
+	chk.SetRepoRewrites(cfg.RepoRewrites)
+	chk.SetFetchDigests(cfg.PreferDigest)
+	chk.SetCheckAppReleases(cfg.CheckAppReleases)
+	chk.SetOffline(cfg.Offline, cfg.RequireCache)
+	chk.SetConcurrency(cfg.Concurrency)
+	chk.SetOnlySemver(cfg.OnlySemver)
+	results, err := chk.CheckAll(ctx, scan)
+	if err != nil && !checker.IsRateLimitError(err) && ctx.Err() == nil {
+		return nil, err
+	}
+	results.Warnings = append(scan.Warnings, results.Warnings...)
+	if !opts.DeferLevel {
+		results.ApplyLevel(level)
+	}
+
+	// Offline runs learn nothing new and leave a committed cache untouched
+	if !cfg.Offline {
+		if err := c.Save(); err != nil {
+			opts.warn("could not save cache: %v", err)
+		}
+	}
+
+	return results, err
+}
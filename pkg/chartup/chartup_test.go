@@ -0,0 +1,209 @@
+package chartup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// registryHandler is a mock OCI registry that lists the same tags for every
+// repository
+var registryHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `{"tags":["1.0.0","1.1.0","1.2.0"]}`)
+})
+
+// writeChart writes a chart with one image on host to dir
+func writeChart(dir, host string) error {
+	files := map[string]string{
+		"app/Chart.yaml":  "apiVersion: v2\nname: app\nversion: 1.0.0\n",
+		"app/values.yaml": "image:\n  repository: " + host + "/org/app\n  tag: 1.0.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewTLSServer(registryHandler)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	dir := t.TempDir()
+	if err := writeChart(dir, host); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+	cfg.InsecureRegistries = []string{host}
+
+	results, err := Run(context.Background(), Options{Dir: dir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results.Images) != 1 {
+		t.Fatalf("got %d images, want 1: %+v", len(results.Images), results.Images)
+	}
+	img := results.Images[0]
+	if img.Current != "1.0.0" || img.Latest != "1.2.0" {
+		t.Errorf("image = %s → %s, want 1.0.0 → 1.2.0", img.Current, img.Latest)
+	}
+	if _, err := os.Stat(cfg.CacheFile); err != nil {
+		t.Errorf("cache not saved: %v", err)
+	}
+
+	// The second run is answered from the cache
+	results, err = Run(context.Background(), Options{Dir: dir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if results.Images[0].CachedAt.IsZero() {
+		t.Error("second Run() did not use the cache")
+	}
+}
+
+func TestRun_Offline(t *testing.T) {
+	server := httptest.NewTLSServer(registryHandler)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	dir := t.TempDir()
+	if err := writeChart(dir, host); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+	cfg.InsecureRegistries = []string{host}
+	if _, err := Run(context.Background(), Options{Dir: dir, Config: cfg}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	server.Close()
+
+	// Offline runs answer from the cache however old it is, without the registry
+	cfg.Offline = true
+	cfg.CacheTTL = time.Nanosecond
+	results, err := Run(context.Background(), Options{Dir: dir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if img := results.Images[0]; img.Latest != "1.2.0" || img.CachedAt.IsZero() {
+		t.Errorf("offline image = %+v, want 1.2.0 from the cache", img)
+	}
+
+	// Without a cache entry the image is skipped
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+	results, err = Run(context.Background(), Options{Dir: dir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if img := results.Images[0]; img.Status != StatusSkipped {
+		t.Errorf("offline image without cache = %v (%s), want skipped", img.Status, img.Error)
+	}
+}
+
+func TestRun_Level(t *testing.T) {
+	server := httptest.NewTLSServer(registryHandler)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	dir := t.TempDir()
+	if err := writeChart(dir, host); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+	cfg.InsecureRegistries = []string{host}
+	cfg.Level = "patch"
+
+	// 1.0.0 → 1.2.0 is a minor update, above the level
+	results, err := Run(context.Background(), Options{Dir: dir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if img := results.Images[0]; img.Status != StatusUpToDate || img.Latest != "1.2.0" {
+		t.Errorf("image above level = %v → %s, want up to date → 1.2.0", img.Status, img.Latest)
+	}
+
+	results, err = Run(context.Background(), Options{Dir: dir, Config: cfg, DeferLevel: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if img := results.Images[0]; img.Status != StatusUpdateAvailable {
+		t.Errorf("image with DeferLevel = %v, want update", img.Status)
+	}
+
+	cfg.Level = "huge"
+	if _, err := Run(context.Background(), Options{Dir: dir, Config: cfg}); err == nil {
+		t.Error("Run() with an unknown level succeeded, want an error")
+	}
+}
+
+func TestRun_Empty(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+
+	results, err := Run(context.Background(), Options{Dir: t.TempDir(), Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results.Images) != 0 || len(results.Charts) != 0 {
+		t.Errorf("Run() on an empty directory = %+v, want no results", results)
+	}
+}
+
+func TestRun_NotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Run(context.Background(), Options{Dir: file}); err == nil {
+		t.Error("Run() on a file succeeded, want an error")
+	}
+}
+
+func ExampleRun() {
+	// A registry with tags 1.0.0 to 1.2.0, and a chart using 1.0.0
+	server := httptest.NewTLSServer(registryHandler)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	dir, err := os.MkdirTemp("", "chartup-example-*")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	if err := writeChart(dir, host); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	cfg := DefaultConfig()
+	cfg.CacheFile = filepath.Join(dir, "cache.json")
+	cfg.InsecureRegistries = []string{host} // The mock's certificate is self-signed
+
+	results, err := Run(context.Background(), Options{Dir: dir, Config: cfg})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, img := range results.Images {
+		fmt.Printf("%s %s → %s\n", img.Repository, img.Current, img.Latest)
+	}
+	// Output: org/app 1.0.0 → 1.2.0
+}